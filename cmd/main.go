@@ -1,22 +1,50 @@
 package main
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/vagabundor/gtp2json/config"
 	"github.com/vagabundor/gtp2json/pkg/assets"
+	"github.com/vagabundor/gtp2json/pkg/avroenc"
+	"github.com/vagabundor/gtp2json/pkg/batch"
+	"github.com/vagabundor/gtp2json/pkg/clickhouse"
+	"github.com/vagabundor/gtp2json/pkg/csvenc"
+	"github.com/vagabundor/gtp2json/pkg/defrag"
+	"github.com/vagabundor/gtp2json/pkg/elastic"
+	"github.com/vagabundor/gtp2json/pkg/endpoint"
+	"github.com/vagabundor/gtp2json/pkg/filesink"
+	"github.com/vagabundor/gtp2json/pkg/flows"
+	"github.com/vagabundor/gtp2json/pkg/gtp1"
+	"github.com/vagabundor/gtp2json/pkg/gtp1ie"
 	"github.com/vagabundor/gtp2json/pkg/gtp2"
 	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+	"github.com/vagabundor/gtp2json/pkg/gtpprime"
+	"github.com/vagabundor/gtp2json/pkg/gtpprimeie"
+	"github.com/vagabundor/gtp2json/pkg/gtpu"
+	"github.com/vagabundor/gtp2json/pkg/parquet"
+	"github.com/vagabundor/gtp2json/pkg/pfcp"
+	"github.com/vagabundor/gtp2json/pkg/pfcpie"
+	"github.com/vagabundor/gtp2json/pkg/protoenc"
+	"github.com/vagabundor/gtp2json/pkg/syslog"
+	"github.com/vagabundor/gtp2json/pkg/tunnel"
+	"github.com/vagabundor/gtp2json/pkg/webhook"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -27,6 +55,7 @@ import (
 
 	"github.com/IBM/sarama"
 	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 	"github.com/google/gopacket/pcap"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -40,29 +69,156 @@ type IE struct {
 	Value interface{} `json:"value"`
 }
 
+// DecodeError describes an IE or a whole message that could not be decoded.
+// The offset is that of the IE value within the message, raw is the undecoded value as hex.
+type DecodeError struct {
+	IEType *uint16 `json:"ieType,omitempty"`
+	Offset int     `json:"offset"`
+	Reason string  `json:"reason"`
+	Raw    string  `json:"raw"`
+}
+
+// DecodeFailure is emitted to the error stream for messages rejected by the protocol decoder
+type DecodeFailure struct {
+	Timestamp time.Time `json:"timestamp"`
+	endpoint.Endpoint
+	RecordType   string           `json:"recordType"`
+	Protocol     string           `json:"protocol"`
+	DecodeErrors []DecodeError    `json:"decodeErrors"`
+	Tunnel       *tunnel.Metadata `json:"tunnel,omitempty"`
+}
+
+// output is a JSON record produced by the parse pipeline, rejected records go to the error stream
+type output struct {
+	data     []byte
+	protocol string
+	rejected bool
+	// followOn holds the records of further messages bundled in the same datagram
+	followOn []output
+}
+
 type GTPv2Packet struct {
-	Timestamp        time.Time `json:"timestamp"`
-	Version          uint8     `json:"version"`
-	PiggybackingFlag bool      `json:"piggybackingFlag"`
-	TEIDflag         bool      `json:"teidFlag"`
-	MessagePriority  uint8     `json:"messagePriority"`
-	MessageType      uint8     `json:"messageType"`
-	MessageLength    uint16    `json:"messageLength"`
-	TEID             *uint32   `json:"teid,omitempty"`
-	SequenceNumber   uint32    `json:"sequenceNumber"`
-	Spare            uint8     `json:"spare"`
-	IEs              []IE      `json:"ies"`
+	Timestamp time.Time `json:"timestamp"`
+	endpoint.Endpoint
+	Version          uint8            `json:"version"`
+	PiggybackingFlag bool             `json:"piggybackingFlag"`
+	TEIDflag         bool             `json:"teidFlag"`
+	MessagePriority  uint8            `json:"messagePriority"`
+	MessageType      uint8            `json:"messageType"`
+	MessageLength    uint16           `json:"messageLength"`
+	TEID             *uint32          `json:"teid,omitempty"`
+	SequenceNumber   uint32           `json:"sequenceNumber"`
+	Spare            uint8            `json:"spare"`
+	IEs              []IE             `json:"ies"`
+	DecodeErrors     []DecodeError    `json:"decodeErrors,omitempty"`
+	Tunnel           *tunnel.Metadata `json:"tunnel,omitempty"`
+}
+
+type ExtensionHeader struct {
+	Type    uint8  `json:"type"`
+	Content string `json:"content"`
 }
 
+type GTPv1Packet struct {
+	Timestamp time.Time `json:"timestamp"`
+	endpoint.Endpoint
+	Protocol            string            `json:"protocol"`
+	Version             uint8             `json:"version"`
+	ProtocolType        uint8             `json:"protocolType"`
+	ExtensionHeaderFlag bool              `json:"extensionHeaderFlag"`
+	SequenceNumberFlag  bool              `json:"sequenceNumberFlag"`
+	NPDUNumberFlag      bool              `json:"npduNumberFlag"`
+	MessageType         uint8             `json:"messageType"`
+	MessageLength       uint16            `json:"messageLength"`
+	TEID                uint32            `json:"teid"`
+	SequenceNumber      *uint16           `json:"sequenceNumber,omitempty"`
+	NPDUNumber          *uint8            `json:"npduNumber,omitempty"`
+	ExtensionHeaders    []ExtensionHeader `json:"extensionHeaders,omitempty"`
+	IEs                 []IE              `json:"ies"`
+	DecodeErrors        []DecodeError     `json:"decodeErrors,omitempty"`
+	Tunnel              *tunnel.Metadata  `json:"tunnel,omitempty"`
+}
+
+type PFCPPacket struct {
+	Timestamp time.Time `json:"timestamp"`
+	endpoint.Endpoint
+	Protocol            string           `json:"protocol"`
+	Version             uint8            `json:"version"`
+	FollowOnFlag        bool             `json:"followOnFlag"`
+	MessagePriorityFlag bool             `json:"messagePriorityFlag"`
+	SEIDFlag            bool             `json:"seidFlag"`
+	MessageType         uint8            `json:"messageType"`
+	MessageLength       uint16           `json:"messageLength"`
+	SEID                *uint64          `json:"seid,omitempty"`
+	SequenceNumber      uint32           `json:"sequenceNumber"`
+	MessagePriority     *uint8           `json:"messagePriority,omitempty"`
+	IEs                 []IE             `json:"ies"`
+	DecodeErrors        []DecodeError    `json:"decodeErrors,omitempty"`
+	Tunnel              *tunnel.Metadata `json:"tunnel,omitempty"`
+}
+
+type GTPPrimePacket struct {
+	Timestamp time.Time `json:"timestamp"`
+	endpoint.Endpoint
+	Protocol       string           `json:"protocol"`
+	Version        uint8            `json:"version"`
+	ShortHeader    bool             `json:"shortHeader"`
+	MessageType    uint8            `json:"messageType"`
+	MessageLength  uint16           `json:"messageLength"`
+	SequenceNumber uint16           `json:"sequenceNumber"`
+	IEs            []IE             `json:"ies"`
+	DecodeErrors   []DecodeError    `json:"decodeErrors,omitempty"`
+	Tunnel         *tunnel.Metadata `json:"tunnel,omitempty"`
+}
+
+// KafkaMsgBuff is the ring buffer records are sent through. Topic and ErrorTopic are the Kafka
+// topics or, for Elasticsearch, the prefixes of the daily indices, for ClickHouse the tables and for
+// webhooks and syslog the kind of record.
 type KafkaMsgBuff struct {
 	Topic      string
+	ErrorTopic string
 	RingBuffer *kafkabuff.RingBuffer
 }
 
+// streamRecords and streamErrors tell records and rejected records apart in the webhook and syslog
+// ring buffers, where the destination does not depend on the topic
+const (
+	streamRecords = "records"
+	streamErrors  = "errors"
+)
+
+// maxBackoff limits the doubling interval between retries of Elasticsearch, ClickHouse, webhook and syslog requests
+const maxBackoff = time.Minute
+
 var (
 	isReady       atomic.Value
 	isFirstOutput        = true
 	AppVersion    string = "dev"
+
+	// flowAggregator is set when G-PDU summarisation is enabled
+	flowAggregator *flows.Aggregator
+
+	// outputEncoding is the encoding of the output records (ndjson, json-array, pretty, protobuf, avro, csv, tsv)
+	outputEncoding string
+
+	// csvEncoder selects the columns of the csv and tsv encodings
+	csvEncoder *csvenc.Encoder
+
+	// avroSchemaID is the Schema Registry ID of the Avro schema of the records
+	avroSchemaID uint32
+
+	// captureInterface is the name of the interface packets are captured from, empty for pcap files
+	captureInterface string
+
+	// strictMode rejects messages with decode errors to the error stream instead of emitting partial records
+	strictMode bool
+
+	// errorFilePath is the file of the error stream when records are not sent through the ring buffer,
+	// the error stream goes to stderr when it is empty
+	errorFilePath string
+
+	// gtpv2Heuristic enables detection of GTPv2 on UDP ports without a registered decoder
+	gtpv2Heuristic bool
 )
 
 const (
@@ -108,6 +264,78 @@ var (
 		Name: "gtp_ie_types_total",
 		Help: "Total number of processed Information Elements by type.",
 	}, []string{"ie_type"})
+	gpduFlowsActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gpdu_flows_active",
+		Help: "Number of G-PDU flows currently aggregated.",
+	})
+	gpduFlowsExported = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gpdu_flows_exported_total",
+		Help: "Total number of exported G-PDU flow records.",
+	})
+	gpduPacketsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gpdu_packets_dropped_total",
+		Help: "Total number of G-PDUs not aggregated because the flow table was full.",
+	})
+	decodeErrorsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "decode_errors_total",
+		Help: "Total number of decode errors by protocol and class (message, ie).",
+	}, []string{"protocol", "class"})
+	decodeRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "decode_rejected_messages_total",
+		Help: "Total number of messages sent to the error stream by protocol.",
+	}, []string{"protocol"})
+	fragmentsReassembled = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ip_datagrams_reassembled_total",
+		Help: "Total number of IP datagrams reassembled from fragments.",
+	})
+	fragmentsExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ip_fragments_expired_total",
+		Help: "Total number of IP fragments discarded because their datagram was not completed in time.",
+	})
+	fragmentsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ip_fragments_dropped_total",
+		Help: "Total number of IP fragments discarded because of the reassembly memory or fragment limits.",
+	})
+	fragmentsBufferedBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_fragments_buffered_bytes",
+		Help: "Size of the IP fragment payload currently waiting for reassembly.",
+	})
+	gtpv2HeuristicMatches = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gtpv2_heuristic_matches_total",
+		Help: "Total number of GTPv2 messages detected heuristically on non-registered UDP ports.",
+	})
+	elasticsearchIndexed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "elasticsearch_documents_indexed_total",
+		Help: "Total number of records indexed in Elasticsearch.",
+	})
+	elasticsearchFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "elasticsearch_documents_failed_total",
+		Help: "Total number of records rejected by Elasticsearch or dropped after retries.",
+	})
+	clickhouseInserted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "clickhouse_rows_inserted_total",
+		Help: "Total number of records inserted into ClickHouse.",
+	})
+	clickhouseFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "clickhouse_rows_failed_total",
+		Help: "Total number of records not converted or dropped after retries of their ClickHouse batch.",
+	})
+	webhookSent = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_records_sent_total",
+		Help: "Total number of records posted to the webhook.",
+	})
+	webhookFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_records_failed_total",
+		Help: "Total number of records dropped after retries of their webhook request.",
+	})
+	syslogSent = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "syslog_messages_sent_total",
+		Help: "Total number of syslog or journald messages sent.",
+	})
+	syslogFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "syslog_messages_failed_total",
+		Help: "Total number of records not converted or dropped after retries of their syslog batch.",
+	})
 )
 
 func init() {
@@ -120,6 +348,48 @@ func init() {
 	prometheus.MustRegister(kafkaBufferSizeGauge)
 	prometheus.MustRegister(kafkaBatchSizeGauge)
 	prometheus.MustRegister(ieTypeCounter)
+	prometheus.MustRegister(gpduFlowsActive)
+	prometheus.MustRegister(gpduFlowsExported)
+	prometheus.MustRegister(gpduPacketsDropped)
+	prometheus.MustRegister(gtpv2HeuristicMatches)
+	prometheus.MustRegister(decodeErrorsCounter)
+	prometheus.MustRegister(decodeRejected)
+	prometheus.MustRegister(fragmentsReassembled)
+	prometheus.MustRegister(fragmentsExpired)
+	prometheus.MustRegister(fragmentsDropped)
+	prometheus.MustRegister(fragmentsBufferedBytes)
+	prometheus.MustRegister(elasticsearchIndexed)
+	prometheus.MustRegister(elasticsearchFailed)
+	prometheus.MustRegister(clickhouseInserted)
+	prometheus.MustRegister(clickhouseFailed)
+	prometheus.MustRegister(webhookSent)
+	prometheus.MustRegister(webhookFailed)
+	prometheus.MustRegister(syslogSent)
+	prometheus.MustRegister(syslogFailed)
+
+	// The registration order defines the field numbers of the Record oneof and the
+	// branches of the Avro union, it must only be appended to
+	for _, r := range []struct {
+		name   string
+		record interface{}
+	}{
+		{"GTPv2Packet", GTPv2Packet{}},
+		{"GTPv1Packet", GTPv1Packet{}},
+		{"PFCPPacket", PFCPPacket{}},
+		{"GTPPrimePacket", GTPPrimePacket{}},
+		{"GTPUFlow", flows.Record{}},
+		{"DecodeFailure", DecodeFailure{}},
+	} {
+		if err := protoenc.RegisterRecord(r.name, r.record); err != nil {
+			panic(err)
+		}
+		if err := avroenc.RegisterRecord(r.name, r.record); err != nil {
+			panic(err)
+		}
+		if err := elastic.RegisterRecord(r.record); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func main() {
@@ -133,13 +403,98 @@ func main() {
 	pflag.String("interface", "", "Name of the interface to analyze")
 	pflag.Int("packetBufferSize", 200000, "Size of the packet buffer channel")
 	pflag.String("format", "numeric", "Specifies the format of the output (numeric, text, mixed)")
+	pflag.String("output-encoding", "", "Encoding of the output (ndjson, json-array, pretty, protobuf, avro, csv, tsv), defaults to ndjson for Kafka and pretty for stdout")
+	pflag.String("csvColumns", "timestamp,srcIP,dstIP,messageType,IMSI,MSISDN,APN,RATType,Cause.Value", "Column expressions of the csv and tsv encodings, comma separated")
+	pflag.String("csvMultiple", "join", "Handling of columns with several values (join, first)")
+	pflag.String("csvJoinSeparator", "|", "Separator of joined column values")
+	pflag.Bool("print-proto", false, "Print the protobuf schema of the output records and exit")
+	pflag.String("timeFormat", "rfc3339", "Specifies the format of decoded timestamps (rfc3339, epochms)")
+	pflag.String("timezone", "UTC", "Timezone for RFC 3339 timestamps, e.g. UTC or Europe/Moscow")
+	pflag.Bool("gtpu", false, "Decode GTP-U control messages (Echo, Error Indication, End Marker) on UDP port 2152")
+	pflag.Bool("gpduFlows", false, "Aggregate GTP-U G-PDUs into per-TEID inner flow records (implies --gtpu)")
+	pflag.Duration("flowActiveTimeout", 60*time.Second, "Interval after which a long-lived G-PDU flow record is exported")
+	pflag.Duration("flowIdleTimeout", 15*time.Second, "Inactivity interval after which a G-PDU flow record is exported")
+	pflag.Int("flowMaxEntries", 1000000, "Maximum number of G-PDU flows aggregated at once (use 0 for unlimited)")
+	pflag.String("gtpv2Ports", "", "Additional UDP ports on which GTPv2 is decoded, comma separated")
+	pflag.Bool("gtpv2Heuristic", false, "Detect GTPv2 on any UDP port by validating the header and IE structure")
+	pflag.Bool("defrag", true, "Reassemble fragmented IPv4 and IPv6 datagrams before decoding")
+	pflag.Duration("defragTimeout", 30*time.Second, "Interval after which an incomplete fragmented datagram is discarded")
+	pflag.Int("defragMaxBytes", 64<<20, "Maximum size of buffered fragments in bytes (use 0 for unlimited)")
+	pflag.String("encapsulation", "", "Outer encapsulations to capture, comma separated (vlan, mpls, gre, erspan, vxlan)")
+	pflag.String("bpf", "", "Custom BPF expression replacing the generated capture filter")
+	pflag.String("nodeNames", "", "Path to a JSON file mapping node IP addresses to node names (optional)")
+	pflag.String("privateExtLayouts", "", "Path to a JSON file with vendor TLV layouts for Private Extension IEs (optional)")
+	pflag.Bool("strict", false, "Send messages with decode errors to the error stream")
+	pflag.String("errorFile", "", "File to which the error stream is appended instead of stderr without a network output")
+	pflag.String("parquetDir", "", "Directory for Parquet files written instead of stdout, requires --file")
+	pflag.Int64("parquetMaxBytes", 256<<20, "Size in bytes after which a Parquet file is closed (use 0 for unlimited)")
+	pflag.Duration("parquetRotateInterval", time.Hour, "Time window of the packet timestamps written to one Parquet file (use 0 to disable)")
+	pflag.Int("parquetRowGroupRows", 100000, "Number of records buffered in memory per Parquet row group")
+	pflag.String("parquetCompression", "snappy", "Compression of the Parquet columns (none, snappy, gzip, zstd)")
+	pflag.String("ndjsonDir", "", "Directory for rotating NDJSON or protobuf files written instead of stdout")
+	pflag.Int64("ndjsonMaxBytes", 256<<20, "Uncompressed size in bytes after which an NDJSON file is closed (use 0 for unlimited)")
+	pflag.Duration("ndjsonRotateInterval", time.Hour, "Clock interval at which NDJSON files are closed (use 0 to disable)")
+	pflag.String("ndjsonCompression", "gzip", "Compression of the NDJSON files (none, gzip, zstd)")
+	pflag.Int("ndjsonMaxFiles", 0, "Number of closed NDJSON files to keep (use 0 for unlimited)")
+	pflag.Duration("ndjsonMaxAge", 0, "Age after which closed NDJSON files are removed (use 0 to keep them)")
+	pflag.String("ndjsonManifest", "manifest.ndjson", "Name of the file in --ndjsonDir listing the closed files (empty to disable)")
 	pflag.String("kafka_brokers", "", "addresses of the Kafka brokers, comma separated")
 	pflag.String("kafkaTopic", "gtp_packets", "Kafka topic to send data to")
+	pflag.String("kafkaErrorTopic", "gtp_errors", "Kafka topic for rejected and undecodable messages")
 	pflag.String("kafka_user", "", "Kafka username for SASL authentication")
 	pflag.String("kafka_password", "", "Kafka password for SASL authentication")
 	pflag.String("kafka_cert_file", "", "TLS certificate file for Kafka (optional)")
-	pflag.Int("maxRetries", 25, "Maximum number of retries for Kafka connection (use 0 for infinite retries)")
-	pflag.Duration("retryInterval", 5*time.Second, "Interval between retries for Kafka connection")
+	pflag.String("schema_registry_url", "", "URL of the Schema Registry for Avro output")
+	pflag.String("schema_registry_user", "", "Schema Registry username for basic authentication")
+	pflag.String("schema_registry_password", "", "Schema Registry password for basic authentication")
+	pflag.String("schema_registry_cert_file", "", "TLS CA certificate file for the Schema Registry (optional)")
+	pflag.String("elasticsearch_urls", "", "URLs of the Elasticsearch or OpenSearch nodes, comma separated")
+	pflag.String("elasticsearch_user", "", "Elasticsearch username for basic authentication")
+	pflag.String("elasticsearch_password", "", "Elasticsearch password for basic authentication")
+	pflag.String("elasticsearch_api_key", "", "Encoded Elasticsearch API key, replaces basic authentication")
+	pflag.String("elasticsearch_cert_file", "", "TLS CA certificate file for Elasticsearch (optional)")
+	pflag.String("elasticsearchIndex", "gtp_packets", "Prefix of the daily Elasticsearch indices")
+	pflag.String("elasticsearchErrorIndex", "gtp_errors", "Prefix of the daily Elasticsearch indices for rejected and undecodable messages")
+	pflag.Bool("elasticsearchTemplate", true, "Create or update the index template of the indices on startup")
+	pflag.Int("elasticsearchBufferSize", 250000, "Size of the Elasticsearch ring buffer")
+	pflag.Int("elasticsearchBatchSize", 5000, "Number of records per bulk request")
+	pflag.Duration("elasticsearchBatchInterval", 5*time.Second, "Interval for Elasticsearch bulk requests")
+	pflag.String("clickhouse_url", "", "URL of the ClickHouse HTTP interface, e.g. http://clickhouse:8123")
+	pflag.String("clickhouse_user", "", "ClickHouse username")
+	pflag.String("clickhouse_password", "", "ClickHouse password")
+	pflag.String("clickhouse_cert_file", "", "TLS CA certificate file for ClickHouse (optional)")
+	pflag.String("clickhouseDatabase", "default", "ClickHouse database of the tables")
+	pflag.String("clickhouseTable", "gtp_packets", "ClickHouse table for the records")
+	pflag.String("clickhouseErrorTable", "gtp_errors", "ClickHouse table for rejected and undecodable messages")
+	pflag.Bool("clickhouseCreateTable", true, "Create the MergeTree tables on startup unless they exist")
+	pflag.Int("clickhouseBufferSize", 250000, "Size of the ClickHouse ring buffer")
+	pflag.Int("clickhouseBatchSize", 10000, "Number of records per ClickHouse insert")
+	pflag.Duration("clickhouseBatchInterval", 10*time.Second, "Interval for ClickHouse inserts")
+	pflag.String("webhook_url", "", "URL to which batches of records are posted")
+	pflag.String("webhook_error_url", "", "URL to which rejected and undecodable messages are posted (default --webhook_url)")
+	pflag.String("webhook_token", "", "Bearer token of the webhook requests")
+	pflag.String("webhook_cert_file", "", "TLS CA certificate file for the webhook (optional)")
+	pflag.StringArray("webhookHeader", nil, "Header added to the webhook requests as 'Name: value', can be repeated")
+	pflag.String("webhookFormat", "ndjson", "Body of the webhook requests (ndjson, array)")
+	pflag.Bool("webhookGzip", false, "Compress the webhook requests with gzip")
+	pflag.Int("webhookConcurrency", 4, "Maximum number of webhook requests in flight")
+	pflag.Int("webhookBufferSize", 250000, "Size of the webhook ring buffer")
+	pflag.Int("webhookBatchSize", 1000, "Number of records per webhook request")
+	pflag.Duration("webhookBatchInterval", 5*time.Second, "Interval for webhook requests")
+	pflag.String("syslog_addr", "", "Address of the syslog server as host:port")
+	pflag.String("syslog_cert_file", "", "TLS CA certificate file for syslog over TLS (optional)")
+	pflag.String("syslogNetwork", "udp", "Transport of the syslog output (udp, tcp, tls, journald)")
+	pflag.String("syslogFacility", "local0", "Syslog facility of the messages")
+	pflag.String("syslogAppName", AppName, "APP-NAME of the syslog messages")
+	pflag.String("syslogHostname", "", "HOSTNAME of the syslog messages (default the host name)")
+	pflag.String("syslogSeverity", "default=info,rejected=warning,error=err", "Severities of the records by message type, e.g. 'GTPv2:33=notice,rejected=warning'")
+	pflag.String("syslogSDID", "gtp@32473", "ID of the structured data element carrying IMSI, APN and cause")
+	pflag.Bool("syslogSummary", false, "Send a summary line of key=value pairs instead of the JSON record")
+	pflag.Int("syslogBufferSize", 250000, "Size of the syslog ring buffer")
+	pflag.Int("syslogBatchSize", 1000, "Number of messages sent per batch")
+	pflag.Duration("syslogBatchInterval", time.Second, "Interval for sending syslog messages")
+	pflag.Int("maxRetries", 25, "Maximum number of retries for Kafka connection, Elasticsearch, ClickHouse, webhook and syslog requests (use 0 for infinite retries)")
+	pflag.Duration("retryInterval", 5*time.Second, "Interval between retries for Kafka connection, doubled between Elasticsearch, ClickHouse, webhook and syslog retries")
 	pflag.Int("kafkaBufferSize", 250000, "Size of the Kafka ring buffer")
 	pflag.Int("kafkaBatchSize", 10000, "Size of the Kafka batch")
 	pflag.Duration("kafkaBatchInterval", 10*time.Second, "Interval for Kafka batch sending")
@@ -148,9 +503,15 @@ func main() {
 	pflag.Parse()
 
 	viper.SetEnvPrefix("G2J")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv()
 	viper.BindPFlags(pflag.CommandLine)
 
+	if viper.GetBool("print-proto") {
+		fmt.Print(protoenc.Schema())
+		return
+	}
+
 	pcapFile := viper.GetString("file")
 	iface := viper.GetString("interface")
 	packetBufferSize := viper.GetInt("packetBufferSize")
@@ -188,6 +549,171 @@ func main() {
 		return
 	}
 
+	parquetDir := viper.GetString("parquetDir")
+	if parquetDir != "" && (pcapFile == "" || kafkaBrokers != "") {
+		log.Println("Error: Parquet output is written for pcap files only, set --file and no --kafka_brokers.")
+		return
+	}
+	elasticsearchURLs := viper.GetString("elasticsearch_urls")
+	clickhouseURL := viper.GetString("clickhouse_url")
+	ndjsonDir := viper.GetString("ndjsonDir")
+	webhookURL := viper.GetString("webhook_url")
+	syslogNetwork := viper.GetString("syslogNetwork")
+	useSyslog := viper.GetString("syslog_addr") != "" || syslogNetwork == "journald"
+	outputs := 0
+	for _, set := range []bool{kafkaBrokers != "", parquetDir != "", elasticsearchURLs != "", clickhouseURL != "", ndjsonDir != "", webhookURL != "", useSyslog} {
+		if set {
+			outputs++
+		}
+	}
+	if outputs > 1 {
+		log.Println("Error: Kafka, Parquet, Elasticsearch, ClickHouse, NDJSON file, webhook and syslog output cannot be combined.")
+		return
+	}
+
+	outputEncoding = viper.GetString("output-encoding")
+	if outputEncoding == "" {
+		// Kafka messages hold a single record each, indentation would only waste bandwidth
+		outputEncoding = "pretty"
+		if outputs > 0 {
+			outputEncoding = "ndjson"
+		}
+	}
+	if (parquetDir != "" || elasticsearchURLs != "" || clickhouseURL != "") && outputEncoding != "ndjson" {
+		log.Println("Error: Parquet, Elasticsearch and ClickHouse output is converted from JSON records, use the 'ndjson' output encoding.")
+		return
+	}
+	if ndjsonDir != "" && outputEncoding != "ndjson" && outputEncoding != "protobuf" {
+		log.Println("Error: Files in --ndjsonDir are written with the 'ndjson' or 'protobuf' output encoding only.")
+		return
+	}
+	if webhookURL != "" && outputEncoding != "ndjson" {
+		log.Println("Error: Webhook requests are written with the 'ndjson' output encoding only.")
+		return
+	}
+	if useSyslog && outputEncoding != "ndjson" {
+		log.Println("Error: Syslog messages are built from JSON records, use the 'ndjson' output encoding.")
+		return
+	}
+	switch outputEncoding {
+	case "ndjson", "pretty", "protobuf":
+	case "avro":
+		if kafkaBrokers == "" || viper.GetString("schema_registry_url") == "" {
+			log.Println("Error: 'avro' output encoding requires Kafka and a Schema Registry, set --kafka_brokers and --schema_registry_url.")
+			return
+		}
+	case "json-array":
+		if kafkaBrokers != "" {
+			log.Println("Error: 'json-array' output encoding is not supported with Kafka, each record is a separate message. Use 'ndjson', 'pretty', 'protobuf', or 'avro'.")
+			return
+		}
+	case "csv", "tsv":
+		if kafkaBrokers != "" {
+			log.Printf("Error: '%s' output encoding is available for stdout only. Use 'ndjson', 'pretty', 'protobuf', or 'avro' with Kafka.", outputEncoding)
+			return
+		}
+		multiple, err := csvenc.ParseMultiple(viper.GetString("csvMultiple"))
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return
+		}
+		comma := ','
+		if outputEncoding == "tsv" {
+			comma = '\t'
+		}
+		csvEncoder, err = csvenc.NewEncoder(viper.GetString("csvColumns"), comma, multiple, viper.GetString("csvJoinSeparator"))
+		if err != nil {
+			log.Printf("Error: invalid --csvColumns: %v", err)
+			return
+		}
+	default:
+		log.Printf("Error: '%s' is not a valid output encoding. Use 'ndjson', 'json-array', 'pretty', 'protobuf', 'avro', 'csv', or 'tsv'.", outputEncoding)
+		return
+	}
+	log.Printf("Output encoding set to: %s\n", outputEncoding)
+
+	timeFormat := viper.GetString("timeFormat")
+	switch timeFormat {
+	case "rfc3339", "epochms":
+		config.SetTimeFormat(timeFormat)
+	default:
+		log.Printf("Error: '%s' is not a valid time format. Use 'rfc3339' or 'epochms'.", timeFormat)
+		return
+	}
+
+	timezone := viper.GetString("timezone")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Printf("Error: '%s' is not a valid timezone: %v", timezone, err)
+		return
+	}
+	config.SetTimeLocation(location)
+	log.Printf("Time format set to: %s, timezone: %s\n", timeFormat, location)
+
+	decodeGTPU := viper.GetBool("gtpu")
+	if viper.GetBool("gpduFlows") {
+		decodeGTPU = true
+		flowAggregator = flows.NewAggregator(viper.GetDuration("flowActiveTimeout"), viper.GetDuration("flowIdleTimeout"), viper.GetInt("flowMaxEntries"))
+		log.Printf("G-PDU flow aggregation enabled: active timeout %v, idle timeout %v\n", viper.GetDuration("flowActiveTimeout"), viper.GetDuration("flowIdleTimeout"))
+	}
+	if decodeGTPU {
+		gtpu.Enable()
+	}
+
+	gtpv2Ports, err := parsePorts(viper.GetString("gtpv2Ports"))
+	if err != nil {
+		log.Printf("Error: invalid gtpv2Ports: %v", err)
+		return
+	}
+	for _, port := range gtpv2Ports {
+		gtp2.RegisterPort(port)
+	}
+	if len(gtpv2Ports) > 0 {
+		log.Printf("GTPv2 decoded on additional UDP ports: %v\n", gtpv2Ports)
+	}
+
+	gtpv2Heuristic = viper.GetBool("gtpv2Heuristic")
+	if gtpv2Heuristic {
+		log.Println("Heuristic GTPv2 detection enabled on all UDP ports")
+	}
+
+	var reassembler *defrag.Reassembler
+	if viper.GetBool("defrag") {
+		reassembler = defrag.NewReassembler(viper.GetDuration("defragTimeout"), viper.GetInt("defragMaxBytes"))
+		log.Printf("IP fragment reassembly enabled: timeout %v, max bytes %d\n", viper.GetDuration("defragTimeout"), viper.GetInt("defragMaxBytes"))
+	}
+
+	bpfFilter := viper.GetString("bpf")
+	if bpfFilter == "" {
+		// Tunnels are always decapsulated, the option only extends the capture filter
+		bpfFilter, err = tunnel.BPFFilter(buildBPFFilter(decodeGTPU, gtpv2Ports, gtpv2Heuristic, reassembler != nil), strings.Split(viper.GetString("encapsulation"), ","))
+		if err != nil {
+			log.Printf("Error: invalid encapsulation: %v", err)
+			return
+		}
+	}
+
+	if privateExtLayouts := viper.GetString("privateExtLayouts"); privateExtLayouts != "" {
+		if err := gtp2ie.LoadPrivateExtensionLayouts(privateExtLayouts); err != nil {
+			log.Fatalf("Failed to load Private Extension layouts: %v", err)
+		}
+		log.Printf("Private Extension layouts loaded from: %s\n", privateExtLayouts)
+	}
+
+	if nodeNames := viper.GetString("nodeNames"); nodeNames != "" {
+		if err := endpoint.LoadNodeNames(nodeNames); err != nil {
+			log.Fatalf("Failed to load node names: %v", err)
+		}
+		log.Printf("Node names loaded from: %s\n", nodeNames)
+	}
+	captureInterface = iface
+
+	strictMode = viper.GetBool("strict")
+	errorFilePath = viper.GetString("errorFile")
+	if strictMode {
+		log.Println("Strict decoding enabled: messages with decode errors are sent to the error stream")
+	}
+
 	pcapBufferSize := os.Getenv("PCAP_BUFFER_SIZE")
 	if pcapBufferSize == "" {
 		pcapBufferSize = "default"
@@ -205,23 +731,20 @@ func main() {
 
 	isReady.Store(false)
 
-	useKafka := false
+	// Records go through the ring buffer of the Kafka, Elasticsearch, ClickHouse, webhook or syslog sender when one is configured
+	useBuffer := false
 	var kmsgbuff *KafkaMsgBuff
+	var bufferSender *batch.Sender
+	var bufferSink string
 
 	// Input packet buffer
 	packetChan := make(chan gopacket.Packet, packetBufferSize)
 
 	if kafkaBrokers != "" {
 
-		useKafka = true
+		useBuffer = true
 
-		//metrics server
-		go func() {
-			log.Printf("Starting metrics server on %s", metricsAddr)
-			if err := http.ListenAndServe(metricsAddr, nil); err != nil {
-				log.Fatalf("Failed to start HTTP server: %v", err)
-			}
-		}()
+		startMetricsServer(metricsAddr)
 
 		logger := logrus.New()
 		logger.SetFormatter(&logrus.TextFormatter{
@@ -268,6 +791,23 @@ func main() {
 			logger.Infof("TLS enabled for Kafka with CA certificate: %s", certKafkaFile)
 		}
 
+		if outputEncoding == "avro" {
+			var registryTLS *tls.Config
+			if certFile := viper.GetString("schema_registry_cert_file"); certFile != "" {
+				registryTLS, err = createTLSConfig(certFile)
+				if err != nil {
+					log.Fatalf("Failed to configure TLS for the Schema Registry: %v", err)
+				}
+			}
+			registry := avroenc.NewRegistry(viper.GetString("schema_registry_url"), viper.GetString("schema_registry_user"),
+				viper.GetString("schema_registry_password"), registryTLS)
+			avroSchemaID, err = registerAvroSchema(registry, avroenc.Subject(kafkaTopic), maxRetries, retryInterval)
+			if err != nil {
+				logger.WithError(err).Fatal("Failed to register the Avro schema")
+			}
+			logger.Infof("Avro schema registered under subject %s with ID %d", avroenc.Subject(kafkaTopic), avroSchemaID)
+		}
+
 		brokerList := strings.Split(kafkaBrokers, ",")
 		for i := range brokerList {
 			brokerList[i] = strings.TrimSpace(brokerList[i])
@@ -288,6 +828,7 @@ func main() {
 
 		kmsgbuff = &KafkaMsgBuff{
 			Topic:      kafkaTopic,
+			ErrorTopic: viper.GetString("kafkaErrorTopic"),
 			RingBuffer: ringBuffer,
 		}
 
@@ -295,20 +836,292 @@ func main() {
 
 		isReady.Store(true)
 
-		go func() {
-			for {
-				packetChanOccupancy.Set(float64(len(packetChan)))
+		go monitorOccupancy(packetChan, ringBuffer)
 
-				if ringBuffer != nil {
-					ringBufferOccupancy.Set(float64(ringBuffer.Size()))
-				} else {
-					ringBufferOccupancy.Set(0)
+	} else if elasticsearchURLs != "" {
+
+		useBuffer = true
+
+		startMetricsServer(metricsAddr)
+
+		var esTLS *tls.Config
+		if certFile := viper.GetString("elasticsearch_cert_file"); certFile != "" {
+			esTLS, err = createTLSConfig(certFile)
+			if err != nil {
+				log.Fatalf("Failed to configure TLS for Elasticsearch: %v", err)
+			}
+		}
+		urls := strings.Split(elasticsearchURLs, ",")
+		for i := range urls {
+			urls[i] = strings.TrimSpace(urls[i])
+		}
+		esClient, err := elastic.NewClient(elastic.Config{
+			URLs:     urls,
+			Username: viper.GetString("elasticsearch_user"),
+			Password: viper.GetString("elasticsearch_password"),
+			APIKey:   viper.GetString("elasticsearch_api_key"),
+			TLS:      esTLS,
+			Timeout:  time.Minute,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize Elasticsearch client: %v", err)
+		}
+		backoff := batch.Backoff{Initial: retryInterval, Max: maxBackoff}
+
+		index, errorIndex := viper.GetString("elasticsearchIndex"), viper.GetString("elasticsearchErrorIndex")
+		if viper.GetBool("elasticsearchTemplate") {
+			template, err := elastic.Template([]string{index + "-*", errorIndex + "-*"})
+			if err != nil {
+				log.Fatalf("Failed to generate the index template: %v", err)
+			}
+			err = batch.Retry(maxRetries, backoff, func() error {
+				err := esClient.PutTemplate(index, template)
+				if err != nil {
+					log.Printf("Failed to put the index template: %v", err)
 				}
+				return err
+			})
+			if err != nil {
+				log.Fatalf("Failed to put the index template: %v", err)
+			}
+			log.Printf("Index template %s installed for %s-* and %s-*", index, index, errorIndex)
+		}
 
-				time.Sleep(5 * time.Second)
+		bufferSink = "Elasticsearch"
+		elasticsearchBufferSize := viper.GetInt("elasticsearchBufferSize")
+		kmsgbuff, bufferSender = startBufferedSink(packetChan, bufferSink, index, errorIndex, elasticsearchBufferSize,
+			viper.GetInt("elasticsearchBatchSize"), viper.GetDuration("elasticsearchBatchInterval"), 1,
+			func(messages []*sarama.ProducerMessage) error {
+				return sendToElasticsearch(esClient, messages, maxRetries, backoff)
+			})
+		log.Printf("Elasticsearch output set to: %s, buffer size: %d", elasticsearchURLs, elasticsearchBufferSize)
+
+	} else if clickhouseURL != "" {
+
+		useBuffer = true
+
+		startMetricsServer(metricsAddr)
+
+		var chTLS *tls.Config
+		if certFile := viper.GetString("clickhouse_cert_file"); certFile != "" {
+			chTLS, err = createTLSConfig(certFile)
+			if err != nil {
+				log.Fatalf("Failed to configure TLS for ClickHouse: %v", err)
 			}
-		}()
+		}
+		chClient, err := clickhouse.NewClient(clickhouse.Config{
+			URL:      clickhouseURL,
+			Database: viper.GetString("clickhouseDatabase"),
+			Username: viper.GetString("clickhouse_user"),
+			Password: viper.GetString("clickhouse_password"),
+			TLS:      chTLS,
+			Timeout:  time.Minute,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize ClickHouse client: %v", err)
+		}
+		backoff := batch.Backoff{Initial: retryInterval, Max: maxBackoff}
+
+		table, errorTable := viper.GetString("clickhouseTable"), viper.GetString("clickhouseErrorTable")
+		if viper.GetBool("clickhouseCreateTable") {
+			for _, name := range []string{table, errorTable} {
+				err := batch.Retry(maxRetries, backoff, func() error {
+					err := chClient.CreateTable(name, clickhouse.Schema)
+					if err != nil {
+						log.Printf("Failed to create ClickHouse table %s: %v", name, err)
+					}
+					return err
+				})
+				if err != nil {
+					log.Fatalf("Failed to create ClickHouse table %s: %v", name, err)
+				}
+			}
+		}
+
+		bufferSink = "ClickHouse"
+		clickhouseBufferSize := viper.GetInt("clickhouseBufferSize")
+		kmsgbuff, bufferSender = startBufferedSink(packetChan, bufferSink, table, errorTable, clickhouseBufferSize,
+			viper.GetInt("clickhouseBatchSize"), viper.GetDuration("clickhouseBatchInterval"), 1,
+			func(messages []*sarama.ProducerMessage) error {
+				return sendToClickHouse(chClient, messages, maxRetries, backoff)
+			})
+		log.Printf("ClickHouse output set to: %s, buffer size: %d", clickhouseURL, clickhouseBufferSize)
+
+	} else if webhookURL != "" {
+
+		errorURL := viper.GetString("webhook_error_url")
+		if errorURL == "" {
+			errorURL = webhookURL
+		}
+		for _, u := range []string{webhookURL, errorURL} {
+			if err := webhook.ValidURL(u); err != nil {
+				log.Printf("Error: %v", err)
+				return
+			}
+		}
+		format, err := webhook.ParseFormat(viper.GetString("webhookFormat"))
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return
+		}
+		headers := http.Header{}
+		for _, h := range viper.GetStringSlice("webhookHeader") {
+			name, value, err := webhook.ParseHeader(h)
+			if err != nil {
+				log.Printf("Error: %v", err)
+				return
+			}
+			headers.Add(name, value)
+		}
+
+		useBuffer = true
+
+		startMetricsServer(metricsAddr)
+
+		var whTLS *tls.Config
+		if certFile := viper.GetString("webhook_cert_file"); certFile != "" {
+			whTLS, err = createTLSConfig(certFile)
+			if err != nil {
+				log.Fatalf("Failed to configure TLS for the webhook: %v", err)
+			}
+		}
+		whClient, err := webhook.NewClient(webhook.Config{
+			Format:  format,
+			Headers: headers,
+			Token:   viper.GetString("webhook_token"),
+			Gzip:    viper.GetBool("webhookGzip"),
+			TLS:     whTLS,
+			Timeout: time.Minute,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize webhook client: %v", err)
+		}
+		backoff := batch.Backoff{Initial: retryInterval, Max: maxBackoff}
+
+		bufferSink = "webhook"
+		webhookBufferSize := viper.GetInt("webhookBufferSize")
+		kmsgbuff, bufferSender = startBufferedSink(packetChan, bufferSink, streamRecords, streamErrors, webhookBufferSize,
+			viper.GetInt("webhookBatchSize"), viper.GetDuration("webhookBatchInterval"), viper.GetInt("webhookConcurrency"),
+			func(messages []*sarama.ProducerMessage) error {
+				return sendToWebhook(whClient, webhookURL, errorURL, messages, maxRetries, backoff)
+			})
+		log.Printf("Webhook output set to: %s, buffer size: %d", webhookURL, webhookBufferSize)
+
+	} else if useSyslog {
+
+		facility, err := syslog.ParseFacility(viper.GetString("syslogFacility"))
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return
+		}
+		severities, err := syslog.ParseSeverityMap(viper.GetString("syslogSeverity"))
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return
+		}
+		hostname := viper.GetString("syslogHostname")
+		if hostname == "" {
+			hostname, _ = os.Hostname()
+		}
+		formatter := &syslog.Formatter{
+			Facility:   facility,
+			Hostname:   hostname,
+			AppName:    viper.GetString("syslogAppName"),
+			ProcID:     strconv.Itoa(os.Getpid()),
+			SDID:       viper.GetString("syslogSDID"),
+			Severities: severities,
+			Summary:    viper.GetBool("syslogSummary"),
+		}
+
+		var syslogTLS *tls.Config
+		if certFile := viper.GetString("syslog_cert_file"); certFile != "" {
+			syslogTLS, err = createTLSConfig(certFile)
+			if err != nil {
+				log.Fatalf("Failed to configure TLS for syslog: %v", err)
+			}
+		}
+		transport, err := syslog.NewTransport(syslogNetwork, viper.GetString("syslog_addr"), syslogTLS, 30*time.Second)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return
+		}
+
+		useBuffer = true
+
+		startMetricsServer(metricsAddr)
+
+		backoff := batch.Backoff{Initial: retryInterval, Max: maxBackoff}
+
+		bufferSink = "syslog"
+		syslogBufferSize := viper.GetInt("syslogBufferSize")
+		kmsgbuff, bufferSender = startBufferedSink(packetChan, bufferSink, streamRecords, streamErrors, syslogBufferSize,
+			viper.GetInt("syslogBatchSize"), viper.GetDuration("syslogBatchInterval"), 1,
+			func(messages []*sarama.ProducerMessage) error {
+				return sendToSyslog(formatter, transport, messages, maxRetries, backoff)
+			})
+		log.Printf("Syslog output set to: %s %s, buffer size: %d", syslogNetwork, viper.GetString("syslog_addr"), syslogBufferSize)
+	}
+
+	var parquetSink *parquet.FileSink
+	if parquetDir != "" {
+		codec, err := parquet.ParseCodec(viper.GetString("parquetCompression"))
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return
+		}
+		parquetSink, err = parquet.NewFileSink(parquet.FileSinkConfig{
+			Dir:          parquetDir,
+			Prefix:       strings.TrimSuffix(filepath.Base(pcapFile), filepath.Ext(pcapFile)),
+			Schema:       parquet.RecordSchema,
+			Codec:        codec,
+			MaxBytes:     viper.GetInt64("parquetMaxBytes"),
+			Interval:     viper.GetDuration("parquetRotateInterval"),
+			RowGroupRows: viper.GetInt("parquetRowGroupRows"),
+			CreatedBy:    fmt.Sprintf("%s version %s", AppName, AppVersion),
+		})
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return
+		}
+		log.Printf("Parquet output set to: %s\n", parquetDir)
+	}
 
+	var fileSink *filesink.Sink
+	if ndjsonDir != "" {
+		compression, err := filesink.ParseCompression(viper.GetString("ndjsonCompression"))
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return
+		}
+		// Files are named after the pcap file or the capture interface
+		prefix := AppName
+		switch {
+		case pcapFile != "":
+			prefix = strings.TrimSuffix(filepath.Base(pcapFile), filepath.Ext(pcapFile))
+		case iface != "":
+			prefix = iface
+		}
+		// Protobuf records are length-delimited as on stdout
+		format := filesink.NDJSON
+		if outputEncoding == "protobuf" {
+			format = filesink.Protobuf
+		}
+		fileSink, err = filesink.New(filesink.Config{
+			Dir:         ndjsonDir,
+			Prefix:      prefix,
+			Format:      format,
+			Compression: compression,
+			MaxBytes:    viper.GetInt64("ndjsonMaxBytes"),
+			Interval:    viper.GetDuration("ndjsonRotateInterval"),
+			MaxFiles:    viper.GetInt("ndjsonMaxFiles"),
+			MaxAge:      viper.GetDuration("ndjsonMaxAge"),
+			Manifest:    viper.GetString("ndjsonManifest"),
+		})
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return
+		}
+		log.Printf("NDJSON file output set to: %s\n", ndjsonDir)
 	}
 
 	doneChan := make(chan struct{})
@@ -317,13 +1130,9 @@ func main() {
 	concurrency := runtime.NumCPU()
 	pipeline := parapipe.NewPipeline(concurrency, parseGTP)
 
-	go func() {
-		for packet := range packetChan {
-			pipeline.Push(packet)
-		}
-	}()
+	go pushPackets(packetChan, pipeline, reassembler)
 
-	go processOutput(pipeline, useKafka, kmsgbuff, doneChan)
+	go processOutput(pipeline, useBuffer, kmsgbuff, parquetSink, fileSink, doneChan)
 
 	if pcapFile != "" {
 		handle, err := pcap.OpenOffline(pcapFile)
@@ -332,6 +1141,13 @@ func main() {
 		}
 		defer handle.Close()
 
+		// Files are filtered only on request, the decoders skip unrelated traffic anyway
+		if customFilter := viper.GetString("bpf"); customFilter != "" {
+			if err := handle.SetBPFFilter(customFilter); err != nil {
+				log.Fatalf("Error setting BPF filter: %v", err)
+			}
+		}
+
 		dispatchPackets(handle, packetChan)
 
 	} else if iface != "" {
@@ -341,7 +1157,7 @@ func main() {
 		}
 		defer handle.Close()
 
-		if err := handle.SetBPFFilter("udp port 2123"); err != nil {
+		if err := handle.SetBPFFilter(bpfFilter); err != nil {
 			log.Fatalf("Error setting BPF filter: %v", err)
 		}
 
@@ -369,11 +1185,15 @@ func main() {
 		dispatchPackets(handle, packetChan)
 	}
 
-	pipeline.Close()
+	// The pipeline is closed by pushPackets once the remaining packets have been pushed
 	close(packetChan)
 	<-doneChan
 
-	if useKafka {
+	if bufferSender != nil {
+		log.Printf("Waiting for %s buffer to flush...", bufferSink)
+		bufferSender.Stop()
+		log.Printf("%s buffer flushed.", bufferSink)
+	} else if useBuffer {
 		log.Println("Waiting for Kafka buffer to flush...")
 		for kmsgbuff.RingBuffer.Size() > 0 {
 			time.Sleep(200 * time.Millisecond)
@@ -384,6 +1204,53 @@ func main() {
 	log.Println("All tasks completed. Exiting.")
 }
 
+// startMetricsServer serves the metrics, probes and about page in the background
+func startMetricsServer(addr string) {
+	go func() {
+		log.Printf("Starting metrics server on %s", addr)
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			log.Fatalf("Failed to start HTTP server: %v", err)
+		}
+	}()
+}
+
+// startBufferedSink creates the ring buffer of a network output and starts the batch sender that drains it
+// with the given number of workers. Records are sent to topic and rejected records to errorTopic, the
+// index, table or kind of record of the output.
+func startBufferedSink(packetChan chan gopacket.Packet, name, topic, errorTopic string, bufferSize, batchSize int,
+	interval time.Duration, workers int, send func([]*sarama.ProducerMessage) error) (*KafkaMsgBuff, *batch.Sender) {
+	ringBuffer := kafkabuff.NewRingBuffer(bufferSize)
+	if ringBuffer == nil {
+		log.Fatalf("Failed to create %s ring buffer", name)
+	}
+
+	sender := batch.NewConcurrentSender(ringBuffer, batchSize, interval, workers, send, func(err error) {
+		log.Printf("Error sending batch to %s: %v", name, err)
+	})
+	sender.Start()
+
+	isReady.Store(true)
+
+	go monitorOccupancy(packetChan, ringBuffer)
+
+	return &KafkaMsgBuff{Topic: topic, ErrorTopic: errorTopic, RingBuffer: ringBuffer}, sender
+}
+
+// monitorOccupancy updates the occupancy gauges of the packet channel and the ring buffer
+func monitorOccupancy(packetChan chan gopacket.Packet, ringBuffer *kafkabuff.RingBuffer) {
+	for {
+		packetChanOccupancy.Set(float64(len(packetChan)))
+
+		if ringBuffer != nil {
+			ringBufferOccupancy.Set(float64(ringBuffer.Size()))
+		} else {
+			ringBufferOccupancy.Set(0)
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+}
+
 func readinessHandler(w http.ResponseWriter, r *http.Request) {
 	if isReady.Load().(bool) {
 		w.WriteHeader(http.StatusOK)
@@ -451,6 +1318,89 @@ func createTLSConfig(caCertPath string) (*tls.Config, error) {
 	}, nil
 }
 
+// buildBPFFilter returns the capture filter for the enabled protocols
+func buildBPFFilter(decodeGTPU bool, gtpv2Ports []uint16, heuristic bool, defragment bool) string {
+	var filter string
+	if heuristic {
+		// Heuristic detection has to look at every UDP payload
+		filter = "udp"
+	} else {
+		filter = "udp port 2123 or udp port 8805 or udp port 3386"
+		if decodeGTPU {
+			filter += " or udp port 2152"
+		}
+		for _, port := range gtpv2Ports {
+			filter += fmt.Sprintf(" or udp port %d", port)
+		}
+	}
+	// Fragments after the first one carry no UDP header and would not match the port filter
+	if defragment {
+		filter += " or (ip[6:2] & 0x3fff != 0) or (ip6 and ip6[6] == 44)"
+	}
+	return filter
+}
+
+// pushPackets feeds captured packets into the parse pipeline. Outer tunnel layers are removed first
+// and IP fragments of the inner packet are reassembled when enabled.
+func pushPackets(packchan <-chan gopacket.Packet, pipeline *parapipe.Pipeline[gopacket.Packet, output], reassembler *defrag.Reassembler) {
+	defer pipeline.Close()
+
+	if reassembler == nil {
+		for packet := range packchan {
+			inner, metadata := tunnel.Decapsulate(packet)
+			pipeline.Push(tunnel.WithMetadata(inner, metadata))
+		}
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var prevReassembled, prevExpired, prevDropped uint64
+	updateMetrics := func() {
+		fragmentsReassembled.Add(float64(reassembler.Reassembled() - prevReassembled))
+		fragmentsExpired.Add(float64(reassembler.Expired() - prevExpired))
+		fragmentsDropped.Add(float64(reassembler.Dropped() - prevDropped))
+		prevReassembled, prevExpired, prevDropped = reassembler.Reassembled(), reassembler.Expired(), reassembler.Dropped()
+		fragmentsBufferedBytes.Set(float64(reassembler.Bytes()))
+	}
+	defer updateMetrics()
+
+	for {
+		select {
+		case packet, ok := <-packchan:
+			if !ok {
+				return
+			}
+			// A reassembled datagram takes the tunnel metadata of its last fragment
+			inner, metadata := tunnel.Decapsulate(packet)
+			if inner, ok = reassembler.Process(inner); ok {
+				pipeline.Push(tunnel.WithMetadata(inner, metadata))
+			}
+		case <-ticker.C:
+			reassembler.Expire(reassembler.Now())
+			updateMetrics()
+		}
+	}
+}
+
+// parsePorts parses a comma separated list of UDP ports
+func parsePorts(list string) ([]uint16, error) {
+	var ports []uint16
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		port, err := strconv.ParseUint(item, 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("'%s' is not a valid UDP port", item)
+		}
+		ports = append(ports, uint16(port))
+	}
+	return ports, nil
+}
+
 func dispatchPackets(handle *pcap.Handle, packchan chan gopacket.Packet) {
 	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
 	for packet := range packetSource.Packets() {
@@ -458,39 +1408,214 @@ func dispatchPackets(handle *pcap.Handle, packchan chan gopacket.Packet) {
 	}
 }
 
-func processOutput(pipeline *parapipe.Pipeline[gopacket.Packet, []byte], useKafka bool, kmsgbuff *KafkaMsgBuff, doneChan chan<- struct{}) {
-	defer finalizeOutput()
+func processOutput(pipeline *parapipe.Pipeline[gopacket.Packet, output], useBuffer bool, kmsgbuff *KafkaMsgBuff, parquetSink *parquet.FileSink, fileSink *filesink.Sink, doneChan chan<- struct{}) {
+	if parquetSink == nil && fileSink == nil {
+		defer finalizeOutput()
+	}
 
-	for jsonData := range pipeline.Out() {
-		if useKafka {
-			err := sendToKafka(jsonData, kmsgbuff)
+	emit := func(record output) {
+		switch {
+		case useBuffer:
+			err := sendToBuffer(record, kmsgbuff)
 			if err != nil {
-				log.Printf("Error sending to Kafka: %v", err)
+				log.Printf("Error buffering record: %v", err)
+			}
+		case parquetSink != nil:
+			outputToParquet(record, parquetSink)
+		case fileSink != nil:
+			if err := fileSink.Write(record.data); err != nil {
+				log.Fatalf("Error writing NDJSON file: %v", err)
+			}
+		default:
+			outputToStdout(record.data)
+		}
+	}
+
+	// Rejected records go to a separate Kafka topic, index or table, or to the error file
+	var errbuff *KafkaMsgBuff
+	if useBuffer {
+		errbuff = &KafkaMsgBuff{Topic: kmsgbuff.ErrorTopic, RingBuffer: kmsgbuff.RingBuffer}
+	}
+	errorFile := &rejectedFile{path: errorFilePath}
+	emitRejected := func(record output) {
+		if useBuffer {
+			if err := sendToBuffer(record, errbuff); err != nil {
+				log.Printf("Error buffering record: %v", err)
+			}
+		} else if err := errorFile.Write(record.data); err != nil {
+			log.Printf("Error writing rejected record: %v", err)
+		}
+	}
+
+	// Expired flow records are written by this goroutine as well to keep a single writer
+	var flowTicker <-chan time.Time
+	if flowAggregator != nil {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		flowTicker = ticker.C
+	}
+	// Files are closed at the end of their interval when no records arrive
+	var rotateTicker <-chan time.Time
+	if fileSink != nil {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		rotateTicker = ticker.C
+	}
+
+	out := pipeline.Out()
+	var prevDropped uint64
+loop:
+	for {
+		select {
+		case record, ok := <-out:
+			if !ok {
+				break loop
+			}
+			for _, r := range append([]output{record}, record.followOn...) {
+				if r.rejected {
+					emitRejected(r)
+				} else {
+					emit(r)
+				}
+			}
+		case <-flowTicker:
+			exportFlows(flowAggregator.Expire(flowAggregator.Now()), emit)
+			gpduFlowsActive.Set(float64(flowAggregator.Len()))
+			dropped := flowAggregator.Dropped()
+			gpduPacketsDropped.Add(float64(dropped - prevDropped))
+			prevDropped = dropped
+		case <-rotateTicker:
+			if err := fileSink.Rotate(); err != nil {
+				log.Fatalf("Error closing NDJSON file: %v", err)
 			}
-		} else {
-			outputToStdout(jsonData)
 		}
 	}
 
+	if flowAggregator != nil {
+		exportFlows(flowAggregator.Flush(), emit)
+		gpduFlowsActive.Set(0)
+	}
+
+	if parquetSink != nil {
+		if err := parquetSink.Close(); err != nil {
+			log.Fatalf("Error writing Parquet file: %v", err)
+		}
+		log.Printf("Parquet files written: %d", parquetSink.FilesWritten())
+	}
+
+	if fileSink != nil {
+		if err := fileSink.Close(); err != nil {
+			log.Fatalf("Error closing NDJSON file: %v", err)
+		}
+		log.Printf("NDJSON files written: %d", fileSink.FilesWritten())
+	}
+
+	if err := errorFile.Close(); err != nil {
+		log.Printf("Error closing %s: %v", errorFilePath, err)
+	}
+
 	doneChan <- struct{}{}
 }
 
-func parseGTP(packet gopacket.Packet) ([]byte, bool) {
-	gtpLayer := packet.Layer(gtp2.LayerTypeGTPv2)
-	if gtpLayer == nil {
+// exportFlows encodes flow records and passes them to the output
+func exportFlows(records []flows.Record, emit func(output)) {
+	for _, record := range records {
+		data, err := encodeRecord(record)
+		if err != nil {
+			log.Printf("Error encoding record: %v", err)
+			continue
+		}
+		emit(output{data: data, protocol: "GTP-U"})
+		gpduFlowsExported.Inc()
+	}
+}
+
+// gtpv2Layer returns the GTPv2 layer of a packet. With heuristic detection enabled,
+// UDP payloads on other ports are checked and decoded as GTPv2 when they match.
+func gtpv2Layer(packet gopacket.Packet) (*gtp2.GTPv2, bool) {
+	if gtpLayer := packet.Layer(gtp2.LayerTypeGTPv2); gtpLayer != nil {
+		gtp, ok := gtpLayer.(*gtp2.GTPv2)
+		if !ok {
+			log.Println("Error asserting layer to GTPv2")
+		}
+		return gtp, ok
+	}
+
+	if !gtpv2Heuristic {
 		return nil, false
 	}
-	gtp, ok := gtpLayer.(*gtp2.GTPv2)
-	if !ok {
-		log.Println("Error asserting layer to GTPv2")
+	udpLayer := packet.Layer(layers.LayerTypeUDP)
+	if udpLayer == nil || !gtp2.IsGTPv2(udpLayer.LayerPayload()) {
+		return nil, false
+	}
+	gtp := &gtp2.GTPv2{}
+	if err := gtp.DecodeFromBytes(udpLayer.LayerPayload(), gopacket.NilDecodeFeedback); err != nil {
 		return nil, false
 	}
+	gtpv2HeuristicMatches.Inc()
+	return gtp, true
+}
+
+func parseGTP(packet gopacket.Packet) (output, bool) {
+	if gtpLayer := packet.Layer(gtp1.LayerTypeGTPv1); gtpLayer != nil {
+		gtp, ok := gtpLayer.(*gtp1.GTPv1)
+		if !ok {
+			log.Println("Error asserting layer to GTPv1")
+			return output{}, false
+		}
+		return parseGTPv1(packet, gtp, "GTPv1-C")
+	}
+
+	if packet.Layer(pfcp.LayerTypePFCP) != nil {
+		// Messages sent with the follow-on flag are decoded as consecutive PFCP layers
+		var records []output
+		for _, layer := range packet.Layers() {
+			if layer.LayerType() != pfcp.LayerTypePFCP {
+				continue
+			}
+			if record, ok := parsePFCP(packet, layer); ok {
+				records = append(records, record)
+			}
+		}
+		if len(records) == 0 {
+			return output{}, false
+		}
+		records[0].followOn = records[1:]
+		return records[0], true
+	}
+
+	if gtpLayer := packet.Layer(gtpprime.LayerTypeGTPPrime); gtpLayer != nil {
+		return parseGTPPrime(packet, gtpLayer)
+	}
+
+	if gtpLayer := packet.Layer(gtpu.LayerTypeGTPU); gtpLayer != nil {
+		gtp, ok := gtpLayer.(*gtpu.GTPU)
+		if !ok {
+			log.Println("Error asserting layer to GTPU")
+			return output{}, false
+		}
+		// Only control messages are emitted, user data is summarised into flow records when enabled
+		if gtp.IsGPDU() {
+			if flowAggregator != nil {
+				flowAggregator.Add(packet)
+			}
+			return output{}, false
+		}
+		return parseGTPv1(packet, &gtp.GTPv1, "GTP-U")
+	}
+
+	gtp, ok := gtpv2Layer(packet)
+	if !ok {
+		return parseDecodeFailure(packet)
+	}
 
 	var ieItems []IE
+	var decodeErrors []DecodeError
+	direction := gtp2ie.MessageDirection(gtp.MessageType)
 	for _, ie := range gtp.IEs {
-		ieName, processedContent, err := gtp2ie.ProcessIE(ie)
+		ieName, processedContent, err := gtp2ie.ProcessIEWithDirection(ie, direction)
 		if err != nil {
-			log.Printf("Error processing IE: %v", err)
+			decodeErrors = append(decodeErrors, ieDecodeError("GTPv2", uint16(ie.Type), gtp.Contents, ie.Content, err))
 			continue
 		}
 
@@ -510,6 +1635,8 @@ func parseGTP(packet gopacket.Packet) ([]byte, bool) {
 
 	packetData := GTPv2Packet{
 		Timestamp:        packet.Metadata().Timestamp,
+		Tunnel:           tunnel.MetadataOf(packet),
+		Endpoint:         endpoint.FromPacket(packet, captureInterface),
 		Version:          gtp.Version,
 		PiggybackingFlag: gtp.PiggybackingFlag,
 		TEIDflag:         gtp.TEIDflag,
@@ -520,40 +1647,521 @@ func parseGTP(packet gopacket.Packet) ([]byte, bool) {
 		SequenceNumber:   gtp.SequenceNumber,
 		Spare:            gtp.Spare,
 		IEs:              ieItems,
+		DecodeErrors:     decodeErrors,
+	}
+
+	return marshalRecord(packetData, "GTPv2", decodeErrors)
+}
+
+// parseGTPv1 converts a GTPv1-C or GTP-U message to JSON, both share the GTPv1 header and IE format
+func parseGTPv1(packet gopacket.Packet, gtp *gtp1.GTPv1, protocol string) (output, bool) {
+	var ieItems []IE
+	var decodeErrors []DecodeError
+	direction := gtp1ie.MessageDirection(gtp.MessageType)
+	for _, ie := range gtp.IEs {
+		ieName, processedContent, err := gtp1ie.ProcessIEWithDirection(ie, direction)
+		if err != nil {
+			decodeErrors = append(decodeErrors, ieDecodeError(protocol, uint16(ie.Type), gtp.Contents, ie.Content, err))
+			continue
+		}
+
+		ieTypeCounter.WithLabelValues(ieName).Inc()
+
+		ieItems = append(ieItems, IE{
+			Type:  ieName,
+			Value: processedContent,
+		})
+	}
+
+	var extensionHeaders []ExtensionHeader
+	for _, eh := range gtp.ExtensionHeaders {
+		extensionHeaders = append(extensionHeaders, ExtensionHeader{
+			Type:    eh.Type,
+			Content: hex.EncodeToString(eh.Content),
+		})
+	}
+
+	packetData := GTPv1Packet{
+		Timestamp:           packet.Metadata().Timestamp,
+		Tunnel:              tunnel.MetadataOf(packet),
+		Endpoint:            endpoint.FromPacket(packet, captureInterface),
+		Protocol:            protocol,
+		Version:             gtp.Version,
+		ProtocolType:        gtp.ProtocolType,
+		ExtensionHeaderFlag: gtp.ExtensionHeaderFlag,
+		SequenceNumberFlag:  gtp.SequenceNumberFlag,
+		NPDUNumberFlag:      gtp.NPDUNumberFlag,
+		MessageType:         gtp.MessageType,
+		MessageLength:       gtp.MessageLength,
+		TEID:                gtp.TEID,
+		ExtensionHeaders:    extensionHeaders,
+		IEs:                 ieItems,
+		DecodeErrors:        decodeErrors,
+	}
+	if gtp.SequenceNumberFlag {
+		packetData.SequenceNumber = &gtp.SequenceNumber
+	}
+	if gtp.NPDUNumberFlag {
+		packetData.NPDUNumber = &gtp.NPDUNumber
 	}
 
-	jsonData, err := json.MarshalIndent(packetData, "", "    ")
+	return marshalRecord(packetData, protocol, decodeErrors)
+}
+
+func parsePFCP(packet gopacket.Packet, pfcpLayer gopacket.Layer) (output, bool) {
+	msg, ok := pfcpLayer.(*pfcp.PFCP)
+	if !ok {
+		log.Println("Error asserting layer to PFCP")
+		return output{}, false
+	}
+
+	var ieItems []IE
+	var decodeErrors []DecodeError
+	for _, ie := range msg.IEs {
+		ieName, processedContent, err := pfcpie.ProcessIE(ie)
+		if err != nil {
+			decodeErrors = append(decodeErrors, ieDecodeError("PFCP", ie.Type, msg.Contents, ie.Content, err))
+			continue
+		}
+
+		ieTypeCounter.WithLabelValues(ieName).Inc()
+
+		ieItems = append(ieItems, IE{
+			Type:  ieName,
+			Value: processedContent,
+		})
+	}
+
+	packetData := PFCPPacket{
+		Timestamp:           packet.Metadata().Timestamp,
+		Tunnel:              tunnel.MetadataOf(packet),
+		Endpoint:            endpoint.FromPacket(packet, captureInterface),
+		Protocol:            "PFCP",
+		Version:             msg.Version,
+		FollowOnFlag:        msg.FollowOnFlag,
+		MessagePriorityFlag: msg.MessagePriorityFlag,
+		SEIDFlag:            msg.SEIDFlag,
+		MessageType:         msg.MessageType,
+		MessageLength:       msg.MessageLength,
+		SequenceNumber:      msg.SequenceNumber,
+		IEs:                 ieItems,
+		DecodeErrors:        decodeErrors,
+	}
+	if msg.SEIDFlag {
+		packetData.SEID = &msg.SEID
+	}
+	if msg.MessagePriorityFlag {
+		packetData.MessagePriority = &msg.MessagePriority
+	}
+
+	return marshalRecord(packetData, "PFCP", decodeErrors)
+}
+
+func parseGTPPrime(packet gopacket.Packet, gtpLayer gopacket.Layer) (output, bool) {
+	gtp, ok := gtpLayer.(*gtpprime.GTPPrime)
+	if !ok {
+		log.Println("Error asserting layer to GTPPrime")
+		return output{}, false
+	}
+
+	var ieItems []IE
+	var decodeErrors []DecodeError
+	for _, ie := range gtp.IEs {
+		ieName, processedContent, err := gtpprimeie.ProcessIE(ie)
+		if err != nil {
+			decodeErrors = append(decodeErrors, ieDecodeError("GTP'", uint16(ie.Type), gtp.Contents, ie.Content, err))
+			continue
+		}
+
+		ieTypeCounter.WithLabelValues(ieName).Inc()
+
+		ieItems = append(ieItems, IE{
+			Type:  ieName,
+			Value: processedContent,
+		})
+	}
+
+	packetData := GTPPrimePacket{
+		Timestamp:      packet.Metadata().Timestamp,
+		Tunnel:         tunnel.MetadataOf(packet),
+		Endpoint:       endpoint.FromPacket(packet, captureInterface),
+		Protocol:       "GTP'",
+		Version:        gtp.Version,
+		ShortHeader:    gtp.ShortHeader,
+		MessageType:    gtp.MessageType,
+		MessageLength:  gtp.MessageLength,
+		SequenceNumber: gtp.SequenceNumber,
+		IEs:            ieItems,
+		DecodeErrors:   decodeErrors,
+	}
+
+	return marshalRecord(packetData, "GTP'", decodeErrors)
+}
+
+// marshalRecord encodes a record. In strict mode records with decode errors are rejected to the error stream.
+func marshalRecord(record interface{}, protocol string, decodeErrors []DecodeError) (output, bool) {
+	rejected := strictMode && len(decodeErrors) > 0
+	encode := encodeRecord
+	if rejected {
+		encode = encodeRejected
+	}
+	data, err := encode(record)
+	if err != nil {
+		log.Printf("Error encoding record: %v", err)
+		return output{}, false
+	}
+
+	if rejected {
+		decodeRejected.WithLabelValues(protocol).Inc()
+	}
+	return output{data: data, protocol: protocol, rejected: rejected}, true
+}
+
+// ieDecodeError records an IE whose value could not be decoded
+func ieDecodeError(protocol string, ieType uint16, message, content []byte, err error) DecodeError {
+	decodeErrorsCounter.WithLabelValues(protocol, "ie").Inc()
+	return DecodeError{
+		IEType: &ieType,
+		Offset: ieOffset(message, content),
+		Reason: err.Error(),
+		Raw:    hex.EncodeToString(content),
+	}
+}
+
+// ieOffset returns the offset of an IE value in its message, or -1 when it is not found. The IE
+// splitters return subslices of the message, whose offset is the difference of the capacities. The
+// offset is checked against the message, values copied by a decoder are searched for instead.
+func ieOffset(message, content []byte) int {
+	offset := cap(message) - cap(content)
+	if offset >= 0 && offset+len(content) <= len(message) && bytes.Equal(message[offset:offset+len(content)], content) {
+		return offset
+	}
+	return bytes.Index(message, content)
+}
+
+// failureProtocols names the protocols decoded on their own UDP ports
+var failureProtocols = map[gopacket.LayerType]string{
+	gtp2.LayerTypeGTPv2:        "GTPv2",
+	gtpu.LayerTypeGTPU:         "GTP-U",
+	pfcp.LayerTypePFCP:         "PFCP",
+	gtpprime.LayerTypeGTPPrime: "GTP'",
+}
+
+// parseDecodeFailure reports a UDP payload rejected by one of the protocol decoders. Such messages
+// have no partial result and always go to the error stream.
+func parseDecodeFailure(packet gopacket.Packet) (output, bool) {
+	failure := packet.ErrorLayer()
+	udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if failure == nil || !ok {
+		return output{}, false
+	}
+	protocol, ok := failureProtocols[udp.NextLayerType()]
+	if !ok {
+		return output{}, false
+	}
+	// GTPv1-C shares the GTPv2 port and is told apart by the version bits
+	if protocol == "GTPv2" && len(udp.Payload) > 0 && udp.Payload[0]>>5 == 1 {
+		protocol = "GTPv1-C"
+	}
+	decodeErrorsCounter.WithLabelValues(protocol, "message").Inc()
+	decodeRejected.WithLabelValues(protocol).Inc()
+
+	record := DecodeFailure{
+		Timestamp:  packet.Metadata().Timestamp,
+		Tunnel:     tunnel.MetadataOf(packet),
+		Endpoint:   endpoint.FromPacket(packet, captureInterface),
+		RecordType: "decodeError",
+		Protocol:   protocol,
+		DecodeErrors: []DecodeError{{
+			Reason: failure.Error().Error(),
+			Raw:    hex.EncodeToString(udp.Payload),
+		}},
+	}
+
+	jsonData, err := encodeRejected(record)
 	if err != nil {
 		log.Printf("Error converting to JSON: %v", err)
-		return nil, false
+		return output{}, false
+	}
+	return output{data: jsonData, protocol: protocol, rejected: true}, true
+}
+
+// registerAvroSchema registers the Avro schema of the records, retrying while the registry is unavailable
+func registerAvroSchema(registry *avroenc.Registry, subject string, maxRetries int, retryInterval time.Duration) (uint32, error) {
+	for attempt := 1; ; attempt++ {
+		id, err := registry.Register(subject, avroenc.Schema())
+		if err == nil {
+			return id, nil
+		}
+		if maxRetries > 0 && attempt >= maxRetries {
+			return 0, err
+		}
+		log.Printf("Schema registration attempt %d failed: %v, retrying in %v", attempt, err, retryInterval)
+		time.Sleep(retryInterval)
 	}
-	return jsonData, true
 }
 
-func sendToKafka(data []byte, msgbuff *KafkaMsgBuff) error {
+// sendToBuffer adds a record to the ring buffer of the Kafka, Elasticsearch, ClickHouse, webhook or syslog sender.
+// The protocol is kept in the metadata of the message, which is not sent to Kafka.
+func sendToBuffer(record output, msgbuff *KafkaMsgBuff) error {
 	if msgbuff == nil || msgbuff.RingBuffer == nil {
 		return fmt.Errorf("invalid KafkaMsgBuff")
 	}
 	msg := &sarama.ProducerMessage{
-		Topic: msgbuff.Topic,
-		Value: sarama.ByteEncoder(data),
+		Topic:    msgbuff.Topic,
+		Value:    sarama.ByteEncoder(record.data),
+		Metadata: record.protocol,
 	}
 	msgbuff.RingBuffer.Add(msg)
 	return nil
 }
 
+// sendToElasticsearch indexes a batch of records, the topic of a message is the prefix of its index
+func sendToElasticsearch(client *elastic.Client, messages []*sarama.ProducerMessage, maxRetries int, backoff batch.Backoff) error {
+	docs := make([]elastic.Document, 0, len(messages))
+	for _, msg := range messages {
+		data, err := msg.Value.Encode()
+		if err != nil {
+			return err
+		}
+		protocol, _ := msg.Metadata.(string)
+		doc, err := elastic.NewDocument(msg.Topic, protocol, data)
+		if err != nil {
+			log.Printf("Error converting record for Elasticsearch: %v", err)
+			elasticsearchFailed.Inc()
+			continue
+		}
+		docs = append(docs, doc)
+	}
+
+	result, err := client.Bulk(docs, maxRetries, backoff)
+	elasticsearchIndexed.Add(float64(result.Indexed))
+	elasticsearchFailed.Add(float64(result.Failed))
+	if len(result.Errors) > 0 {
+		log.Printf("Elasticsearch rejected %d records, first reasons: %s", result.Failed, strings.Join(result.Errors, "; "))
+	}
+	return err
+}
+
+// sendToClickHouse inserts a batch of records, the topic of a message is its table
+func sendToClickHouse(client *clickhouse.Client, messages []*sarama.ProducerMessage, maxRetries int, backoff batch.Backoff) error {
+	var tables []string
+	rows := map[string][][]byte{}
+	for _, msg := range messages {
+		data, err := msg.Value.Encode()
+		if err != nil {
+			return err
+		}
+		protocol, _ := msg.Metadata.(string)
+		row, err := clickhouse.Row(protocol, data)
+		if err != nil {
+			log.Printf("Error converting record for ClickHouse: %v", err)
+			clickhouseFailed.Inc()
+			continue
+		}
+		if _, ok := rows[msg.Topic]; !ok {
+			tables = append(tables, msg.Topic)
+		}
+		rows[msg.Topic] = append(rows[msg.Topic], row)
+	}
+
+	var errs []error
+	for _, table := range tables {
+		if err := client.Insert(table, rows[table], maxRetries, backoff); err != nil {
+			clickhouseFailed.Add(float64(len(rows[table])))
+			errs = append(errs, err)
+			continue
+		}
+		clickhouseInserted.Add(float64(len(rows[table])))
+	}
+	return errors.Join(errs...)
+}
+
+// sendToWebhook posts a batch of records to recordURL and the rejected records to errorURL in separate
+// requests, the latter marked as the error stream even when both URLs are the same
+func sendToWebhook(client *webhook.Client, recordURL, errorURL string, messages []*sarama.ProducerMessage, maxRetries int, backoff batch.Backoff) error {
+	var records, rejected [][]byte
+	for _, msg := range messages {
+		data, err := msg.Value.Encode()
+		if err != nil {
+			return err
+		}
+		if msg.Topic == streamErrors {
+			rejected = append(rejected, data)
+		} else {
+			records = append(records, data)
+		}
+	}
+
+	var errs []error
+	post := func(send func(string, [][]byte, int, batch.Backoff) error, u string, records [][]byte) {
+		if len(records) == 0 {
+			return
+		}
+		if err := send(u, records, maxRetries, backoff); err != nil {
+			webhookFailed.Add(float64(len(records)))
+			errs = append(errs, err)
+			return
+		}
+		webhookSent.Add(float64(len(records)))
+	}
+	post(client.Post, recordURL, records)
+	post(client.PostErrors, errorURL, rejected)
+	return errors.Join(errs...)
+}
+
+// sendToSyslog sends a batch of records as syslog or journald messages
+func sendToSyslog(formatter *syslog.Formatter, transport *syslog.Transport, messages []*sarama.ProducerMessage, maxRetries int, backoff batch.Backoff) error {
+	lines := make([][]byte, 0, len(messages))
+	for _, msg := range messages {
+		data, err := msg.Value.Encode()
+		if err != nil {
+			return err
+		}
+		protocol, _ := msg.Metadata.(string)
+		record, err := syslog.ParseRecord(protocol, data, msg.Topic == streamErrors)
+		if err != nil {
+			log.Printf("Error converting record for syslog: %v", err)
+			syslogFailed.Inc()
+			continue
+		}
+		if transport.Network() == "journald" {
+			lines = append(lines, formatter.FormatJournal(record, data))
+		} else {
+			lines = append(lines, formatter.Format(record, data))
+		}
+	}
+
+	sent, err := transport.Send(lines, maxRetries, backoff)
+	syslogSent.Add(float64(sent))
+	syslogFailed.Add(float64(len(lines) - sent))
+	return err
+}
+
+// encodeRecord converts a record to the selected output encoding
+func encodeRecord(record interface{}) ([]byte, error) {
+	switch outputEncoding {
+	case "pretty":
+		return json.MarshalIndent(record, "", "    ")
+	case "protobuf":
+		return protoenc.Marshal(record)
+	case "avro":
+		return avroenc.Marshal(avroSchemaID, record)
+	case "csv", "tsv":
+		// Columns are selected from the JSON form, so that they follow --format like the JSON output
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		return csvEncoder.Encode(data)
+	}
+	return json.Marshal(record)
+}
+
+// encodeRejected converts a record for the error stream, which stays JSON so that it can be
+// inspected without the schema or the column set
+func encodeRejected(record interface{}) ([]byte, error) {
+	switch outputEncoding {
+	case "protobuf", "avro", "csv", "tsv":
+		return json.Marshal(record)
+	}
+	return encodeRecord(record)
+}
+
+// outputToStdout writes a record with a single call, so that readers of a pipe never see half a record
 func outputToStdout(data []byte) {
-	if isFirstOutput {
-		fmt.Println("[") // Start of array
-		isFirstOutput = false
-	} else {
-		fmt.Println(",")
+	buf := make([]byte, 0, len(data)+binary.MaxVarintLen64)
+	switch outputEncoding {
+	case "ndjson":
+		buf = append(append(buf, data...), '\n')
+	case "protobuf":
+		// Records are length-delimited like protobuf streams written by writeDelimitedTo
+		buf = protoenc.AppendDelimited(buf, data)
+	case "csv", "tsv":
+		// Rows are already terminated, the header precedes the first one
+		if isFirstOutput {
+			buf = append(buf, csvEncoder.Header()...)
+			isFirstOutput = false
+		}
+		buf = append(buf, data...)
+	default:
+		if isFirstOutput {
+			buf = append(buf, "[\n"...) // Start of array
+			isFirstOutput = false
+		} else {
+			buf = append(buf, ",\n"...)
+		}
+		buf = append(buf, data...)
+	}
+	os.Stdout.Write(buf)
+}
+
+// outputToParquet flattens a JSON record to a row of the Parquet files
+func outputToParquet(record output, sink *parquet.FileSink) {
+	ts, row, err := parquet.RecordRow(record.protocol, record.data)
+	if err != nil {
+		log.Printf("Error converting record to Parquet: %v", err)
+		return
+	}
+	if err := sink.Write(ts, row); err != nil {
+		log.Fatalf("Error writing Parquet file: %v", err)
 	}
-	fmt.Print(string(data))
+}
+
+// rejectedFile writes the error stream one record per line, to stderr unless a path is set. The file is
+// opened with the first rejected record, so that runs without errors leave no file behind.
+type rejectedFile struct {
+	path string
+	file *os.File
+}
+
+// Write appends a record as a single line, so that the error stream can be processed line by line
+func (r *rejectedFile) Write(data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return fmt.Errorf("failed to compact JSON: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	if r.file == nil && r.path == "" {
+		r.file = os.Stderr
+	}
+	if r.file == nil {
+		file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		r.file = file
+	}
+	_, err := r.file.Write(buf.Bytes())
+	return err
+}
+
+// Close closes the file if it was opened
+func (r *rejectedFile) Close() error {
+	if r.file == nil || r.file == os.Stderr {
+		r.file = nil
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
 }
 
 func finalizeOutput() {
-	if !isFirstOutput {
+	switch outputEncoding {
+	case "ndjson", "protobuf":
+		return
+	case "csv", "tsv":
+		// A file without records still gets its header
+		if isFirstOutput {
+			os.Stdout.Write(csvEncoder.Header())
+		}
+		return
+	}
+	if isFirstOutput {
+		fmt.Println("[]")
+	} else {
 		fmt.Println("\n]") // End of array
 	}
 }
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/vagabundor/gtp2json/pkg/batch"
+	"github.com/vagabundor/gtp2json/pkg/webhook"
+)
+
+// buildGTPv2 serialises a GTPv2 Echo Request carrying the given IEs
+func buildGTPv2(t *testing.T, ies []byte) gopacket.Packet {
+	t.Helper()
+	message := append([]byte{0x40, 0x01, 0x00, byte(4 + len(ies)), 0x00, 0x00, 0x01, 0x00}, ies...)
+	return buildUDP(t, 2123, message)
+}
+
+// buildUDP serialises the message as the payload of a UDP datagram to the given port
+func buildUDP(t *testing.T, port layers.UDPPort, message []byte) gopacket.Packet {
+	t.Helper()
+	eth := &layers.Ethernet{SrcMAC: net.HardwareAddr{0, 1, 2, 3, 4, 5}, DstMAC: net.HardwareAddr{0, 1, 2, 3, 4, 6}, EthernetType: layers.EthernetTypeIPv4}
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.ParseIP("10.0.0.2"), DstIP: net.ParseIP("10.0.0.1")}
+	udp := &layers.UDP{SrcPort: port, DstPort: port}
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, eth, ip, udp, gopacket.Payload(message)); err != nil {
+		t.Fatal(err)
+	}
+	return gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+}
+
+var (
+	// recoveryIE is a valid Recovery IE
+	recoveryIE = []byte{0x03, 0x00, 0x01, 0x00, 0x05}
+	// truncatedFTEID is an F-TEID IE without TEID and address
+	truncatedFTEID = []byte{0x57, 0x00, 0x01, 0x00, 0x8a}
+)
+
+func TestDecodeErrors(t *testing.T) {
+	outputEncoding = "ndjson"
+	defer func() { outputEncoding, strictMode = "", false }()
+
+	tests := []struct {
+		name         string
+		strict       bool
+		ies          []byte
+		wantRejected bool
+		wantIEs      int
+		wantType     string
+		wantErrors   []DecodeError
+	}{
+		{
+			name:    "Lenient",
+			ies:     append(append([]byte{}, recoveryIE...), truncatedFTEID...),
+			wantIEs: 1,
+			wantErrors: []DecodeError{
+				{Offset: 17, Raw: "8a"},
+			},
+		},
+		{
+			name:         "Strict",
+			strict:       true,
+			ies:          append(append([]byte{}, truncatedFTEID...), recoveryIE...),
+			wantRejected: true,
+			wantIEs:      1,
+			wantErrors: []DecodeError{
+				{Offset: 12, Raw: "8a"},
+			},
+		},
+		{
+			name:   "Strict Without Errors",
+			strict: true,
+			ies:    recoveryIE,
+			// Records without decode errors are never rejected
+			wantIEs: 1,
+		},
+		{
+			name:         "Message Rejected by Decoder",
+			ies:          []byte{0x03, 0x00, 0x09, 0x00, 0x05},
+			wantRejected: true,
+			wantType:     "decodeError",
+			wantErrors: []DecodeError{
+				{Raw: "40010009000001000300090005"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strictMode = tt.strict
+			record, ok := parseGTP(buildGTPv2(t, tt.ies))
+			if !ok {
+				t.Fatal("no record")
+			}
+			if record.rejected != tt.wantRejected || record.protocol != "GTPv2" {
+				t.Errorf("rejected = %v, protocol = %s, want %v, GTPv2", record.rejected, record.protocol, tt.wantRejected)
+			}
+
+			var got struct {
+				RecordType   string            `json:"recordType"`
+				IEs          []json.RawMessage `json:"ies"`
+				DecodeErrors []DecodeError     `json:"decodeErrors"`
+			}
+			if err := json.Unmarshal(record.data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.RecordType != tt.wantType || len(got.IEs) != tt.wantIEs {
+				t.Errorf("recordType = %q with %d IEs, want %q with %d", got.RecordType, len(got.IEs), tt.wantType, tt.wantIEs)
+			}
+			if len(got.DecodeErrors) != len(tt.wantErrors) {
+				t.Fatalf("decodeErrors = %+v, want %+v", got.DecodeErrors, tt.wantErrors)
+			}
+			for i, want := range tt.wantErrors {
+				if e := got.DecodeErrors[i]; e.Offset != want.Offset || e.Raw != want.Raw || e.Reason == "" {
+					t.Errorf("decodeErrors[%d] = %+v, want offset %d and raw %s", i, e, want.Offset, want.Raw)
+				}
+			}
+		})
+	}
+}
+
+func TestPFCPFollowOn(t *testing.T) {
+	outputEncoding = "ndjson"
+	defer func() { outputEncoding = "" }()
+
+	// Two Heartbeat Requests with a Recovery Time Stamp, the first one with the follow-on flag
+	heartbeat := []byte{0x20, 0x01, 0x00, 0x0c, 0x00, 0x00, 0x01, 0x00, 0x00, 0x60, 0x00, 0x04, 0xe5, 0x00, 0x00, 0x00}
+	message := append(append([]byte{}, heartbeat...), heartbeat...)
+	message[0] |= 0x04
+	message[len(heartbeat)+6] = 0x02
+
+	record, ok := parseGTP(buildUDP(t, 8805, message))
+	if !ok {
+		t.Fatal("no record")
+	}
+	records := append([]output{record}, record.followOn...)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	for i, r := range records {
+		var got PFCPPacket
+		if err := json.Unmarshal(r.data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if r.protocol != "PFCP" || got.SequenceNumber != uint32(i+1) || got.FollowOnFlag != (i == 0) || len(got.IEs) != 1 {
+			t.Errorf("records[%d] = %s", i, r.data)
+		}
+	}
+}
+
+func TestIEOffset(t *testing.T) {
+	message := []byte{0x40, 0x01, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05}
+	tests := []struct {
+		name    string
+		content []byte
+		want    int
+	}{
+		{"Subslice", message[12:13], 12},
+		{"Empty Subslice", message[13:], 13},
+		{"Copied Value", []byte{0x05}, 12},
+		{"Foreign Value", []byte{0x07}, -1},
+	}
+	for _, tt := range tests {
+		if got := ieOffset(message[:13], tt.content); got != tt.want {
+			t.Errorf("%s: ieOffset() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRejectedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.ndjson")
+	f := &rejectedFile{path: path}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file created without records: %v", err)
+	}
+
+	for _, record := range []string{"{\n    \"n\": 1\n}", `{"n":2}`} {
+		if err := f.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Write([]byte("not json")); err == nil {
+		t.Error("Write() of invalid JSON did not fail")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records of a later run are appended
+	f = &rejectedFile{path: path}
+	if err := f.Write([]byte(`{"n":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"; string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+}
+
+func TestRejectedFileStderr(t *testing.T) {
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(orig *os.File) { os.Stderr = orig }(os.Stderr)
+	os.Stderr = stderr
+
+	// Without a path the records go to stderr, which is left open
+	f := &rejectedFile{}
+	if err := f.Write([]byte("{\n    \"n\": 1\n}")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stderr.WriteString("log\n"); err != nil {
+		t.Fatalf("stderr closed: %v", err)
+	}
+
+	data, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"n\":1}\nlog\n"; string(data) != want {
+		t.Errorf("stderr = %q, want %q", data, want)
+	}
+}
+
+func TestSendToWebhook(t *testing.T) {
+	got := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		got[req.Header.Get(webhook.StreamHeader)] += string(body)
+	}))
+	defer server.Close()
+
+	client, err := webhook.NewClient(webhook.Config{Format: webhook.NDJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []*sarama.ProducerMessage{
+		{Topic: streamRecords, Value: sarama.StringEncoder(`{"n":1}`)},
+		{Topic: streamErrors, Value: sarama.StringEncoder(`{"n":2}`)},
+		{Topic: streamRecords, Value: sarama.StringEncoder(`{"n":3}`)},
+	}
+	// The error stream falls back to the URL of the records
+	if err := sendToWebhook(client, server.URL, server.URL, messages, 1, batch.Backoff{Initial: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"": "{\"n\":1}\n{\"n\":3}\n", "errors": "{\"n\":2}\n"}
+	if len(got) != len(want) || got[""] != want[""] || got["errors"] != want["errors"] {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+}
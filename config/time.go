@@ -0,0 +1,28 @@
+package config
+
+import "time"
+
+var (
+	timeFormat   = "rfc3339"
+	timeLocation = time.UTC
+)
+
+// SetTimeFormat updates the global format of decoded timestamps (rfc3339, epochms)
+func SetTimeFormat(format string) {
+	timeFormat = format
+}
+
+// GetTimeFormat retrieves the current global format of decoded timestamps
+func GetTimeFormat() string {
+	return timeFormat
+}
+
+// SetTimeLocation updates the timezone used to render RFC 3339 timestamps
+func SetTimeLocation(location *time.Location) {
+	timeLocation = location
+}
+
+// GetTimeLocation retrieves the timezone used to render RFC 3339 timestamps
+func GetTimeLocation() *time.Location {
+	return timeLocation
+}
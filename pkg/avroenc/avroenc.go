@@ -0,0 +1,276 @@
+package avroenc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+)
+
+// The Avro schema is derived from the Go types of the output records. Field names are the JSON
+// names, embedded structs are flattened like encoding/json does. Avro resolves fields by name,
+// so unlike protobuf the field order does not matter for compatibility.
+
+// Namespace is the namespace of all named types of the schema
+const Namespace = "gtp2json"
+
+// fieldKind classifies how a Go field is encoded
+type fieldKind int
+
+const (
+	kindBoolean fieldKind = iota
+	kindInt
+	kindLong
+	kindDouble
+	kindString
+	kindBytes
+	kindTimestamp
+	kindRecord
+	kindValue
+)
+
+// field describes a single record field
+type field struct {
+	name     string
+	index    []int
+	kind     fieldKind
+	nullable bool
+	array    bool
+	record   *record
+}
+
+// record describes the Avro record of a Go struct type
+type record struct {
+	name   string
+	goType reflect.Type
+	fields []field
+}
+
+// registry holds the record descriptors, the top level union of records and the typed Value branches
+type registry struct {
+	mu      sync.Mutex
+	records map[reflect.Type]*record
+	names   map[string]reflect.Type
+
+	top       []*record
+	topByType map[reflect.Type]int
+
+	values      []*record
+	valueByType map[reflect.Type]int
+}
+
+var reg = &registry{
+	records: map[reflect.Type]*record{},
+	// The names of the generic Value records are reserved
+	names:       map[string]reflect.Type{"Value": nil, "Struct": nil, "List": nil, "Field": nil},
+	topByType:   map[reflect.Type]int{},
+	valueByType: map[reflect.Type]int{},
+}
+
+func init() {
+	// Typed IE values, the order defines the Value union branches and must only be appended to
+	for _, v := range []interface{}{
+		gtp2ie.AMBR{}, gtp2ie.BearerContext{}, gtp2ie.BearerQoS{}, gtp2ie.Cause{}, gtp2ie.ChargingChars{},
+		gtp2ie.NodeIdentifier{}, gtp2ie.FTEID{}, gtp2ie.Indication{}, gtp2ie.MCCMNC{}, gtp2ie.PAA{},
+		gtp2ie.PCO{}, gtp2ie.PCOOption{}, gtp2ie.IPCPOption{}, gtp2ie.IPCP{}, gtp2ie.PAP{}, gtp2ie.CHAP{},
+		gtp2ie.APNRateControl{}, gtp2ie.PresenceReportingAreaAction{}, gtp2ie.PresenceReportingAreaInformation{},
+		gtp2ie.AdditionalPRA{}, gtp2ie.HomeENodebID{}, gtp2ie.PrivateExtension{}, gtp2ie.TLVOption{},
+		gtp2ie.QoSRule{}, gtp2ie.PacketFilter{}, gtp2ie.QoSFlowDescription{}, gtp2ie.QoSFlowParameter{},
+		gtp2ie.SecondaryRATUsageDataReport{}, gtp2ie.UETimeZone{}, gtp2ie.ULI{}, gtp2ie.CGI{}, gtp2ie.SAI{},
+		gtp2ie.RAI{}, gtp2ie.TAI{}, gtp2ie.ECGI{}, gtp2ie.LAI{}, gtp2ie.MacroENodebID{},
+		gtp2ie.ExtendedMacroENodebID{},
+	} {
+		if err := RegisterValue(v); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterRecord adds a record type as a branch of the top level union under the given name.
+// Branches are numbered in registration order. It is meant to be called from init functions.
+func RegisterRecord(name string, value interface{}) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	t := reflect.TypeOf(value)
+	if _, exists := reg.topByType[t]; exists {
+		return fmt.Errorf("record type %s already registered", t)
+	}
+	r, err := reg.recordFor(t, name)
+	if err != nil {
+		return err
+	}
+	reg.topByType[t] = len(reg.top)
+	reg.top = append(reg.top, r)
+	return nil
+}
+
+// RegisterValue adds a struct type as a typed branch of the Value union used for interface{} fields
+// such as IE values. Values of types not registered are encoded as a generic Struct.
+func RegisterValue(value interface{}) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	t := reflect.TypeOf(value)
+	if _, exists := reg.valueByType[t]; exists {
+		return fmt.Errorf("value type %s already registered", t)
+	}
+	r, err := reg.recordFor(t, "")
+	if err != nil {
+		return err
+	}
+	reg.valueByType[t] = len(reg.values)
+	reg.values = append(reg.values, r)
+	return nil
+}
+
+// recordFor returns the record describing a struct type, building it on first use
+func (g *registry) recordFor(t reflect.Type, name string) (*record, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", t)
+	}
+	if r, ok := g.records[t]; ok {
+		return r, nil
+	}
+
+	if name == "" {
+		name = t.Name()
+		// Types of different packages may share a name, the later one is prefixed with its package
+		if other, taken := g.names[name]; taken && other != t {
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+	}
+	name = avroName(name)
+	if other, taken := g.names[name]; taken && other != t {
+		return nil, fmt.Errorf("record name %s is used by %s and %s", name, other, t)
+	}
+
+	// The record is stored before its fields are built so that recursive types terminate
+	r := &record{name: name, goType: t}
+	g.records[t] = r
+	g.names[name] = t
+
+	if err := g.addFields(r, t, nil, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// addFields appends the exported fields of t to r, flattening embedded structs
+func (g *registry) addFields(r *record, t reflect.Type, index []int, seen map[string]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" || !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && tag == "" {
+			if err := g.addFields(r, sf.Type, fieldIndex, seen); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := avroName(jsonName(sf))
+		// Like encoding/json, the first field of a name wins
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		f := field{name: name, index: fieldIndex}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			f.nullable = true
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 {
+			f.array, f.nullable = true, false
+			ft = ft.Elem()
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+		}
+
+		kind, err := kindOf(ft)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", t.Name(), sf.Name, err)
+		}
+		f.kind = kind
+		if kind == kindRecord {
+			if f.record, err = g.recordFor(ft, ""); err != nil {
+				return err
+			}
+		}
+		if kind == kindValue {
+			// The Value union has a null branch of its own
+			f.nullable = false
+		}
+		r.fields = append(r.fields, f)
+	}
+	return nil
+}
+
+// kindOf maps a Go type to its encoding. Avro has signed types only, unsigned values that
+// do not fit into an int are encoded as long.
+func kindOf(t reflect.Type) (fieldKind, error) {
+	if t == reflect.TypeOf(time.Time{}) {
+		return kindTimestamp, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return kindBoolean, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return kindInt, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return kindLong, nil
+	case reflect.Float32, reflect.Float64:
+		return kindDouble, nil
+	case reflect.String:
+		return kindString, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return kindBytes, nil
+		}
+	case reflect.Struct:
+		return kindRecord, nil
+	case reflect.Interface:
+		return kindValue, nil
+	}
+	return 0, fmt.Errorf("unsupported type %s", t)
+}
+
+// jsonName returns the name of a field in the JSON output
+func jsonName(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("json"); tag != "" {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+// avroName replaces the characters not allowed in Avro names, e.g. F-TEID-IPv4 becomes F_TEID_IPv4
+func avroName(name string) string {
+	var b strings.Builder
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+			b.WriteRune(c)
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,274 @@
+package avroenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+)
+
+type TestEndpoint struct {
+	SrcIP string `json:"srcIP,omitempty"`
+	DSCP  uint8  `json:"dscp"`
+}
+
+type TestIE struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type testRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	TestEndpoint
+	TEID  *uint32  `json:"teid,omitempty"`
+	TEIDs []uint32 `json:"teids,omitempty"`
+	IEs   []TestIE `json:"ies"`
+}
+
+func init() {
+	if err := RegisterRecord("TestRecord", testRecord{}); err != nil {
+		panic(err)
+	}
+}
+
+// reader decodes the Avro binary encoding
+type reader struct {
+	t *testing.T
+	b []byte
+}
+
+func (r *reader) long() int64 {
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.t.Fatalf("invalid long at %x", r.b)
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) string() string {
+	n := r.long()
+	if int64(len(r.b)) < n {
+		r.t.Fatalf("string of %d bytes exceeds the message", n)
+	}
+	s := string(r.b[:n])
+	r.b = r.b[n:]
+	return s
+}
+
+func TestMarshal(t *testing.T) {
+	teid := uint32(0x0a000001)
+	record := testRecord{
+		Timestamp:    time.Unix(1700000000, 5000),
+		TestEndpoint: TestEndpoint{SrcIP: "10.0.0.1"},
+		TEID:         &teid,
+		IEs: []TestIE{
+			{Type: "Cause", Value: gtp2ie.Cause{CauseValue: "Request accepted", CS: 1}},
+			{Type: "Recovery", Value: uint8(5)},
+			{Type: "Unknown", Value: map[string]interface{}{"b": nil, "a": -1}},
+		},
+	}
+
+	data, err := Marshal(42, &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := SchemaID(data); err != nil || id != 42 {
+		t.Fatalf("SchemaID() = %d, %v, want 42", id, err)
+	}
+
+	r := &reader{t: t, b: data[5:]}
+	if branch := r.long(); branch != int64(len(reg.top)-1) {
+		t.Errorf("union branch = %d", branch)
+	}
+	if ts := r.long(); ts != 1700000000000005 {
+		t.Errorf("timestamp = %d", ts)
+	}
+	if ip := r.string(); ip != "10.0.0.1" {
+		t.Errorf("srcIP = %q", ip)
+	}
+	if dscp := r.long(); dscp != 0 {
+		t.Errorf("dscp = %d", dscp)
+	}
+	if branch, v := r.long(), r.long(); branch != 1 || v != int64(teid) {
+		t.Errorf("teid = %d (branch %d)", v, branch)
+	}
+	if n := r.long(); n != 0 {
+		t.Errorf("empty teids array has %d items", n)
+	}
+
+	if n := r.long(); n != 3 {
+		t.Fatalf("ies array has %d items", n)
+	}
+	// A typed Cause: the CauseValue string, PCE, BCE and CS
+	if typ, branch := r.string(), r.long(); typ != "Cause" || branch != int64(firstValueBranch+reg.valueByType[reflect.TypeOf(gtp2ie.Cause{})]) {
+		t.Fatalf("cause IE %q has branch %d", typ, branch)
+	}
+	if branch, v := r.long(), r.string(); branch != valueString || v != "Request accepted" {
+		t.Errorf("cause value = %q (branch %d)", v, branch)
+	}
+	if !bytes.Equal(r.b[:2], []byte{0, 0}) {
+		t.Errorf("PCE and BCE = %x", r.b[:2])
+	}
+	r.b = r.b[2:]
+	if cs := r.long(); cs != 1 {
+		t.Errorf("CS = %d", cs)
+	}
+
+	if typ, branch, v := r.string(), r.long(), r.long(); typ != "Recovery" || branch != valueLong || v != 5 {
+		t.Errorf("recovery IE %q = %d (branch %d)", typ, v, branch)
+	}
+
+	// Maps become a Struct with sorted names
+	if typ, branch := r.string(), r.long(); typ != "Unknown" || branch != valueStruct {
+		t.Fatalf("unknown IE %q has branch %d", typ, branch)
+	}
+	if n := r.long(); n != 2 {
+		t.Fatalf("struct has %d fields", n)
+	}
+	if name, branch, v := r.string(), r.long(), r.long(); name != "a" || branch != valueLong || v != -1 {
+		t.Errorf("first field %q = %d (branch %d)", name, v, branch)
+	}
+	if name, branch := r.string(), r.long(); name != "b" || branch != valueNull {
+		t.Errorf("second field %q has branch %d", name, branch)
+	}
+	if r.long() != 0 || r.long() != 0 {
+		t.Error("arrays are not terminated")
+	}
+	if len(r.b) != 0 {
+		t.Errorf("%d trailing bytes", len(r.b))
+	}
+}
+
+func TestMarshalUnregistered(t *testing.T) {
+	if _, err := Marshal(1, struct{}{}); err == nil {
+		t.Error("Marshal() of an unregistered type did not fail")
+	}
+}
+
+// checkNames verifies that every named type is defined before it is referenced and defined only once
+func checkNames(t *testing.T, schema interface{}, defined map[string]bool) {
+	switch s := schema.(type) {
+	case string:
+		switch s {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return
+		}
+		if !defined[s] {
+			t.Errorf("type %s referenced before its definition", s)
+		}
+	case []interface{}:
+		for _, branch := range s {
+			checkNames(t, branch, defined)
+		}
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record":
+			name := s["name"].(string)
+			if defined[name] {
+				t.Errorf("record %s defined twice", name)
+			}
+			defined[name] = true
+			for _, f := range s["fields"].([]interface{}) {
+				checkNames(t, f.(map[string]interface{})["type"], defined)
+			}
+		case "array":
+			checkNames(t, s["items"], defined)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var schema interface{}
+	if err := json.Unmarshal([]byte(Schema()), &schema); err != nil {
+		t.Fatalf("Schema() is not valid JSON: %v", err)
+	}
+	checkNames(t, schema, map[string]bool{})
+
+	union := schema.([]interface{})
+	record := union[len(union)-1].(map[string]interface{})
+	fields, _ := json.Marshal(record["fields"].([]interface{})[:5])
+	// Object keys are sorted by the round trip through a map
+	want := `[{"name":"timestamp","type":{"logicalType":"timestamp-micros","type":"long"}},` +
+		`{"name":"srcIP","type":"string"},{"name":"dscp","type":"int"},` +
+		`{"default":null,"name":"teid","type":["null","long"]},` +
+		`{"name":"teids","type":{"items":"long","type":"array"}}]`
+	if record["name"] != "TestRecord" || string(fields) != want {
+		t.Errorf("%s fields = %s, want %s", record["name"], fields, want)
+	}
+	if !strings.Contains(Schema(), `{"name":"CauseValue","type":"Value"}`) {
+		t.Error("Cause value is not a Value")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		wantID   uint32
+		wantErr  bool
+	}{
+		{
+			name:     "Registered",
+			status:   http.StatusOK,
+			response: `{"id": 7}`,
+			wantID:   7,
+		},
+		{
+			name:     "Incompatible Schema",
+			status:   http.StatusConflict,
+			response: `{"error_code": 409, "message": "Schema being registered is incompatible with an earlier schema"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "Missing ID",
+			status:   http.StatusOK,
+			response: `{}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Method != http.MethodPost || req.URL.Path != "/subjects/gtp_packets-value/versions" {
+					t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+				}
+				if user, password, ok := req.BasicAuth(); !ok || user != "registry" || password != "secret" {
+					t.Errorf("unexpected credentials %q %q", user, password)
+				}
+				if ct := req.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/vnd.schemaregistry.v1+json") {
+					t.Errorf("Content-Type = %q", ct)
+				}
+				body, _ := io.ReadAll(req.Body)
+				var payload struct {
+					Schema string `json:"schema"`
+				}
+				if err := json.Unmarshal(body, &payload); err != nil || payload.Schema != Schema() {
+					t.Errorf("unexpected request body %s", body)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			registry := NewRegistry(server.URL+"/", "registry", "secret", nil)
+			id, err := registry.Register(Subject("gtp_packets"), Schema())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if id != tt.wantID {
+				t.Errorf("Register() = %d, want %d", id, tt.wantID)
+			}
+		})
+	}
+}
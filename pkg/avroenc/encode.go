@@ -0,0 +1,296 @@
+package avroenc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// magicByte starts every message in the Schema Registry wire format
+const magicByte = 0
+
+// Marshal encodes a registered record in the Schema Registry wire format: the magic byte, the
+// big-endian schema ID and the Avro binary encoding of the record as a branch of the top level union
+func Marshal(schemaID uint32, value interface{}) ([]byte, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	reg.mu.Lock()
+	branch, ok := reg.topByType[v.Type()]
+	reg.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("record type %s is not registered", v.Type())
+	}
+
+	b := make([]byte, 5, 256)
+	b[0] = magicByte
+	binary.BigEndian.PutUint32(b[1:], schemaID)
+	b = appendLong(b, int64(branch))
+	return appendRecord(b, v, reg.top[branch]), nil
+}
+
+// SchemaID returns the schema ID of a message in the Schema Registry wire format
+func SchemaID(data []byte) (uint32, error) {
+	if len(data) < 5 || data[0] != magicByte {
+		return 0, fmt.Errorf("not in the Schema Registry wire format")
+	}
+	return binary.BigEndian.Uint32(data[1:5]), nil
+}
+
+func appendLong(b []byte, v int64) []byte {
+	return binary.AppendVarint(b, v)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendLong(b, int64(len(s)))
+	return append(b, s...)
+}
+
+// appendRecord encodes the fields of a struct value in schema order
+func appendRecord(b []byte, v reflect.Value, r *record) []byte {
+	for i := range r.fields {
+		f := &r.fields[i]
+		fv, ok := fieldByIndex(v, f.index)
+		switch {
+		case f.nullable:
+			if !ok || fv.IsNil() {
+				b = appendLong(b, 0)
+				continue
+			}
+			b = appendLong(b, 1)
+			b = appendField(b, f, fv.Elem())
+		case f.array:
+			if !ok {
+				b = appendLong(b, 0)
+				continue
+			}
+			b = appendArray(b, f, fv)
+		default:
+			b = appendField(b, f, fv)
+		}
+	}
+	return b
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex that tolerates nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// appendArray encodes a slice as a single block followed by the end marker
+func appendArray(b []byte, f *field, v reflect.Value) []byte {
+	if n := v.Len(); n > 0 {
+		b = appendLong(b, int64(n))
+		for i := 0; i < n; i++ {
+			b = appendField(b, f, v.Index(i))
+		}
+	}
+	return appendLong(b, 0)
+}
+
+// appendField encodes a single value of a field, a missing value is encoded as the zero value
+func appendField(b []byte, f *field, v reflect.Value) []byte {
+	if f.kind == kindValue {
+		return appendValue(b, v)
+	}
+	v = indirect(v)
+	if !v.IsValid() {
+		v = reflect.Zero(fieldType(f))
+	}
+
+	switch f.kind {
+	case kindBoolean:
+		if v.Bool() {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case kindInt, kindLong:
+		return appendLong(b, integer(v))
+	case kindDouble:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.Float()))
+	case kindString:
+		return appendString(b, v.String())
+	case kindBytes:
+		b = appendLong(b, int64(v.Len()))
+		return append(b, v.Bytes()...)
+	case kindTimestamp:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return appendLong(b, 0)
+		}
+		return appendLong(b, t.UnixMicro())
+	case kindRecord:
+		return appendRecord(b, v, f.record)
+	}
+	return b
+}
+
+// fieldType returns the Go type used for the zero value of a field
+func fieldType(f *field) reflect.Type {
+	switch f.kind {
+	case kindBoolean:
+		return reflect.TypeOf(false)
+	case kindInt, kindLong:
+		return reflect.TypeOf(int64(0))
+	case kindDouble:
+		return reflect.TypeOf(float64(0))
+	case kindString:
+		return reflect.TypeOf("")
+	case kindBytes:
+		return reflect.TypeOf([]byte(nil))
+	case kindTimestamp:
+		return reflect.TypeOf(time.Time{})
+	}
+	return f.record.goType
+}
+
+// integer returns a signed or unsigned integer as int64, unsigned values above the int64 range wrap
+func integer(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+// indirect dereferences pointers and interfaces, a nil yields an invalid value
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// appendValue encodes a dynamic value as a Value record. Registered struct types use their typed
+// branch, other structs and maps become a Struct of named fields and slices a List.
+func appendValue(b []byte, v reflect.Value) []byte {
+	v = indirect(v)
+	if !v.IsValid() {
+		return appendLong(b, valueNull)
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		b = appendLong(b, valueString)
+		return appendString(b, t.Format(time.RFC3339Nano))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b = appendLong(b, valueBoolean)
+		if v.Bool() {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b = appendLong(b, valueLong)
+		return appendLong(b, integer(v))
+	case reflect.Float32, reflect.Float64:
+		b = appendLong(b, valueDouble)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.Float()))
+	case reflect.String:
+		b = appendLong(b, valueString)
+		return appendString(b, v.String())
+	case reflect.Struct:
+		reg.mu.Lock()
+		index, typed := reg.valueByType[v.Type()]
+		reg.mu.Unlock()
+		if typed {
+			b = appendLong(b, int64(firstValueBranch+index))
+			return appendRecord(b, v, reg.values[index])
+		}
+		b = appendLong(b, valueStruct)
+		return appendStruct(b, v)
+	case reflect.Map:
+		b = appendLong(b, valueStruct)
+		return appendMap(b, v)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			b = appendLong(b, valueBytes)
+			b = appendLong(b, int64(v.Len()))
+			return append(b, v.Bytes()...)
+		}
+		b = appendLong(b, valueList)
+		if n := v.Len(); n > 0 {
+			b = appendLong(b, int64(n))
+			for i := 0; i < n; i++ {
+				b = appendValue(b, v.Index(i))
+			}
+		}
+		return appendLong(b, 0)
+	}
+	return appendLong(b, valueNull)
+}
+
+// structField is a named value of a generic Struct
+type structField struct {
+	name  string
+	value reflect.Value
+}
+
+// appendFields encodes the fields array of a Struct
+func appendFields(b []byte, fields []structField) []byte {
+	if len(fields) > 0 {
+		b = appendLong(b, int64(len(fields)))
+		for _, f := range fields {
+			b = appendString(b, f.name)
+			b = appendValue(b, f.value)
+		}
+	}
+	return appendLong(b, 0)
+}
+
+// appendStruct encodes an unregistered struct with its JSON field names
+func appendStruct(b []byte, v reflect.Value) []byte {
+	return appendFields(b, structFields(nil, v))
+}
+
+func structFields(fields []structField, v reflect.Value) []structField {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Tag.Get("json") == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && sf.Tag.Get("json") == "" {
+			if inner := indirect(fv); inner.IsValid() && inner.Kind() == reflect.Struct {
+				fields = structFields(fields, inner)
+				continue
+			}
+		}
+		if fv.IsZero() {
+			continue
+		}
+		fields = append(fields, structField{name: jsonName(sf), value: fv})
+	}
+	return fields
+}
+
+// appendMap encodes a map with sorted keys so that the output is deterministic
+func appendMap(b []byte, v reflect.Value) []byte {
+	fields := make([]structField, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		fields = append(fields, structField{name: fmt.Sprint(iter.Key().Interface()), value: iter.Value()})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	return appendFields(b, fields)
+}
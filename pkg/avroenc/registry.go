@@ -0,0 +1,94 @@
+package avroenc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// contentType is the media type of the Schema Registry REST API
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// Registry is a client of a Confluent compatible Schema Registry
+type Registry struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewRegistry creates a Schema Registry client. Basic authentication is used when a username is set,
+// tlsConfig may be nil.
+func NewRegistry(registryURL, username, password string, tlsConfig *tls.Config) *Registry {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &Registry{
+		url:      strings.TrimSuffix(registryURL, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Transport: transport, Timeout: 10 * time.Second},
+	}
+}
+
+// Subject returns the subject of the values of a topic under the default TopicNameStrategy
+func Subject(topic string) string {
+	return topic + "-value"
+}
+
+// Register registers a schema under a subject and returns its ID. Registering a schema
+// that already exists returns the ID of the existing version.
+func (r *Registry) Register(subject, schema string) (uint32, error) {
+	body, err := json.Marshal(struct {
+		Schema string `json:"schema"`
+	}{schema})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.url+"/subjects/"+url.PathEscape(subject)+"/versions", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to register schema: %w", err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read Schema Registry response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var registryErr struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		if json.Unmarshal(content, &registryErr) == nil && registryErr.Message != "" {
+			return 0, fmt.Errorf("schema registration rejected: %s (error code %d)", registryErr.Message, registryErr.ErrorCode)
+		}
+		return 0, fmt.Errorf("schema registration failed with status %s", resp.Status)
+	}
+
+	var result struct {
+		ID *uint32 `json:"id"`
+	}
+	if err := json.Unmarshal(content, &result); err != nil || result.ID == nil {
+		return 0, fmt.Errorf("invalid Schema Registry response: %s", content)
+	}
+	return *result.ID, nil
+}
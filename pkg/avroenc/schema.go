@@ -0,0 +1,146 @@
+package avroenc
+
+import (
+	"encoding/json"
+)
+
+// Branches of the Value union that do not depend on registered types
+const (
+	valueNull = iota
+	valueBoolean
+	valueLong
+	valueDouble
+	valueString
+	valueBytes
+	valueStruct
+	valueList
+	firstValueBranch
+)
+
+// schemaRecord, schemaField and schemaArray are the JSON forms of the Avro complex types
+type schemaRecord struct {
+	Type      string        `json:"type"`
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace"`
+	Doc       string        `json:"doc,omitempty"`
+	Fields    []schemaField `json:"fields"`
+}
+
+type schemaField struct {
+	Name    string          `json:"name"`
+	Type    interface{}     `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+type schemaArray struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+type schemaLogical struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+}
+
+var primitiveTypes = map[fieldKind]interface{}{
+	kindBoolean:   "boolean",
+	kindInt:       "int",
+	kindLong:      "long",
+	kindDouble:    "double",
+	kindString:    "string",
+	kindBytes:     "bytes",
+	kindTimestamp: schemaLogical{Type: "long", LogicalType: "timestamp-micros"},
+	kindValue:     "Value",
+}
+
+// schemaBuilder defines every named type at its first use and refers to it by name afterwards
+type schemaBuilder struct {
+	defined map[string]bool
+}
+
+// Schema returns the Avro schema of the registered records, a union with one branch per record type
+func Schema() string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	s := &schemaBuilder{defined: map[string]bool{}}
+	union := make([]interface{}, 0, len(reg.top))
+	for _, r := range reg.top {
+		union = append(union, s.record(r))
+	}
+	data, err := json.Marshal(union)
+	if err != nil {
+		// The schema consists of strings and slices only
+		panic(err)
+	}
+	return string(data)
+}
+
+// record returns the definition of a record or its name when it has been defined already
+func (s *schemaBuilder) record(r *record) interface{} {
+	if s.defined[r.name] {
+		return r.name
+	}
+	s.defined[r.name] = true
+
+	def := schemaRecord{Type: "record", Name: r.name, Namespace: Namespace, Fields: []schemaField{}}
+	for _, f := range r.fields {
+		def.Fields = append(def.Fields, s.field(f))
+	}
+	return def
+}
+
+func (s *schemaBuilder) field(f field) schemaField {
+	var t interface{}
+	switch f.kind {
+	case kindRecord:
+		t = s.record(f.record)
+	case kindValue:
+		t = s.value()
+	default:
+		t = primitiveTypes[f.kind]
+	}
+
+	sf := schemaField{Name: f.name}
+	switch {
+	case f.array:
+		sf.Type = schemaArray{Type: "array", Items: t}
+	case f.nullable:
+		sf.Type = []interface{}{"null", t}
+		sf.Default = json.RawMessage("null")
+	default:
+		sf.Type = t
+	}
+	return sf
+}
+
+// value returns the definition of the Value record wrapping the union of dynamically typed values
+func (s *schemaBuilder) value() interface{} {
+	if s.defined["Value"] {
+		return "Value"
+	}
+	s.defined["Value"] = true
+	s.defined["Struct"] = true
+	s.defined["List"] = true
+	s.defined["Field"] = true
+
+	field := schemaRecord{Type: "record", Name: "Field", Namespace: Namespace, Fields: []schemaField{
+		{Name: "name", Type: "string"},
+		{Name: "value", Type: "Value"},
+	}}
+	union := []interface{}{
+		"null", "boolean", "long", "double", "string", "bytes",
+		schemaRecord{Type: "record", Name: "Struct", Namespace: Namespace,
+			Doc:    "A value of a type without a dedicated record as named fields",
+			Fields: []schemaField{{Name: "fields", Type: schemaArray{Type: "array", Items: field}}}},
+		schemaRecord{Type: "record", Name: "List", Namespace: Namespace,
+			Fields: []schemaField{{Name: "values", Type: schemaArray{Type: "array", Items: "Value"}}}},
+	}
+	for _, r := range reg.values {
+		union = append(union, s.record(r))
+	}
+
+	return schemaRecord{Type: "record", Name: "Value", Namespace: Namespace,
+		Doc:    "The decoded value of an IE or any other dynamically typed field",
+		Fields: []schemaField{{Name: "value", Type: union}}}
+}
@@ -0,0 +1,158 @@
+package batch
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/vagabundor/kafkabuff"
+)
+
+// pollInterval is the interval at which the buffer is checked for a full batch
+const pollInterval = 50 * time.Millisecond
+
+// Sender takes batches of messages from a ring buffer and passes them to a send function, the way
+// the Kafka batch sender does: a batch is sent when it is full or when the interval has passed.
+// Sinks other than Kafka are fed from the same buffer, so buffer occupancy and overwriting of the
+// oldest messages behave alike for all of them.
+type Sender struct {
+	buffer   *kafkabuff.RingBuffer
+	size     int
+	interval time.Duration
+	send     func([]*sarama.ProducerMessage) error
+	onError  func(error)
+
+	// workers limits the batches sent at the same time
+	workers chan struct{}
+	sending sync.WaitGroup
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewSender returns a sender of batches of up to size messages. Errors returned by send are passed
+// to onError, the batch is not sent again.
+func NewSender(buffer *kafkabuff.RingBuffer, size int, interval time.Duration, send func([]*sarama.ProducerMessage) error, onError func(error)) *Sender {
+	return NewConcurrentSender(buffer, size, interval, 1, send, onError)
+}
+
+// NewConcurrentSender returns a sender that sends up to workers batches at the same time. While
+// all workers are busy, messages stay in the buffer.
+func NewConcurrentSender(buffer *kafkabuff.RingBuffer, size int, interval time.Duration, workers int, send func([]*sarama.ProducerMessage) error, onError func(error)) *Sender {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Sender{
+		buffer:   buffer,
+		size:     size,
+		interval: interval,
+		send:     send,
+		onError:  onError,
+		workers:  make(chan struct{}, workers),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start starts sending in a separate goroutine
+func (s *Sender) Start() {
+	go s.run()
+}
+
+func (s *Sender) run() {
+	defer close(s.done)
+	defer s.sending.Wait()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			// The remaining messages are sent before stopping
+			for s.buffer.Size() > 0 {
+				s.sendBatch()
+			}
+			return
+		case <-ticker.C:
+			if s.buffer.Size() > 0 {
+				s.sendBatch()
+			}
+		case <-poll.C:
+			for s.buffer.Size() >= s.size {
+				s.sendBatch()
+			}
+		}
+	}
+}
+
+// sendBatch waits for a free worker and sends the next batch with it
+func (s *Sender) sendBatch() {
+	s.workers <- struct{}{}
+	batch := s.buffer.GetBatch(s.size)
+	if len(batch) == 0 {
+		<-s.workers
+		return
+	}
+	s.sending.Add(1)
+	go func() {
+		defer s.sending.Done()
+		defer func() { <-s.workers }()
+		if err := s.send(batch); err != nil && s.onError != nil {
+			s.onError(err)
+		}
+	}()
+}
+
+// Stop sends the messages left in the buffer and waits until the sender has finished
+func (s *Sender) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Backoff is the waiting time between retries. It starts at Initial and doubles up to Max.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// permanentError marks an error that is not resolved by retrying
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an error that stops Retry, such as a rejected request
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Retry calls fn until it succeeds, returns a permanent error or maxRetries attempts have failed.
+// maxRetries 0 retries forever. The last error is returned.
+func Retry(maxRetries int, backoff Backoff, fn func() error) error {
+	return retry(maxRetries, backoff, fn, time.Sleep)
+}
+
+func retry(maxRetries int, backoff Backoff, fn func() error, sleep func(time.Duration)) error {
+	wait := backoff.Initial
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		var permanent permanentError
+		if err == nil || errors.As(err, &permanent) {
+			return err
+		}
+		if maxRetries > 0 && attempt >= maxRetries {
+			return err
+		}
+		sleep(wait)
+		wait *= 2
+		if backoff.Max > 0 && wait > backoff.Max {
+			wait = backoff.Max
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/vagabundor/kafkabuff"
+)
+
+func TestSender(t *testing.T) {
+	tests := []struct {
+		name      string
+		messages  int
+		size      int
+		interval  time.Duration
+		wantSizes []int
+	}{
+		{
+			name:      "Full Batches",
+			messages:  7,
+			size:      3,
+			interval:  time.Hour,
+			wantSizes: []int{3, 3, 1},
+		},
+		{
+			name:      "Interval",
+			messages:  2,
+			size:      100,
+			interval:  10 * time.Millisecond,
+			wantSizes: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := kafkabuff.NewRingBuffer(100)
+			var mu sync.Mutex
+			var sizes []int
+			sent := make(chan struct{}, 10)
+			s := NewSender(buffer, tt.size, tt.interval, func(batch []*sarama.ProducerMessage) error {
+				mu.Lock()
+				sizes = append(sizes, len(batch))
+				mu.Unlock()
+				sent <- struct{}{}
+				return errors.New("failed")
+			}, func(err error) {
+				if err.Error() != "failed" {
+					t.Errorf("unexpected error %v", err)
+				}
+			})
+			for i := 0; i < tt.messages; i++ {
+				buffer.Add(&sarama.ProducerMessage{Value: sarama.StringEncoder(fmt.Sprint(i))})
+			}
+			s.Start()
+			if tt.interval < time.Second {
+				// The interval sends the incomplete batch without stopping
+				select {
+				case <-sent:
+				case <-time.After(5 * time.Second):
+					t.Fatal("batch not sent after the interval")
+				}
+			}
+			s.Stop()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(sizes, tt.wantSizes) {
+				t.Errorf("batch sizes = %v, want %v", sizes, tt.wantSizes)
+			}
+			if buffer.Size() != 0 {
+				t.Errorf("%d messages left in the buffer", buffer.Size())
+			}
+		})
+	}
+}
+
+func TestConcurrentSender(t *testing.T) {
+	buffer := kafkabuff.NewRingBuffer(100)
+	for i := 0; i < 10; i++ {
+		buffer.Add(&sarama.ProducerMessage{Value: sarama.StringEncoder(fmt.Sprint(i))})
+	}
+
+	var mu sync.Mutex
+	active, maxActive, sent := 0, 0, 0
+	release := make(chan struct{})
+	s := NewConcurrentSender(buffer, 2, time.Hour, 3, func(batch []*sarama.ProducerMessage) error {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		active--
+		sent += len(batch)
+		mu.Unlock()
+		return nil
+	}, nil)
+	s.Start()
+
+	// Three batches are sent at the same time, the others wait in the buffer
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := active
+		mu.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d batches sent concurrently, want 3", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if buffer.Size() != 4 {
+		t.Errorf("%d messages in the buffer while the workers are busy, want 4", buffer.Size())
+	}
+	close(release)
+	s.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxActive != 3 || sent != 10 {
+		t.Errorf("max concurrent batches = %d, sent = %d, want 3 and 10", maxActive, sent)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errRejected := errors.New("rejected")
+	tests := []struct {
+		name       string
+		maxRetries int
+		errs       []error
+		wantCalls  int
+		wantErr    error
+		wantWaits  []time.Duration
+	}{
+		{
+			name:       "Success After Retries",
+			maxRetries: 5,
+			errs:       []error{errTemporary, errTemporary, errTemporary, errTemporary, nil},
+			wantCalls:  5,
+			wantWaits:  []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second},
+		},
+		{
+			name:       "Retries Exhausted",
+			maxRetries: 2,
+			errs:       []error{errTemporary, errTemporary, nil},
+			wantCalls:  2,
+			wantErr:    errTemporary,
+			wantWaits:  []time.Duration{time.Second},
+		},
+		{
+			name:       "Permanent Error",
+			maxRetries: 0,
+			errs:       []error{errTemporary, Permanent(errRejected), nil},
+			wantCalls:  2,
+			wantErr:    errRejected,
+			wantWaits:  []time.Duration{time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var waits []time.Duration
+			err := retry(tt.maxRetries, Backoff{Initial: time.Second, Max: 5 * time.Second}, func() error {
+				calls++
+				return tt.errs[calls-1]
+			}, func(d time.Duration) { waits = append(waits, d) })
+
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Retry() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(waits, tt.wantWaits) {
+				t.Errorf("waits = %v, want %v", waits, tt.wantWaits)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package clickhouse
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/batch"
+	"github.com/vagabundor/gtp2json/pkg/parquet"
+)
+
+// Config configures the connection to the HTTP interface of ClickHouse
+type Config struct {
+	URL      string
+	Database string
+	Username string
+	Password string
+	TLS      *tls.Config
+	Timeout  time.Duration
+}
+
+// Client inserts rows through the HTTP interface of ClickHouse
+type Client struct {
+	config Config
+	client *http.Client
+}
+
+// NewClient returns a client for the given server
+func NewClient(config Config) (*Client, error) {
+	if _, err := url.ParseRequestURI(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid ClickHouse URL %q: %w", config.URL, err)
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/") + "/"
+	if config.Database == "" {
+		config.Database = "default"
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config.TLS
+	return &Client{config: config, client: &http.Client{Transport: transport, Timeout: config.Timeout}}, nil
+}
+
+// statusError is the error of a query answered with an unexpected status
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.status, e.body)
+}
+
+// do sends a query with the given data. Errors that are not resolved by retrying, such as
+// rejected credentials or an invalid query, are marked permanent.
+func (c *Client) do(query string, settings url.Values, data []byte) error {
+	params := url.Values{"query": {query}, "database": {c.config.Database}}
+	for name, values := range settings {
+		params[name] = values
+	}
+	req, err := http.NewRequest(http.MethodPost, c.config.URL+"?"+params.Encode(), bytes.NewReader(data))
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	if c.config.Username != "" {
+		req.Header.Set("X-ClickHouse-User", c.config.Username)
+		req.Header.Set("X-ClickHouse-Key", c.config.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		err := &statusError{status: resp.StatusCode, body: strings.TrimSpace(string(body))}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return err
+		}
+		return batch.Permanent(err)
+	}
+	return nil
+}
+
+// CreateTable creates the table of the schema unless it exists
+func (c *Client) CreateTable(table string, schema []parquet.Node) error {
+	query, err := CreateTableQuery(c.config.Database, table, schema)
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	return c.do(query, nil, nil)
+}
+
+// Insert inserts JSONEachRow lines into a table, retrying the whole batch with back-off while
+// the server is unavailable. Columns missing from an existing table are skipped.
+func (c *Client) Insert(table string, rows [][]byte, maxRetries int, backoff batch.Backoff) error {
+	var body bytes.Buffer
+	for _, row := range rows {
+		body.Write(row)
+		body.WriteByte('\n')
+	}
+	query := fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", quote(table))
+	settings := url.Values{"input_format_skip_unknown_fields": {"1"}}
+	err := batch.Retry(maxRetries, backoff, func() error {
+		return c.do(query, settings, body.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("insert into %s failed: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/batch"
+	"github.com/vagabundor/gtp2json/pkg/parquet"
+)
+
+func TestCreateTableQuery(t *testing.T) {
+	schema := []parquet.Node{
+		{Name: "timestamp", Type: parquet.Int64, Repetition: parquet.Required, Annotation: parquet.TimestampMicros},
+		{Name: "protocol", Type: parquet.ByteArray, Repetition: parquet.Required, Annotation: parquet.String},
+		{Name: "teid", Type: parquet.Int64, Repetition: parquet.Optional},
+		{Name: "teids", Type: parquet.ByteArray, Repetition: parquet.Repeated, Annotation: parquet.String},
+		{Name: "ies", Repetition: parquet.Repeated, Fields: []parquet.Node{
+			{Name: "type", Type: parquet.ByteArray, Repetition: parquet.Required, Annotation: parquet.String},
+			{Name: "value", Type: parquet.ByteArray, Repetition: parquet.Optional, Annotation: parquet.JSON},
+		}},
+	}
+	want := "CREATE TABLE IF NOT EXISTS `gtp`.`gtp_packets` (\n" +
+		"    `timestamp` DateTime64(6, 'UTC'),\n" +
+		"    `protocol` String,\n" +
+		"    `teid` Nullable(Int64),\n" +
+		"    `teids` Array(String),\n" +
+		"    `ies` Nested(`type` String, `value` Nullable(String))\n" +
+		") ENGINE = MergeTree\nPARTITION BY toYYYYMMDD(timestamp)\nORDER BY (protocol, timestamp)"
+
+	got, err := CreateTableQuery("gtp", "gtp_packets", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("CreateTableQuery() =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := CreateTableQuery("gtp", "gtp_packets", Schema); err != nil {
+		t.Errorf("CreateTableQuery(Schema) error = %v", err)
+	}
+	nested := []parquet.Node{{Name: "g", Repetition: parquet.Repeated, Fields: []parquet.Node{
+		{Name: "l", Type: parquet.ByteArray, Repetition: parquet.Repeated},
+	}}}
+	if _, err := CreateTableQuery("gtp", "gtp_packets", nested); err == nil {
+		t.Error("CreateTableQuery() with a repeated field in a group did not fail")
+	}
+}
+
+func TestRow(t *testing.T) {
+	record := `{"timestamp":"2024-05-01T12:00:00.123456+02:00","srcIP":"10.0.0.1","srcPort":2123,
+		"messageType":32,"teid":0,"ies":[
+		{"type":"IMSI","value":"001010123456789"},
+		{"type":"F-TEID","value":{"InterfaceType":10,"TEID/GRE Key":"0x1"}},
+		{"type":"Recovery","value":null}]}`
+
+	data, err := Row("GTPv2", []byte(record))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal(data, &row); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		column string
+		want   interface{}
+	}{
+		{"timestamp", "2024-05-01 10:00:00.123456"},
+		{"protocol", "GTPv2"},
+		{"message_type", 32.0},
+		{"src_ip", "10.0.0.1"},
+		{"dst_ip", nil},
+		{"teid", 0.0},
+		{"imsi", "001010123456789"},
+		{"teids", []interface{}{"0x1"}},
+		{"ies.type", []interface{}{"IMSI", "F-TEID", "Recovery"}},
+		{"ies.value", []interface{}{`"001010123456789"`, `{"InterfaceType":10,"TEID/GRE Key":"0x1"}`, nil}},
+	}
+	for _, tt := range tests {
+		if got := row[tt.column]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.column, got, tt.want)
+		}
+	}
+	if len(row) != len(Schema)+1 {
+		t.Errorf("row has %d columns, want %d", len(row), len(Schema)+1)
+	}
+
+	if _, err := Row("GTPv2", []byte(`{"ies":`)); err == nil {
+		t.Error("Row() with an invalid record did not fail")
+	}
+}
+
+func TestInsert(t *testing.T) {
+	rows := [][]byte{[]byte(`{"protocol":"GTPv2"}`), []byte(`{"protocol":"PFCP"}`)}
+	tests := []struct {
+		name       string
+		statuses   []int
+		config     Config
+		maxRetries int
+		wantErr    bool
+		wantCalls  int
+	}{
+		{
+			name:      "Inserted",
+			statuses:  []int{http.StatusOK},
+			config:    Config{Username: "gtp2json", Password: "secret"},
+			wantCalls: 1,
+		},
+		{
+			name:      "Retried",
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK},
+			config:    Config{Database: "gtp"},
+			wantCalls: 3,
+		},
+		{
+			name:       "Retries Exhausted",
+			statuses:   []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			maxRetries: 2,
+			wantErr:    true,
+			wantCalls:  2,
+		},
+		{
+			name:      "Rejected",
+			statuses:  []int{http.StatusBadRequest, http.StatusOK},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				query := req.URL.Query()
+				if got := query.Get("query"); got != "INSERT INTO `gtp_packets` FORMAT JSONEachRow" {
+					t.Errorf("query = %q", got)
+				}
+				wantDatabase := tt.config.Database
+				if wantDatabase == "" {
+					wantDatabase = "default"
+				}
+				if got := query.Get("database"); got != wantDatabase {
+					t.Errorf("database = %q, want %q", got, wantDatabase)
+				}
+				if user, key := req.Header.Get("X-ClickHouse-User"), req.Header.Get("X-ClickHouse-Key"); user != tt.config.Username || key != tt.config.Password {
+					t.Errorf("unexpected credentials %q %q", user, key)
+				}
+				body, _ := io.ReadAll(req.Body)
+				if string(body) != "{\"protocol\":\"GTPv2\"}\n{\"protocol\":\"PFCP\"}\n" {
+					t.Errorf("unexpected body %q", body)
+				}
+				w.WriteHeader(tt.statuses[calls-1])
+				if tt.statuses[calls-1] != http.StatusOK {
+					io.WriteString(w, "Code: 241. DB::Exception: Memory limit exceeded")
+				}
+			}))
+			defer server.Close()
+
+			config := tt.config
+			config.URL = server.URL
+			client, err := NewClient(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = client.Insert("gtp_packets", rows, tt.maxRetries, batch.Backoff{Initial: time.Millisecond})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Insert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("%d requests, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query().Get("query")
+		if !strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS `default`.`gtp_errors` (") || !strings.Contains(query, "`imsi` Nullable(String)") {
+			t.Errorf("unexpected query %s", query)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateTable("gtp_errors", Schema); err != nil {
+		t.Errorf("CreateTable() error = %v", err)
+	}
+}
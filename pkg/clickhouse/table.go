@@ -0,0 +1,141 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/parquet"
+)
+
+// Schema is the table written for GTP records. It is the flattened record model of the Parquet
+// files, so that the most used IEs are columns and the full IE list is kept in the ies column.
+var Schema = parquet.RecordSchema
+
+// timeLayout is the DateTime64 text format ClickHouse parses without best effort parsing
+const timeLayout = "2006-01-02 15:04:05.000000"
+
+// CreateTableQuery returns the statement creating a MergeTree table for the schema. Tables are
+// partitioned by day and ordered by protocol and time.
+func CreateTableQuery(database, table string, schema []parquet.Node) (string, error) {
+	var columns []string
+	for i := range schema {
+		typ, err := columnType(&schema[i], false)
+		if err != nil {
+			return "", err
+		}
+		columns = append(columns, fmt.Sprintf("    %s %s", quote(schema[i].Name), typ))
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s (\n%s\n) ENGINE = MergeTree\nPARTITION BY toYYYYMMDD(timestamp)\nORDER BY (protocol, timestamp)",
+		quote(database), quote(table), strings.Join(columns, ",\n")), nil
+}
+
+// columnType maps a schema node to a ClickHouse type. Optional fields are Nullable, repeated
+// fields are arrays and repeated groups are Nested columns.
+func columnType(n *parquet.Node, nested bool) (string, error) {
+	if n.Fields != nil {
+		if n.Repetition != parquet.Repeated || nested {
+			return "", fmt.Errorf("column %s: only top-level repeated groups are supported", n.Name)
+		}
+		var fields []string
+		for i := range n.Fields {
+			typ, err := columnType(&n.Fields[i], true)
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, quote(n.Fields[i].Name)+" "+typ)
+		}
+		return "Nested(" + strings.Join(fields, ", ") + ")", nil
+	}
+
+	var typ string
+	switch {
+	case n.Type == parquet.Int64 && n.Annotation == parquet.TimestampMicros:
+		typ = "DateTime64(6, 'UTC')"
+	case n.Type == parquet.Boolean:
+		typ = "Bool"
+	case n.Type == parquet.Int32:
+		typ = "Int32"
+	case n.Type == parquet.Int64:
+		typ = "Int64"
+	case n.Type == parquet.Double:
+		typ = "Float64"
+	case n.Type == parquet.ByteArray:
+		typ = "String"
+	default:
+		return "", fmt.Errorf("column %s: unsupported type %d", n.Name, n.Type)
+	}
+	switch n.Repetition {
+	case parquet.Optional:
+		typ = "Nullable(" + typ + ")"
+	case parquet.Repeated:
+		if nested {
+			return "", fmt.Errorf("column %s: repeated fields in a Nested column are not supported", n.Name)
+		}
+		typ = "Array(" + typ + ")"
+	}
+	return typ, nil
+}
+
+// quote quotes an identifier
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+// Row converts a JSON record of the given protocol to a JSONEachRow line of the table
+func Row(protocol string, record []byte) ([]byte, error) {
+	_, values, err := parquet.RecordRow(protocol, record)
+	if err != nil {
+		return nil, err
+	}
+	return encodeRow(Schema, values)
+}
+
+// encodeRow encodes the values of a row as a JSON object keyed by column. The fields of Nested
+// columns are written as parallel arrays named <column>.<field>.
+func encodeRow(schema []parquet.Node, values []interface{}) ([]byte, error) {
+	if len(values) != len(schema) {
+		return nil, fmt.Errorf("row has %d values for %d columns", len(values), len(schema))
+	}
+	row := make(map[string]interface{}, len(schema))
+	for i := range schema {
+		n := &schema[i]
+		switch {
+		case n.Fields != nil:
+			groups, _ := values[i].([]interface{})
+			for j, field := range n.Fields {
+				column := make([]interface{}, 0, len(groups))
+				for _, group := range groups {
+					fields, ok := group.([]interface{})
+					if !ok || len(fields) != len(n.Fields) {
+						return nil, fmt.Errorf("column %s: invalid group %v", n.Name, group)
+					}
+					column = append(column, jsonValue(fields[j]))
+				}
+				row[n.Name+"."+field.Name] = column
+			}
+		case n.Repetition == parquet.Repeated:
+			list, _ := values[i].([]interface{})
+			column := make([]interface{}, 0, len(list))
+			for _, v := range list {
+				column = append(column, jsonValue(v))
+			}
+			row[n.Name] = column
+		default:
+			row[n.Name] = jsonValue(values[i])
+		}
+	}
+	return json.Marshal(row)
+}
+
+// jsonValue converts a value of a row to its JSONEachRow form
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(timeLayout)
+	case []byte:
+		return string(v)
+	}
+	return v
+}
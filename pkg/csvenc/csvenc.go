@@ -0,0 +1,324 @@
+package csvenc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Multiple selects how a column with several values is written
+type Multiple int
+
+const (
+	// Join writes all values separated by the join separator
+	Join Multiple = iota
+	// First writes the first value only
+	First
+)
+
+// ParseMultiple returns the mode of a name (join, first)
+func ParseMultiple(name string) (Multiple, error) {
+	switch name {
+	case "join":
+		return Join, nil
+	case "first":
+		return First, nil
+	}
+	return 0, fmt.Errorf("unknown multiple value mode %q, use 'join' or 'first'", name)
+}
+
+// segment is a step of a column expression: a field or IE name with an optional filter
+type segment struct {
+	name        string
+	filterField string
+	filterValue string
+}
+
+// column is a parsed column expression such as F-TEID[interface=10].IPv4
+type column struct {
+	expr     string
+	segments []segment
+}
+
+// Encoder converts JSON records to CSV or TSV rows
+type Encoder struct {
+	columns   []column
+	comma     rune
+	multiple  Multiple
+	separator string
+}
+
+// NewEncoder returns an encoder for a comma separated list of column expressions.
+// comma is the field delimiter, ',' for CSV and '\t' for TSV.
+func NewEncoder(columns string, comma rune, multiple Multiple, separator string) (*Encoder, error) {
+	e := &Encoder{comma: comma, multiple: multiple, separator: separator}
+	for _, expr := range split(columns, ',') {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		c, err := parseColumn(expr)
+		if err != nil {
+			return nil, err
+		}
+		e.columns = append(e.columns, c)
+	}
+	if len(e.columns) == 0 {
+		return nil, fmt.Errorf("no columns configured")
+	}
+	return e, nil
+}
+
+// split splits s at sep outside of brackets, so that filter values may contain separators
+func split(s string, sep rune) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func parseColumn(expr string) (column, error) {
+	c := column{expr: expr}
+	for _, part := range split(expr, '.') {
+		var seg segment
+		name, filter, hasFilter := strings.Cut(part, "[")
+		seg.name = strings.TrimSpace(name)
+		if hasFilter {
+			filter, ok := strings.CutSuffix(filter, "]")
+			field, value, hasValue := strings.Cut(filter, "=")
+			if !ok || !hasValue || strings.TrimSpace(field) == "" {
+				return column{}, fmt.Errorf("column %q: filter must be [field=value]", expr)
+			}
+			seg.filterField, seg.filterValue = strings.TrimSpace(field), strings.TrimSpace(value)
+		}
+		if seg.name == "" {
+			return column{}, fmt.Errorf("column %q: empty name", expr)
+		}
+		c.segments = append(c.segments, seg)
+	}
+	return c, nil
+}
+
+// Header returns the header row holding the column expressions
+func (e *Encoder) Header() []byte {
+	names := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		names[i] = c.expr
+	}
+	return e.row(names)
+}
+
+// Encode converts a JSON record to a row terminated by a newline
+func (e *Encoder) Encode(record []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(record))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to parse record: %w", err)
+	}
+
+	fields := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		var texts []string
+		for _, value := range c.evaluate(v) {
+			if t, ok := text(value); ok {
+				texts = append(texts, t)
+			}
+		}
+		if e.multiple == First && len(texts) > 1 {
+			texts = texts[:1]
+		}
+		fields[i] = strings.Join(texts, e.separator)
+	}
+	return e.row(fields), nil
+}
+
+func (e *Encoder) row(fields []string) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = e.comma
+	w.Write(fields)
+	w.Flush()
+	return buf.Bytes()
+}
+
+// evaluate returns the values a column selects from a record. The first name is a field of the
+// record or, if the record has no such field, an IE type. The following names select fields of
+// the values. Lists are searched element by element, so a column may select several values.
+func (c column) evaluate(record interface{}) []interface{} {
+	first := c.segments[0]
+	var values []interface{}
+	if obj, ok := record.(map[string]interface{}); ok {
+		if key, ok := exactKey(obj, first.name); ok {
+			values = flatten(nil, obj[key])
+		} else {
+			ies, _ := obj["ies"].([]interface{})
+			values = ieValues(ies, first.name)
+		}
+	}
+	values = filter(values, first)
+
+	for _, seg := range c.segments[1:] {
+		var next []interface{}
+		for _, v := range values {
+			next = append(next, lookup(v, seg.name)...)
+		}
+		values = filter(next, seg)
+	}
+	return values
+}
+
+// lookup returns the field of an object or the values of the IEs of a type in a grouped IE
+func lookup(v interface{}, name string) []interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if key, ok := findKey(v, name); ok {
+			return flatten(nil, v[key])
+		}
+		if isIE(v) {
+			// A single IE of a grouped IE
+			return ieValues([]interface{}{v}, name)
+		}
+	case []interface{}:
+		var out []interface{}
+		for _, elem := range v {
+			out = append(out, lookup(elem, name)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// ieValues returns the values of the IEs of a type, grouped IEs hold a list of IEs as value
+func ieValues(ies []interface{}, typ string) []interface{} {
+	var out []interface{}
+	for _, elem := range ies {
+		ie, ok := elem.(map[string]interface{})
+		if !ok || !isIE(ie) {
+			continue
+		}
+		if t, _ := ie["type"].(string); normalize(t) == normalize(typ) {
+			out = flatten(out, ie["value"])
+		}
+	}
+	return out
+}
+
+func isIE(v map[string]interface{}) bool {
+	_, hasType := v["type"].(string)
+	_, hasValue := v["value"]
+	return hasType && hasValue
+}
+
+// flatten appends a value, lists are appended element by element
+func flatten(out []interface{}, v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		for _, elem := range list {
+			out = flatten(out, elem)
+		}
+		return out
+	}
+	return append(out, v)
+}
+
+// filter keeps the values whose filter field matches the filter value of a segment
+func filter(values []interface{}, seg segment) []interface{} {
+	if seg.filterField == "" {
+		return values
+	}
+	var out []interface{}
+	for _, v := range values {
+		for _, field := range lookup(v, seg.filterField) {
+			if t, ok := text(field); ok && matches(t, seg.filterValue) {
+				out = append(out, v)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// matches compares a value with a filter. In the mixed format values look like
+// "S11 MME GTP-C (10)", both the name and the number match them.
+func matches(value, want string) bool {
+	return strings.EqualFold(value, want) ||
+		strings.HasSuffix(value, " ("+want+")") ||
+		len(value) > len(want) && strings.EqualFold(value[:len(want)], want) && strings.HasPrefix(value[len(want):], " (")
+}
+
+// exactKey returns the key of an object equal to a name, ignoring case and punctuation
+func exactKey(obj map[string]interface{}, name string) (string, bool) {
+	n := normalize(name)
+	for key := range obj {
+		if normalize(key) == n {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// findKey returns the key of an object matching a name. Besides the exact key, a name matches
+// the only key starting or ending with it, so that Value finds CauseValue, IPv4 finds F-TEID IPv4
+// and interface finds InterfaceType.
+func findKey(obj map[string]interface{}, name string) (string, bool) {
+	if key, ok := exactKey(obj, name); ok {
+		return key, true
+	}
+	n := normalize(name)
+	for _, match := range []func(s, affix string) bool{strings.HasPrefix, strings.HasSuffix} {
+		var found []string
+		for key := range obj {
+			if match(normalize(key), n) {
+				found = append(found, key)
+			}
+		}
+		if len(found) == 1 {
+			return found[0], true
+		}
+	}
+	return "", false
+}
+
+// normalize lower-cases a name and removes everything but letters and digits
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// text returns a value as cell text, structures are written as JSON. Nulls have no text.
+func text(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return fmt.Sprint(v), true
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
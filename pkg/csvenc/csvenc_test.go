@@ -0,0 +1,102 @@
+package csvenc
+
+import (
+	"testing"
+)
+
+const testRecord = `{"timestamp":"2024-05-01T10:00:00Z","srcIP":"10.0.0.1","messageType":32,"teid":0,"ies":[
+	{"type":"IMSI","value":"001010123456789"},
+	{"type":"ULI","value":{"TAI":{"MCC":"001","MNC":"01","TAC":"0x0001"},"ECGI":{"MCC":"001","MNC":"01","ECI":"0x0000101"}}},
+	{"type":"F-TEID","value":{"InterfaceType":10,"TEID/GRE Key":"0x00000001","F-TEID IPv4":"10.0.0.1"}},
+	{"type":"F-TEID","value":{"InterfaceType":"S5/S8 SGW GTP-C (6)","TEID/GRE Key":"0x00000002","F-TEID IPv4":"10.0.0.2"}},
+	{"type":"Cause","value":{"CauseValue":16,"PCE":0,"BCE":0,"CS":0}},
+	{"type":"Recovery","value":null},
+	{"type":"BearerContext","value":{"EBI":5,"FTEIDs":[
+		{"InterfaceType":0,"TEID/GRE Key":"0x00000003","F-TEID IPv4":"10.0.0.3"},
+		{"InterfaceType":1,"TEID/GRE Key":"0x00000004","F-TEID IPv4":"10.0.0.4"}]}},
+	{"type":"CreatePDR","value":[{"type":"PDR ID","value":1},{"type":"PDI","value":[{"type":"Source Interface","value":"Access"}]}]}]}`
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name     string
+		columns  string
+		comma    rune
+		multiple Multiple
+		want     string
+	}{
+		{
+			name:    "Record Fields and IEs",
+			columns: "timestamp, srcIP, messageType, TEID, IMSI, MSISDN",
+			comma:   ',',
+			want:    "2024-05-01T10:00:00Z,10.0.0.1,32,0,001010123456789,\n",
+		},
+		{
+			name:    "Nested Fields",
+			columns: "ULI.ECGI.ECI,Cause.Value,F-TEID.TEID",
+			comma:   ',',
+			want:    "0x0000101,16,0x00000001|0x00000002\n",
+		},
+		{
+			name:    "Filters",
+			columns: "F-TEID[interface=10].IPv4,F-TEID[interface=6].IPv4,F-TEID[interface=S5/S8 SGW GTP-C].IPv4,F-TEID[interface=7].IPv4",
+			comma:   ',',
+			want:    "10.0.0.1,10.0.0.2,10.0.0.2,\n",
+		},
+		{
+			name:     "First Value",
+			columns:  "F-TEID.IPv4,BearerContext.FTEIDs.IPv4",
+			comma:    '\t',
+			multiple: First,
+			want:     "10.0.0.1\t10.0.0.3\n",
+		},
+		{
+			name:    "Grouped IEs and Structures",
+			columns: "BearerContext.FTEIDs[interface=1].TEID,CreatePDR.PDI.Source Interface,ULI.TAI,Recovery",
+			comma:   ',',
+			want:    `0x00000004,Access,"{""MCC"":""001"",""MNC"":""01"",""TAC"":""0x0001""}",` + "\n",
+		},
+		{
+			name:    "Filter Value with Separators",
+			columns: "F-TEID[interface=S5/S8 SGW GTP-C (6)].TEID,IMSI",
+			comma:   ',',
+			want:    "0x00000002,001010123456789\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewEncoder(tt.columns, tt.comma, tt.multiple, "|")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := e.Encode([]byte(testRecord))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeader(t *testing.T) {
+	e, err := NewEncoder("IMSI, F-TEID[interface=10].IPv4,Cause.Value", ',', Join, "|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(e.Header()), "IMSI,F-TEID[interface=10].IPv4,Cause.Value\n"; got != want {
+		t.Errorf("Header() = %q, want %q", got, want)
+	}
+}
+
+func TestNewEncoderErrors(t *testing.T) {
+	for _, columns := range []string{"", " , ", "IMSI..APN", "F-TEID[interface].IPv4", "F-TEID[interface=10.IPv4", "F-TEID[=10]"} {
+		if _, err := NewEncoder(columns, ',', Join, "|"); err == nil {
+			t.Errorf("NewEncoder(%q) did not fail", columns)
+		}
+	}
+	if _, err := ParseMultiple("all"); err == nil {
+		t.Error("ParseMultiple() of an unknown mode did not fail")
+	}
+}
@@ -0,0 +1,300 @@
+package defrag
+
+import (
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// maxFragments limits the number of fragments collected for a single datagram,
+// a datagram split into more pieces is discarded
+const maxFragments = 256
+
+// maxDatagramSize is the largest payload an IP datagram can carry
+const maxDatagramSize = 65535
+
+// key identifies the fragments of one datagram (RFC 791 and RFC 8200)
+type key struct {
+	version  uint8
+	src      string
+	dst      string
+	id       uint32
+	protocol uint8
+}
+
+// fragment holds the payload of a single fragment at its offset in the datagram
+type fragment struct {
+	offset int
+	data   []byte
+}
+
+// datagram collects the fragments of a datagram until it is complete
+type datagram struct {
+	// first is the fragment with offset 0, its headers are reused for the reassembled packet
+	first     gopacket.Packet
+	fragments []fragment
+	// total is the payload length, known once the last fragment has arrived
+	total     int
+	bytes     int
+	firstSeen time.Time
+}
+
+// Reassembler rebuilds fragmented IPv4 and IPv6 datagrams so that the GTP decoders see the
+// whole message. Memory is bounded by the total size of the buffered fragments, incomplete
+// datagrams are discarded after the timeout. It is not safe for concurrent use.
+type Reassembler struct {
+	datagrams map[key]*datagram
+	timeout   time.Duration
+	maxBytes  int
+	bytes     int
+
+	// The clock follows packet timestamps so that pcap files are reassembled in capture time,
+	// it keeps running in wall time while no packets arrive
+	lastPacket time.Time
+	lastWall   time.Time
+
+	reassembled uint64
+	expired     uint64
+	dropped     uint64
+}
+
+// NewReassembler creates a Reassembler buffering at most maxBytes of fragment payload, 0 means unlimited
+func NewReassembler(timeout time.Duration, maxBytes int) *Reassembler {
+	return &Reassembler{
+		datagrams: make(map[key]*datagram),
+		timeout:   timeout,
+		maxBytes:  maxBytes,
+	}
+}
+
+// Process returns the packet itself if it is not a fragment and the reassembled packet when it
+// completes a datagram. Otherwise the fragment is buffered or dropped and false is returned.
+func (r *Reassembler) Process(packet gopacket.Packet) (gopacket.Packet, bool) {
+	ts := packet.Metadata().Timestamp
+	if ts.After(r.lastPacket) {
+		r.lastPacket = ts
+		r.lastWall = time.Now()
+	}
+
+	switch ip := packet.NetworkLayer().(type) {
+	case *layers.IPv4:
+		if ip.Flags&layers.IPv4MoreFragments == 0 && ip.FragOffset == 0 {
+			return packet, true
+		}
+		k := key{
+			version:  4,
+			src:      ip.SrcIP.String(),
+			dst:      ip.DstIP.String(),
+			id:       uint32(ip.Id),
+			protocol: uint8(ip.Protocol),
+		}
+		return r.add(k, packet, int(ip.FragOffset)*8, ip.Flags&layers.IPv4MoreFragments != 0, ip.Payload)
+	case *layers.IPv6:
+		frag, ok := packet.Layer(layers.LayerTypeIPv6Fragment).(*layers.IPv6Fragment)
+		if !ok {
+			return packet, true
+		}
+		// Only a Fragment header directly following the fixed header is supported
+		if ip.NextHeader != layers.IPProtocolIPv6Fragment {
+			return packet, true
+		}
+		k := key{
+			version: 6,
+			src:     ip.SrcIP.String(),
+			dst:     ip.DstIP.String(),
+			id:      frag.Identification,
+		}
+		return r.add(k, packet, int(frag.FragmentOffset)*8, frag.MoreFragments, frag.Payload)
+	}
+
+	return packet, true
+}
+
+// add buffers a fragment and returns the reassembled packet once all fragments are present
+func (r *Reassembler) add(k key, packet gopacket.Packet, offset int, more bool, payload []byte) (gopacket.Packet, bool) {
+	if offset+len(payload) > maxDatagramSize {
+		r.dropped++
+		return nil, false
+	}
+
+	d, exists := r.datagrams[k]
+	if !exists {
+		d = &datagram{total: -1, firstSeen: packet.Metadata().Timestamp}
+	}
+	if len(d.fragments) >= maxFragments {
+		r.discard(k, d)
+		r.dropped += uint64(len(d.fragments)) + 1
+		return nil, false
+	}
+	if r.maxBytes > 0 && r.bytes+len(payload) > r.maxBytes {
+		r.dropped++
+		return nil, false
+	}
+	if !exists {
+		r.datagrams[k] = d
+	}
+
+	// The payload is copied, the packet data is not retained apart from the first fragment
+	data := make([]byte, len(payload))
+	copy(data, payload)
+	d.fragments = append(d.fragments, fragment{offset: offset, data: data})
+	d.bytes += len(data)
+	r.bytes += len(data)
+	if offset == 0 {
+		d.first = packet
+	}
+	if !more {
+		d.total = offset + len(data)
+	}
+
+	if d.first == nil || d.total < 0 || !d.complete() {
+		return nil, false
+	}
+
+	r.discard(k, d)
+	reassembled, ok := d.build(k.version, packet.Metadata().CaptureInfo)
+	if !ok {
+		r.dropped += uint64(len(d.fragments))
+		return nil, false
+	}
+	r.reassembled++
+	return reassembled, true
+}
+
+// discard removes a datagram from the table and releases its fragments
+func (r *Reassembler) discard(k key, d *datagram) {
+	delete(r.datagrams, k)
+	r.bytes -= d.bytes
+}
+
+// complete reports whether the fragments cover the whole datagram without holes
+func (d *datagram) complete() bool {
+	sort.Slice(d.fragments, func(i, j int) bool {
+		return d.fragments[i].offset < d.fragments[j].offset
+	})
+
+	covered := 0
+	for _, f := range d.fragments {
+		if f.offset > covered {
+			return false
+		}
+		if end := f.offset + len(f.data); end > covered {
+			covered = end
+		}
+	}
+	return covered >= d.total
+}
+
+// build creates a packet from the headers of the first fragment and the joined payload
+func (d *datagram) build(version uint8, ci gopacket.CaptureInfo) (gopacket.Packet, bool) {
+	payload := make([]byte, d.total)
+	for _, f := range d.fragments {
+		if f.offset < d.total {
+			copy(payload[f.offset:], f.data)
+		}
+	}
+
+	// Link layer and tunnel headers in front of the IP header are kept as they are
+	ipStart := 0
+	network := d.first.NetworkLayer()
+	for _, layer := range d.first.Layers() {
+		if layer == network {
+			break
+		}
+		ipStart += len(layer.LayerContents())
+	}
+
+	var header []byte
+	switch ip := network.(type) {
+	case *layers.IPv4:
+		header = append([]byte{}, ip.Contents...)
+		if len(header)+len(payload) > maxDatagramSize {
+			return nil, false
+		}
+		binary.BigEndian.PutUint16(header[2:4], uint16(len(header)+len(payload)))
+		// Keep the Don't Fragment flag, clear More Fragments and the offset
+		binary.BigEndian.PutUint16(header[6:8], binary.BigEndian.Uint16(header[6:8])&0x4000)
+		binary.BigEndian.PutUint16(header[10:12], 0)
+		binary.BigEndian.PutUint16(header[10:12], checksum(header))
+	case *layers.IPv6:
+		frag, ok := d.first.Layer(layers.LayerTypeIPv6Fragment).(*layers.IPv6Fragment)
+		if !ok {
+			return nil, false
+		}
+		// The Fragment header is removed and its Next Header moved into the fixed header
+		header = append([]byte{}, ip.Contents...)
+		header[6] = uint8(frag.NextHeader)
+		binary.BigEndian.PutUint16(header[4:6], uint16(len(payload)))
+	default:
+		return nil, false
+	}
+
+	data := make([]byte, 0, ipStart+len(header)+len(payload))
+	data = append(data, d.first.Data()[:ipStart]...)
+	data = append(data, header...)
+	data = append(data, payload...)
+
+	packet := gopacket.NewPacket(data, d.first.Layers()[0].LayerType(), gopacket.Default)
+	ci.CaptureLength = len(data)
+	ci.Length = len(data)
+	packet.Metadata().CaptureInfo = ci
+	return packet, true
+}
+
+// checksum computes the IPv4 header checksum
+func checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i : i+2]))
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// Now returns the current time of the reassembler clock
+func (r *Reassembler) Now() time.Time {
+	if r.lastPacket.IsZero() {
+		return time.Now()
+	}
+	return r.lastPacket.Add(time.Since(r.lastWall))
+}
+
+// Expire discards incomplete datagrams whose first fragment arrived longer than the timeout ago
+func (r *Reassembler) Expire(now time.Time) {
+	for k, d := range r.datagrams {
+		if now.Sub(d.firstSeen) >= r.timeout {
+			r.discard(k, d)
+			r.expired += uint64(len(d.fragments))
+		}
+	}
+}
+
+// Len returns the number of incomplete datagrams
+func (r *Reassembler) Len() int {
+	return len(r.datagrams)
+}
+
+// Bytes returns the size of the buffered fragment payload
+func (r *Reassembler) Bytes() int {
+	return r.bytes
+}
+
+// Reassembled returns the number of datagrams rebuilt from fragments
+func (r *Reassembler) Reassembled() uint64 {
+	return r.reassembled
+}
+
+// Expired returns the number of fragments discarded after the timeout
+func (r *Reassembler) Expired() uint64 {
+	return r.expired
+}
+
+// Dropped returns the number of fragments discarded because of the memory or fragment limits
+func (r *Reassembler) Dropped() uint64 {
+	return r.dropped
+}
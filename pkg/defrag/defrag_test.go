@@ -0,0 +1,200 @@
+package defrag
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var (
+	testSrcMAC = net.HardwareAddr{0x84, 0xb5, 0x9c, 0x67, 0x9d, 0x29}
+	testDstMAC = net.HardwareAddr{0x84, 0xb5, 0xd1, 0x58, 0x1f, 0xa3}
+	testTime   = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// testUDPDatagram returns a UDP header and payload of the given size addressed to port 2123
+func testUDPDatagram(t *testing.T, size int) []byte {
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	udp := &layers.UDP{SrcPort: 2123, DstPort: 2123}
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, udp, gopacket.Payload(payload)); err != nil {
+		t.Fatalf("failed to serialize UDP datagram: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// testIPv4Fragment builds an Ethernet frame carrying an IPv4 fragment
+func testIPv4Fragment(t *testing.T, id uint16, offset int, more bool, data []byte, ts time.Time) gopacket.Packet {
+	ip := &layers.IPv4{
+		Version:    4,
+		TTL:        64,
+		Id:         id,
+		Protocol:   layers.IPProtocolUDP,
+		SrcIP:      net.IP{10, 0, 0, 2},
+		DstIP:      net.IP{10, 0, 0, 1},
+		FragOffset: uint16(offset / 8),
+	}
+	if more {
+		ip.Flags = layers.IPv4MoreFragments
+	}
+	return testPacket(t, layers.EthernetTypeIPv4, ts, ip, gopacket.Payload(data))
+}
+
+// testIPv6Fragment builds an Ethernet frame carrying an IPv6 fragment
+func testIPv6Fragment(t *testing.T, id uint32, offset int, more bool, data []byte, ts time.Time) gopacket.Packet {
+	ip := &layers.IPv6{
+		Version:    6,
+		HopLimit:   64,
+		NextHeader: layers.IPProtocolIPv6Fragment,
+		SrcIP:      net.ParseIP("2001:db8::2"),
+		DstIP:      net.ParseIP("2001:db8::1"),
+	}
+	// The IPv6 Fragment layer cannot be serialized by gopacket, its header is written as payload
+	header := []byte{uint8(layers.IPProtocolUDP), 0, byte(offset >> 8), byte(offset & 0xf8), byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	if more {
+		header[3] |= 0x01
+	}
+	return testPacket(t, layers.EthernetTypeIPv6, ts, ip, gopacket.Payload(append(header, data...)))
+}
+
+func testPacket(t *testing.T, ethType layers.EthernetType, ts time.Time, l ...gopacket.SerializableLayer) gopacket.Packet {
+	eth := &layers.Ethernet{SrcMAC: testSrcMAC, DstMAC: testDstMAC, EthernetType: ethType}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, append([]gopacket.SerializableLayer{eth}, l...)...); err != nil {
+		t.Fatalf("failed to serialize packet: %v", err)
+	}
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+	p.Metadata().Timestamp = ts
+	return p
+}
+
+// checkUDP verifies that the reassembled packet carries the original UDP datagram
+func checkUDP(t *testing.T, p gopacket.Packet, datagram []byte) {
+	udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok {
+		t.Fatal("UDP layer not decoded in reassembled packet")
+	}
+	if udp.DstPort != 2123 || !bytes.Equal(udp.Payload, datagram[8:]) {
+		t.Errorf("reassembled UDP payload mismatch: got %d bytes to port %d", len(udp.Payload), udp.DstPort)
+	}
+}
+
+func TestReassembleIPv4(t *testing.T) {
+	datagram := testUDPDatagram(t, 2992)
+
+	tests := []struct {
+		name  string
+		order []int
+	}{
+		{name: "In Order", order: []int{0, 1, 2}},
+		{name: "Out Of Order", order: []int{2, 0, 1}},
+		{name: "Duplicate Fragment", order: []int{0, 0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReassembler(30*time.Second, 0)
+			fragments := []gopacket.Packet{
+				testIPv4Fragment(t, 7, 0, true, datagram[:1480], testTime),
+				testIPv4Fragment(t, 7, 1480, true, datagram[1480:2960], testTime),
+				testIPv4Fragment(t, 7, 2960, false, datagram[2960:], testTime.Add(time.Millisecond)),
+			}
+
+			var got gopacket.Packet
+			for i, index := range tt.order {
+				p, ok := r.Process(fragments[index])
+				if ok != (i == len(tt.order)-1) {
+					t.Fatalf("Process() of fragment %d returned %v", index, ok)
+				}
+				got = p
+			}
+
+			checkUDP(t, got, datagram)
+			ip := got.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+			if ip.Flags&layers.IPv4MoreFragments != 0 || ip.FragOffset != 0 || int(ip.Length) != 20+len(datagram) {
+				t.Errorf("unexpected reassembled IPv4 header: %+v", ip)
+			}
+			if r.Len() != 0 || r.Bytes() != 0 || r.Reassembled() != 1 {
+				t.Errorf("unexpected state: len %d, bytes %d, reassembled %d", r.Len(), r.Bytes(), r.Reassembled())
+			}
+		})
+	}
+}
+
+func TestReassembleIPv6(t *testing.T) {
+	datagram := testUDPDatagram(t, 2000)
+	r := NewReassembler(30*time.Second, 0)
+
+	if _, ok := r.Process(testIPv6Fragment(t, 0x01020304, 1232, false, datagram[1232:], testTime)); ok {
+		t.Fatal("last fragment returned before the first one arrived")
+	}
+	got, ok := r.Process(testIPv6Fragment(t, 0x01020304, 0, true, datagram[:1232], testTime))
+	if !ok {
+		t.Fatal("datagram not reassembled")
+	}
+
+	checkUDP(t, got, datagram)
+	if got.Layer(layers.LayerTypeIPv6Fragment) != nil {
+		t.Error("Fragment header left in reassembled packet")
+	}
+	if got.Metadata().Length != len(got.Data()) {
+		t.Errorf("capture length %d does not match packet size %d", got.Metadata().Length, len(got.Data()))
+	}
+}
+
+func TestUnfragmented(t *testing.T) {
+	r := NewReassembler(30*time.Second, 0)
+	datagram := testUDPDatagram(t, 16)
+	p := testPacket(t, layers.EthernetTypeIPv4, testTime, &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.IP{10, 0, 0, 2},
+		DstIP:    net.IP{10, 0, 0, 1},
+	}, gopacket.Payload(datagram))
+
+	got, ok := r.Process(p)
+	if !ok || got != p {
+		t.Error("unfragmented packet not passed through")
+	}
+}
+
+func TestExpire(t *testing.T) {
+	datagram := testUDPDatagram(t, 2992)
+	r := NewReassembler(30*time.Second, 0)
+
+	r.Process(testIPv4Fragment(t, 1, 0, true, datagram[:1480], testTime))
+	r.Process(testIPv4Fragment(t, 1, 1480, true, datagram[1480:2960], testTime))
+	r.Process(testIPv4Fragment(t, 2, 0, true, datagram[:1480], testTime.Add(20*time.Second)))
+
+	r.Expire(testTime.Add(40 * time.Second))
+	if r.Len() != 1 || r.Expired() != 2 || r.Bytes() != 1480 {
+		t.Errorf("unexpected state after expiry: len %d, expired %d, bytes %d", r.Len(), r.Expired(), r.Bytes())
+	}
+
+	// The last fragment of an expired datagram starts a new one that never completes
+	if _, ok := r.Process(testIPv4Fragment(t, 1, 2960, false, datagram[2960:], testTime.Add(41*time.Second))); ok {
+		t.Error("expired datagram reassembled")
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	datagram := testUDPDatagram(t, 2992)
+	r := NewReassembler(30*time.Second, 2000)
+
+	r.Process(testIPv4Fragment(t, 1, 0, true, datagram[:1480], testTime))
+	if _, ok := r.Process(testIPv4Fragment(t, 2, 0, true, datagram[:1480], testTime)); ok {
+		t.Error("fragment accepted beyond the memory limit")
+	}
+	if r.Dropped() != 1 || r.Bytes() != 1480 || r.Len() != 1 {
+		t.Errorf("unexpected state: dropped %d, bytes %d, len %d", r.Dropped(), r.Bytes(), r.Len())
+	}
+}
@@ -0,0 +1,80 @@
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// NewDocument converts a JSON record of a protocol to a document of the daily index of its
+// timestamp, the time of the packet or the start of a G-PDU flow. The IEs are added as an ie object
+// keyed by protocol and IE type, so that they can be searched as e.g. ie.gtpv2.IMSI or
+// ie.gtpv2.ULI.ECGI.ECI. IEs that occur more than once become lists. The ies list is kept unchanged.
+func NewDocument(prefix, protocol string, record []byte) (Document, error) {
+	dec := json.NewDecoder(bytes.NewReader(record))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return Document{}, fmt.Errorf("failed to parse record: %w", err)
+	}
+
+	var ts time.Time
+	for _, name := range []string{"timestamp", "firstSeen"} {
+		if s, ok := fields[name].(string); ok {
+			var err error
+			if ts, err = time.Parse(time.RFC3339Nano, s); err != nil {
+				return Document{}, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			break
+		}
+	}
+	if ts.IsZero() {
+		return Document{}, fmt.Errorf("record has no timestamp")
+	}
+
+	if ies, ok := fields["ies"].([]interface{}); ok {
+		if keyed, ok := keyIEs(ies); ok {
+			fields["ie"] = map[string]interface{}{ProtocolKey(protocol): keyed}
+		}
+	}
+
+	source, err := json.Marshal(fields)
+	if err != nil {
+		return Document{}, err
+	}
+	return Document{Index: IndexName(prefix, ts), Source: source}, nil
+}
+
+// keyIEs converts a list of IEs to an object keyed by IE type. Grouped IEs, whose values are
+// lists of IEs, are converted as well.
+func keyIEs(list []interface{}) (map[string]interface{}, bool) {
+	values := map[string][]interface{}{}
+	for _, elem := range list {
+		ie, ok := elem.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		typ, ok := ie["type"].(string)
+		value, hasValue := ie["value"]
+		if !ok || !hasValue {
+			return nil, false
+		}
+		if nested, ok := value.([]interface{}); ok && len(nested) > 0 {
+			if grouped, ok := keyIEs(nested); ok {
+				value = grouped
+			}
+		}
+		values[typ] = append(values[typ], value)
+	}
+
+	keyed := make(map[string]interface{}, len(values))
+	for typ, v := range values {
+		if len(v) == 1 {
+			keyed[typ] = v[0]
+		} else {
+			keyed[typ] = v
+		}
+	}
+	return keyed, true
+}
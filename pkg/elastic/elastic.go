@@ -0,0 +1,196 @@
+package elastic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/batch"
+)
+
+// Config configures the connection to Elasticsearch or OpenSearch
+type Config struct {
+	// URLs of the nodes, requests go to the next node when one fails
+	URLs     []string
+	Username string
+	Password string
+	// APIKey is the encoded API key sent as "Authorization: ApiKey", it replaces basic authentication
+	APIKey  string
+	TLS     *tls.Config
+	Timeout time.Duration
+}
+
+// Client sends bulk requests to an Elasticsearch or OpenSearch cluster
+type Client struct {
+	config Config
+	client *http.Client
+	next   int
+}
+
+// Document is a record to be indexed
+type Document struct {
+	Index  string
+	Source []byte
+}
+
+// BulkResult counts the documents of a bulk request
+type BulkResult struct {
+	Indexed int
+	// Failed documents were rejected by the cluster, e.g. because they do not match the mapping
+	Failed int
+	// Errors holds the first rejection reasons
+	Errors []string
+}
+
+// maxErrors is the number of rejection reasons kept in a BulkResult
+const maxErrors = 5
+
+// NewClient returns a client for the given cluster
+func NewClient(config Config) (*Client, error) {
+	if len(config.URLs) == 0 {
+		return nil, fmt.Errorf("no Elasticsearch URL configured")
+	}
+	for i, u := range config.URLs {
+		if _, err := url.ParseRequestURI(u); err != nil {
+			return nil, fmt.Errorf("invalid Elasticsearch URL %q: %w", u, err)
+		}
+		config.URLs[i] = strings.TrimSuffix(u, "/")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config.TLS
+	return &Client{config: config, client: &http.Client{Transport: transport, Timeout: config.Timeout}}, nil
+}
+
+// IndexName returns the daily index of a record: the prefix followed by the UTC date
+func IndexName(prefix string, ts time.Time) string {
+	return prefix + "-" + ts.UTC().Format("2006.01.02")
+}
+
+// retryable tells whether a response status is resolved by sending the request again
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// statusError is the error of a request answered with an unexpected status
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.status, e.body)
+}
+
+// do sends a request to the current node. Connection errors move on to the next node.
+// Errors that are not resolved by retrying are marked permanent.
+func (c *Client) do(method, path, contentType string, body []byte) ([]byte, error) {
+	base := c.config.URLs[c.next%len(c.config.URLs)]
+	req, err := http.NewRequest(method, base+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, batch.Permanent(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case c.config.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.config.APIKey)
+	case c.config.Username != "":
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.next++
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		err := &statusError{status: resp.StatusCode, body: strings.TrimSpace(string(data))}
+		if retryable(resp.StatusCode) {
+			c.next++
+			return nil, err
+		}
+		return nil, batch.Permanent(err)
+	}
+	return data, nil
+}
+
+// PutTemplate creates or replaces a composable index template
+func (c *Client) PutTemplate(name string, template []byte) error {
+	_, err := c.do(http.MethodPut, "/_index_template/"+url.PathEscape(name), "application/json", template)
+	return err
+}
+
+// bulkResponse is the part of the bulk API response needed to find rejected documents
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// Bulk indexes documents with the bulk API, retrying with back-off while the cluster is
+// unavailable or rejects documents because it is overloaded
+func (c *Client) Bulk(docs []Document, maxRetries int, backoff batch.Backoff) (BulkResult, error) {
+	var result BulkResult
+	pending := docs
+	err := batch.Retry(maxRetries, backoff, func() error {
+		var body bytes.Buffer
+		for _, doc := range pending {
+			action, _ := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": doc.Index}})
+			body.Write(action)
+			body.WriteByte('\n')
+			body.Write(doc.Source)
+			body.WriteByte('\n')
+		}
+
+		data, err := c.do(http.MethodPost, "/_bulk", "application/x-ndjson", body.Bytes())
+		if err != nil {
+			return err
+		}
+		var resp bulkResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return batch.Permanent(fmt.Errorf("invalid bulk response: %w", err))
+		}
+		if len(resp.Items) != len(pending) {
+			return batch.Permanent(fmt.Errorf("bulk response has %d items for %d documents", len(resp.Items), len(pending)))
+		}
+
+		var retry []Document
+		for i, item := range resp.Items {
+			for _, status := range item {
+				switch {
+				case status.Status/100 == 2:
+					result.Indexed++
+				case retryable(status.Status):
+					retry = append(retry, pending[i])
+				default:
+					result.Failed++
+					if len(result.Errors) < maxErrors {
+						result.Errors = append(result.Errors, string(status.Error))
+					}
+				}
+			}
+		}
+		pending = retry
+		if len(pending) > 0 {
+			return fmt.Errorf("%d documents rejected by an overloaded cluster", len(pending))
+		}
+		return nil
+	})
+	result.Failed += len(pending)
+	if err != nil {
+		return result, fmt.Errorf("bulk request failed: %w", err)
+	}
+	return result, nil
+}
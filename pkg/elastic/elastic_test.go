@@ -0,0 +1,336 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/batch"
+)
+
+type testEndpoint struct {
+	SrcIP   string `json:"srcIP,omitempty"`
+	SrcPort uint16 `json:"srcPort,omitempty"`
+}
+
+type testIE struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type testRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	testEndpoint
+	Protocol string   `json:"protocol"`
+	TEID     *uint32  `json:"teid,omitempty"`
+	IEs      []testIE `json:"ies"`
+}
+
+type testFlow struct {
+	Protocol  uint8     `json:"protocol"`
+	Bytes     uint64    `json:"bytes"`
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+func init() {
+	for _, r := range []interface{}{testRecord{}, testFlow{}} {
+		if err := RegisterRecord(r); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	data, err := Template([]string{"gtp_packets-*", "gtp_errors-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template struct {
+		IndexPatterns []string `json:"index_patterns"`
+		Template      struct {
+			Mappings struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"mappings"`
+		} `json:"template"`
+	}
+	if err := json.Unmarshal(data, &template); err != nil {
+		t.Fatalf("Template() is not valid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(template.IndexPatterns, []string{"gtp_packets-*", "gtp_errors-*"}) {
+		t.Errorf("index_patterns = %v", template.IndexPatterns)
+	}
+
+	props := template.Template.Mappings.Properties
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"timestamp", `{"type":"date"}`},
+		{"firstSeen", `{"type":"date"}`},
+		{"srcIP", `{"ignore_above":1024,"type":"keyword"}`},
+		{"srcPort", `{"type":"long"}`},
+		{"teid", `{"type":"long"}`},
+		{"bytes", `{"type":"long"}`},
+		// A string in one record type and a number in another
+		{"protocol", `{"ignore_above":1024,"type":"keyword"}`},
+		{"ies", `{"enabled":false,"type":"object"}`},
+	}
+	for _, tt := range tests {
+		if got := string(props[tt.field]); got != tt.want {
+			t.Errorf("%s mapping = %s, want %s", tt.field, got, tt.want)
+		}
+	}
+
+	var ie struct {
+		Properties map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(props["ie"], &ie); err != nil {
+		t.Fatal(err)
+	}
+	gtpv2 := ie.Properties["gtpv2"].Properties
+	for name, want := range map[string]string{
+		"IMSI":   `{"ignore_above":1024,"type":"keyword"}`,
+		"F-TEID": `{"properties":{"F-TEID IPv4":{"ignore_above":1024,"type":"keyword"},"F-TEID IPv6":{"ignore_above":1024,"type":"keyword"},"TEID/GRE Key":{"ignore_above":1024,"type":"keyword"}}}`,
+	} {
+		if got := string(gtpv2[name]); got != want {
+			t.Errorf("ie.gtpv2.%s mapping = %s, want %s", name, got, want)
+		}
+	}
+	if !strings.Contains(string(gtpv2["ULI"]), `"ECGI":{"properties":{"ECI":{"ignore_above":1024,"type":"keyword"},"MCC"`) {
+		t.Errorf("ie.gtpv2.ULI mapping = %s", gtpv2["ULI"])
+	}
+	// PFCP IEs are mapped dynamically, so that the scalar PFCP Cause does not meet the GTPv2 object
+	if _, ok := ie.Properties["pfcp"]; ok {
+		t.Errorf("ie.pfcp mapping = %v", ie.Properties["pfcp"])
+	}
+}
+
+func TestNewDocument(t *testing.T) {
+	record := `{"timestamp":"2024-05-01T23:59:59.5+02:00","teid":1,"ies":[
+		{"type":"IMSI","value":"001010123456789"},
+		{"type":"F-TEID","value":{"TEID/GRE Key":"0x1"}},
+		{"type":"F-TEID","value":{"TEID/GRE Key":"0x2"}},
+		{"type":"CreatePDR","value":[{"type":"PDR ID","value":1},{"type":"PDI","value":[{"type":"Source Interface","value":"Access"}]}]}]}`
+
+	doc, err := NewDocument("gtp_packets", "GTPv2", []byte(record))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Index != "gtp_packets-2024.05.01" {
+		t.Errorf("Index = %q", doc.Index)
+	}
+	var source struct {
+		TEID json.Number     `json:"teid"`
+		IEs  json.RawMessage `json:"ies"`
+		IE   json.RawMessage `json:"ie"`
+	}
+	if err := json.Unmarshal(doc.Source, &source); err != nil {
+		t.Fatal(err)
+	}
+	wantIE := `{"gtpv2":{"CreatePDR":{"PDI":{"Source Interface":"Access"},"PDR ID":1},"F-TEID":[{"TEID/GRE Key":"0x1"},{"TEID/GRE Key":"0x2"}],"IMSI":"001010123456789"}}`
+	if string(source.IE) != wantIE {
+		t.Errorf("ie = %s, want %s", source.IE, wantIE)
+	}
+	if source.TEID != "1" || !bytes.Contains(source.IEs, []byte(`"type":"CreatePDR"`)) {
+		t.Errorf("record fields changed: %s", doc.Source)
+	}
+
+	pfcp, err := NewDocument("gtp_packets", "PFCP", []byte(`{"timestamp":"2024-05-01T00:00:00Z","protocol":"PFCP","ies":[
+		{"type":"Cause","value":"Request accepted (1)"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(pfcp.Source, []byte(`"ie":{"pfcp":{"Cause":"Request accepted (1)"}}`)) {
+		t.Errorf("PFCP document = %s", pfcp.Source)
+	}
+
+	flow, err := NewDocument("gtp_packets", "GTP-U", []byte(`{"recordType":"flow","firstSeen":"2024-05-02T00:00:00Z"}`))
+	if err != nil || flow.Index != "gtp_packets-2024.05.02" {
+		t.Errorf("flow document index = %q, %v", flow.Index, err)
+	}
+	if _, err := NewDocument("gtp_packets", "GTPv2", []byte(`{"ies":[]}`)); err == nil {
+		t.Error("NewDocument() without a timestamp did not fail")
+	}
+}
+
+// bulkItem is the response of the stand-in cluster for a document
+type bulkItem struct {
+	status int
+	reason string
+}
+
+func TestBulk(t *testing.T) {
+	docs := []Document{
+		{Index: "gtp_packets-2024.05.01", Source: []byte(`{"n":0}`)},
+		{Index: "gtp_packets-2024.05.01", Source: []byte(`{"n":1}`)},
+		{Index: "gtp_errors-2024.05.02", Source: []byte(`{"n":2}`)},
+	}
+	tests := []struct {
+		name string
+		// responses holds per request either an HTTP status or the item results
+		responses  []interface{}
+		config     Config
+		maxRetries int
+		want       BulkResult
+		wantErr    bool
+		wantDocs   []int
+	}{
+		{
+			name:      "Indexed",
+			responses: []interface{}{[]bulkItem{{status: 201}, {status: 201}, {status: 200}}},
+			config:    Config{Username: "gtp2json", Password: "secret"},
+			want:      BulkResult{Indexed: 3},
+			wantDocs:  []int{3},
+		},
+		{
+			name: "Rejected and Overloaded Documents",
+			responses: []interface{}{
+				[]bulkItem{{status: 201}, {status: 429}, {status: 400, reason: "mapper_parsing_exception"}},
+				[]bulkItem{{status: 201}},
+			},
+			config:   Config{APIKey: "a2V5"},
+			want:     BulkResult{Indexed: 2, Failed: 1, Errors: []string{`{"type":"mapper_parsing_exception"}`}},
+			wantDocs: []int{3, 1},
+		},
+		{
+			name:      "Unavailable Cluster",
+			responses: []interface{}{http.StatusServiceUnavailable, []bulkItem{{status: 201}, {status: 201}, {status: 201}}},
+			config:    Config{Username: "gtp2json", Password: "secret"},
+			want:      BulkResult{Indexed: 3},
+			wantDocs:  []int{3, 3},
+		},
+		{
+			name:       "Retries Exhausted",
+			responses:  []interface{}{http.StatusBadGateway, http.StatusBadGateway},
+			config:     Config{Username: "gtp2json", Password: "secret"},
+			maxRetries: 2,
+			want:       BulkResult{Failed: 3},
+			wantErr:    true,
+			wantDocs:   []int{3, 3},
+		},
+		{
+			name:      "Unauthorized",
+			responses: []interface{}{http.StatusUnauthorized, []bulkItem{}},
+			config:    Config{Username: "gtp2json", Password: "wrong"},
+			want:      BulkResult{Failed: 3},
+			wantErr:   true,
+			wantDocs:  []int{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotDocs []int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Method != http.MethodPost || req.URL.Path != "/_bulk" {
+					t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+				}
+				if ct := req.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+					t.Errorf("Content-Type = %q", ct)
+				}
+				if tt.config.APIKey != "" {
+					if auth := req.Header.Get("Authorization"); auth != "ApiKey "+tt.config.APIKey {
+						t.Errorf("Authorization = %q", auth)
+					}
+				} else if user, password, ok := req.BasicAuth(); !ok || user != tt.config.Username || password != tt.config.Password {
+					t.Errorf("unexpected credentials %q %q", user, password)
+				}
+
+				// Every document is an action line followed by the source
+				lines := 0
+				scanner := bufio.NewScanner(req.Body)
+				for scanner.Scan() {
+					if lines%2 == 0 && !strings.HasPrefix(scanner.Text(), `{"index":{"_index":"gtp_`) {
+						t.Errorf("unexpected action %s", scanner.Text())
+					}
+					lines++
+				}
+				gotDocs = append(gotDocs, lines/2)
+
+				response := tt.responses[len(gotDocs)-1]
+				if status, ok := response.(int); ok {
+					w.WriteHeader(status)
+					io.WriteString(w, `{"error":"unavailable"}`)
+					return
+				}
+				var items []string
+				for _, item := range response.([]bulkItem) {
+					errorField := ""
+					if item.reason != "" {
+						errorField = fmt.Sprintf(`,"error":{"type":%q}`, item.reason)
+					}
+					items = append(items, fmt.Sprintf(`{"index":{"status":%d%s}}`, item.status, errorField))
+				}
+				fmt.Fprintf(w, `{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))
+			}))
+			defer server.Close()
+
+			config := tt.config
+			config.URLs = []string{server.URL + "/"}
+			client, err := NewClient(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := client.Bulk(docs, tt.maxRetries, batch.Backoff{Initial: time.Millisecond})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Bulk() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("Bulk() = %+v, want %+v", result, tt.want)
+			}
+			if !reflect.DeepEqual(gotDocs, tt.wantDocs) {
+				t.Errorf("documents per request = %v, want %v", gotDocs, tt.wantDocs)
+			}
+		})
+	}
+}
+
+func TestPutTemplate(t *testing.T) {
+	template, _ := Template([]string{"gtp_packets-*"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPut || req.URL.Path != "/_index_template/gtp_packets" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+		}
+		body, _ := io.ReadAll(req.Body)
+		if !bytes.Equal(body, template) {
+			t.Errorf("unexpected template %s", body)
+		}
+		io.WriteString(w, `{"acknowledged":true}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URLs: []string{server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PutTemplate("gtp_packets", template); err != nil {
+		t.Errorf("PutTemplate() error = %v", err)
+	}
+}
+
+func TestProtocolKey(t *testing.T) {
+	for protocol, want := range map[string]string{
+		"GTPv2": "gtpv2", "GTPv1-C": "gtpv1c", "GTP-U": "gtpu", "PFCP": "pfcp", "GTP'": "gtpprime",
+	} {
+		if got := ProtocolKey(protocol); got != want {
+			t.Errorf("ProtocolKey(%q) = %q, want %q", protocol, got, want)
+		}
+	}
+}
+
+func TestIndexName(t *testing.T) {
+	ts := time.Date(2024, 12, 31, 23, 30, 0, 0, time.FixedZone("", -3600))
+	if got := IndexName("gtp_packets", ts); got != "gtp_packets-2025.01.01" {
+		t.Errorf("IndexName() = %q", got)
+	}
+}
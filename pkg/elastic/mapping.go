@@ -0,0 +1,211 @@
+package elastic
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+)
+
+// property is the mapping of a field: a leaf type or an object with properties
+type property map[string]interface{}
+
+// registry holds the mapping of the registered record types and of the IE values per protocol key
+type registry struct {
+	mu      sync.Mutex
+	records property
+	ies     map[string]property
+}
+
+var reg = &registry{records: property{}, ies: map[string]property{}}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func init() {
+	// Structured GTPv2 IE values, IEs not listed here are mapped dynamically
+	for _, ie := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"IMSI", ""}, {"MSISDN", ""}, {"MEI", ""}, {"APN", ""}, {"FQDN", ""},
+		{"F-TEID", gtp2ie.FTEID{}}, {"ULI", gtp2ie.ULI{}}, {"ServingNetwork", gtp2ie.MCCMNC{}},
+		{"Indication", gtp2ie.Indication{}}, {"PAA", gtp2ie.PAA{}}, {"AMBR", gtp2ie.AMBR{}},
+		{"PCO", gtp2ie.PCO{}}, {"APCO", gtp2ie.PCO{}}, {"ePCO", gtp2ie.PCO{}}, {"Cause", gtp2ie.Cause{}},
+		{"BearerQoS", gtp2ie.BearerQoS{}}, {"BearerContext", gtp2ie.BearerContext{}},
+		{"UETimeZone", gtp2ie.UETimeZone{}}, {"ChargingCharacteristics", gtp2ie.ChargingChars{}},
+		{"PresenceReportingAreaAction", gtp2ie.PresenceReportingAreaAction{}},
+		{"PresenceReportingAreaInformation", gtp2ie.PresenceReportingAreaInformation{}},
+		{"PrivateExtension", gtp2ie.PrivateExtension{}}, {"NodeIdentifier", gtp2ie.NodeIdentifier{}},
+		{"SecondaryRATUsageDataReport", gtp2ie.SecondaryRATUsageDataReport{}},
+	} {
+		if err := RegisterIE("GTPv2", ie.name, ie.value); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterRecord adds the fields of a record type to the index template. Fields of several
+// record types with the same name are merged, fields whose types differ become keywords.
+// It is meant to be called from init functions.
+func RegisterRecord(record interface{}) error {
+	t := reflect.TypeOf(record)
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("record type %s is not a struct", t)
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	mergeProperties(reg.records, structProperties(t))
+	return nil
+}
+
+// RegisterIE adds the mapping of the value of an IE type of a protocol, which is indexed under
+// ie.<protocol key>.<name>. IEs of the same name may carry values of different shapes in other
+// protocols, e.g. the Cause of PFCP is a scalar.
+func RegisterIE(protocol, name string, value interface{}) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	key := ProtocolKey(protocol)
+	if _, exists := reg.ies[key][name]; exists {
+		return fmt.Errorf("IE %s of %s already registered", name, protocol)
+	}
+	if p := mapping(reflect.TypeOf(value)); p != nil {
+		if reg.ies[key] == nil {
+			reg.ies[key] = property{}
+		}
+		reg.ies[key][name] = p
+	}
+	return nil
+}
+
+// ProtocolKey returns the field name of a protocol under the ie object: the protocol name in lower
+// case without punctuation, e.g. gtpv2, gtpv1c, pfcp, gtpu and gtpprime for GTP'
+func ProtocolKey(protocol string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(protocol) {
+		switch {
+		case c >= 'a' && c <= 'z' || c >= '0' && c <= '9':
+			b.WriteRune(c)
+		case c == '\'':
+			b.WriteString("prime")
+		}
+	}
+	return b.String()
+}
+
+// mapping returns the mapping of a Go type, nil for types that are mapped dynamically
+func mapping(t reflect.Type) property {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 || t.Kind() == reflect.Array {
+		// Lists are indexed as their elements
+		t = t.Elem()
+	}
+	if t == timeType {
+		return property{"type": "date"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return property{"type": "keyword", "ignore_above": ignoreAbove}
+	case reflect.Bool:
+		return property{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return property{"type": "long"}
+	case reflect.Float32, reflect.Float64:
+		return property{"type": "double"}
+	case reflect.Slice:
+		return property{"type": "binary"}
+	case reflect.Struct:
+		return property{"properties": structProperties(t)}
+	}
+	// interface{} fields change their type with the output format, maps have arbitrary keys
+	return nil
+}
+
+// structProperties maps the fields of a struct by their JSON names, embedded structs are inlined
+func structProperties(t reflect.Type) property {
+	props := property{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			mergeProperties(props, structProperties(f.Type))
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if p := mapping(f.Type); p != nil {
+			props[name] = p
+		}
+	}
+	return props
+}
+
+// mergeProperties adds the properties of src to dst
+func mergeProperties(dst, src property) {
+	for name, p := range src {
+		existing, ok := dst[name].(property)
+		if !ok {
+			dst[name] = p
+			continue
+		}
+		next := p.(property)
+		existingProps, existingObject := existing["properties"].(property)
+		nextProps, nextObject := next["properties"].(property)
+		switch {
+		case existingObject && nextObject:
+			mergeProperties(existingProps, nextProps)
+		case existingObject || nextObject || existing["type"] != next["type"]:
+			dst[name] = property{"type": "keyword", "ignore_above": ignoreAbove}
+		}
+	}
+}
+
+// ignoreAbove is the length above which strings are stored but not indexed
+const ignoreAbove = 1024
+
+// Template returns an index template for the given index patterns. Record fields are mapped from
+// the registered record types and IE values from the registered IE types under the ie object per
+// protocol. The ies list is kept in the source without being indexed, as its values differ in type.
+func Template(patterns []string) ([]byte, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	props := property{}
+	mergeProperties(props, reg.records)
+	props["ies"] = property{"type": "object", "enabled": false}
+	ies := property{}
+	for key, p := range reg.ies {
+		ies[key] = property{"properties": p}
+	}
+	props["ie"] = property{"properties": ies}
+
+	template := map[string]interface{}{
+		"index_patterns": patterns,
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"index.mapping.total_fields.limit": 5000,
+			},
+			"mappings": map[string]interface{}{
+				"date_detection": false,
+				"dynamic_templates": []interface{}{
+					map[string]interface{}{
+						"strings": map[string]interface{}{
+							"match_mapping_type": "string",
+							"mapping":            property{"type": "keyword", "ignore_above": ignoreAbove},
+						},
+					},
+				},
+				"properties": props,
+			},
+		},
+		"_meta": map[string]interface{}{"generated_by": "gtp2json"},
+	}
+	return json.Marshal(template)
+}
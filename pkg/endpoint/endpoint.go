@@ -0,0 +1,141 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Endpoint holds the IP and UDP addressing of a captured message and the names of the nodes
+// that exchanged it. It is embedded into every record so the fields appear at the top level.
+type Endpoint struct {
+	SrcIP     string `json:"srcIP,omitempty"`
+	DstIP     string `json:"dstIP,omitempty"`
+	SrcPort   uint16 `json:"srcPort,omitempty"`
+	DstPort   uint16 `json:"dstPort,omitempty"`
+	IPVersion uint8  `json:"ipVersion,omitempty"`
+	DSCP      uint8  `json:"dscp"`
+	Interface string `json:"interface,omitempty"`
+	SrcNode   string `json:"srcNode,omitempty"`
+	DstNode   string `json:"dstNode,omitempty"`
+}
+
+// Node maps a set of addresses or prefixes to a configured node name
+type Node struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses"`
+}
+
+// nodeNames holds exact address matches, nodePrefixes is checked when no exact match exists
+var (
+	nodeNames    = map[string]string{}
+	nodePrefixes []nodePrefix
+)
+
+type nodePrefix struct {
+	network *net.IPNet
+	name    string
+}
+
+// FromPacket extracts the endpoint of a packet. The first network and transport layers are used,
+// so tunnels have to be removed before. iface is the name of the capture interface, if any.
+func FromPacket(packet gopacket.Packet, iface string) Endpoint {
+	e := Endpoint{Interface: iface}
+
+	switch ip := packet.NetworkLayer().(type) {
+	case *layers.IPv4:
+		e.SrcIP, e.DstIP = ip.SrcIP.String(), ip.DstIP.String()
+		e.IPVersion = 4
+		e.DSCP = ip.TOS >> 2
+	case *layers.IPv6:
+		e.SrcIP, e.DstIP = ip.SrcIP.String(), ip.DstIP.String()
+		e.IPVersion = 6
+		e.DSCP = ip.TrafficClass >> 2
+	}
+
+	switch transport := packet.TransportLayer().(type) {
+	case *layers.UDP:
+		e.SrcPort, e.DstPort = uint16(transport.SrcPort), uint16(transport.DstPort)
+	case *layers.TCP:
+		e.SrcPort, e.DstPort = uint16(transport.SrcPort), uint16(transport.DstPort)
+	case *layers.SCTP:
+		e.SrcPort, e.DstPort = uint16(transport.SrcPort), uint16(transport.DstPort)
+	}
+
+	e.SrcNode = NodeName(e.SrcIP)
+	e.DstNode = NodeName(e.DstIP)
+	return e
+}
+
+// NodeName returns the configured name of the node owning an address or an empty string
+func NodeName(address string) string {
+	if address == "" {
+		return ""
+	}
+	if name, ok := nodeNames[address]; ok {
+		return name
+	}
+	if len(nodePrefixes) == 0 {
+		return ""
+	}
+	ip := net.ParseIP(address)
+	for _, prefix := range nodePrefixes {
+		if prefix.network.Contains(ip) {
+			return prefix.name
+		}
+	}
+	return ""
+}
+
+// RegisterNode adds the addresses of a node, given as IPs or CIDR prefixes. Prefixes are
+// matched in registration order. It is meant to be called before any packet is decoded.
+func RegisterNode(node Node) error {
+	if node.Name == "" {
+		return fmt.Errorf("node without name")
+	}
+	for _, address := range node.Addresses {
+		if strings.Contains(address, "/") {
+			_, network, err := net.ParseCIDR(address)
+			if err != nil {
+				return fmt.Errorf("node %s: invalid prefix %q", node.Name, address)
+			}
+			nodePrefixes = append(nodePrefixes, nodePrefix{network: network, name: node.Name})
+			continue
+		}
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return fmt.Errorf("node %s: invalid address %q", node.Name, address)
+		}
+		// Addresses are stored in the form FromPacket prints them
+		nodeNames[ip.String()] = node.Name
+	}
+	return nil
+}
+
+// LoadNodeNames reads node names from a JSON file and registers them
+func LoadNodeNames(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read node names: %w", err)
+	}
+
+	var file struct {
+		Nodes []Node `json:"nodes"`
+	}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return fmt.Errorf("failed to parse node names: %w", err)
+	}
+
+	for _, node := range file.Nodes {
+		if err := RegisterNode(node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package endpoint
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func testPacket(t *testing.T, ip gopacket.NetworkLayer) gopacket.Packet {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x84, 0xb5, 0x9c, 0x67, 0x9d, 0x29},
+		DstMAC:       net.HardwareAddr{0x84, 0xb5, 0xd1, 0x58, 0x1f, 0xa3},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	if _, ok := ip.(*layers.IPv6); ok {
+		eth.EthernetType = layers.EthernetTypeIPv6
+	}
+	udp := &layers.UDP{SrcPort: 2123, DstPort: 34000}
+	udp.SetNetworkLayerForChecksum(ip)
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ip.(gopacket.SerializableLayer), udp, gopacket.Payload{0x00}); err != nil {
+		t.Fatalf("failed to serialize packet: %v", err)
+	}
+	return gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func TestFromPacket(t *testing.T) {
+	if err := RegisterNode(Node{Name: "MME-MSK-01", Addresses: []string{"10.0.0.1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterNode(Node{Name: "SGW-MSK", Addresses: []string{"2001:db8:0:0::1", "10.20.0.0/16"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		ip    gopacket.NetworkLayer
+		iface string
+		want  Endpoint
+	}{
+		{
+			name: "IPv4 With Exact And Prefix Match",
+			ip: &layers.IPv4{
+				Version: 4, TTL: 64, TOS: 0xb8, Protocol: layers.IPProtocolUDP,
+				SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 20, 3, 4},
+			},
+			iface: "eth0",
+			want: Endpoint{
+				SrcIP: "10.0.0.1", DstIP: "10.20.3.4", SrcPort: 2123, DstPort: 34000,
+				IPVersion: 4, DSCP: 46, Interface: "eth0", SrcNode: "MME-MSK-01", DstNode: "SGW-MSK",
+			},
+		},
+		{
+			name: "IPv6 Unknown Peer",
+			ip: &layers.IPv6{
+				Version: 6, HopLimit: 64, TrafficClass: 0x60, NextHeader: layers.IPProtocolUDP,
+				SrcIP: net.ParseIP("2001:db8::1"), DstIP: net.ParseIP("2001:db8::2"),
+			},
+			want: Endpoint{
+				SrcIP: "2001:db8::1", DstIP: "2001:db8::2", SrcPort: 2123, DstPort: 34000,
+				IPVersion: 6, DSCP: 24, SrcNode: "SGW-MSK",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromPacket(testPacket(t, tt.ip), tt.iface)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromPacket() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadNodeNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "Valid",
+			content: `{"nodes": [{"name": "PGW-SPB-02", "addresses": ["192.168.10.5", "192.168.11.0/24"]}]}`,
+		},
+		{
+			name:    "Invalid Address",
+			content: `{"nodes": [{"name": "PGW-SPB-03", "addresses": ["192.168.300.5"]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Missing Name",
+			content: `{"nodes": [{"addresses": ["192.168.10.6"]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Malformed JSON",
+			content: `{"nodes": [`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nodes.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := LoadNodeNames(path); (err != nil) != tt.wantErr {
+				t.Errorf("LoadNodeNames() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if got := NodeName("192.168.11.77"); got != "PGW-SPB-02" {
+		t.Errorf("NodeName() = %q, want %q", got, "PGW-SPB-02")
+	}
+}
@@ -0,0 +1,345 @@
+package filesink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the compression of the written files
+type Compression int
+
+const (
+	None Compression = iota
+	Gzip
+	Zstd
+)
+
+// Format is the framing of the records in the files
+type Format int
+
+const (
+	// NDJSON writes one record per line
+	NDJSON Format = iota
+	// Protobuf writes records prefixed by their length as a varint, like protobuf streams
+	// written by writeDelimitedTo
+	Protobuf
+)
+
+// formatExtensions name the files of a format
+var formatExtensions = map[Format]string{
+	NDJSON:   ".ndjson",
+	Protobuf: ".pb",
+}
+
+// extensions are appended to the names of the files after the format extension
+var extensions = map[Compression]string{
+	None: "",
+	Gzip: ".gz",
+	Zstd: ".zst",
+}
+
+// ParseCompression returns the compression of a name: none, gzip or zstd
+func ParseCompression(name string) (Compression, error) {
+	switch strings.ToLower(name) {
+	case "none", "":
+		return None, nil
+	case "gzip":
+		return Gzip, nil
+	case "zstd":
+		return Zstd, nil
+	}
+	return None, fmt.Errorf("unknown compression %q, use none, gzip or zstd", name)
+}
+
+// Config configures the rotation and retention of a Sink
+type Config struct {
+	// Dir is the directory the files are written to, Prefix starts their names
+	Dir         string
+	Prefix      string
+	Format      Format
+	Compression Compression
+	// MaxBytes closes a file once this many uncompressed bytes are written, 0 disables the limit
+	MaxBytes int64
+	// Interval closes files at the end of windows of the wall clock, 0 disables the windows
+	Interval time.Duration
+	// MaxFiles and MaxAge limit the closed files kept in Dir, 0 disables a limit
+	MaxFiles int
+	MaxAge   time.Duration
+	// Manifest is the name of the file in Dir to which closed files are appended, empty disables it
+	Manifest string
+}
+
+// ManifestEntry describes a closed file, the manifest has one entry per line
+type ManifestEntry struct {
+	File    string    `json:"file"`
+	Records int64     `json:"records"`
+	Bytes   int64     `json:"bytes"`
+	Opened  time.Time `json:"opened"`
+	Closed  time.Time `json:"closed"`
+}
+
+// Sink writes NDJSON or length-delimited protobuf records to a sequence of optionally compressed
+// files. Files are written under a temporary name, synced to disk and renamed when they are closed,
+// so that a shipper only ever sees complete files, either by watching the directory or by following
+// the manifest.
+type Sink struct {
+	config Config
+	now    func() time.Time
+
+	file       *os.File
+	buf        *bufio.Writer
+	compressor io.WriteCloser
+	path       string
+	opened     time.Time
+	window     time.Time
+	records    int64
+	written    int64
+	seq        int
+	closed     int
+}
+
+// New creates the output directory and returns a sink writing to it
+func New(config Config) (*Sink, error) {
+	if _, ok := extensions[config.Compression]; !ok {
+		return nil, fmt.Errorf("unknown compression %d", config.Compression)
+	}
+	if _, ok := formatExtensions[config.Format]; !ok {
+		return nil, fmt.Errorf("unknown format %d", config.Format)
+	}
+	if config.Prefix == "" || strings.ContainsRune(config.Prefix, os.PathSeparator) {
+		return nil, fmt.Errorf("invalid file prefix %q", config.Prefix)
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return &Sink{config: config, now: time.Now}, nil
+}
+
+// Write appends a record as a line or with its length, rotating to a new file when needed
+func (s *Sink) Write(record []byte) error {
+	if err := s.Rotate(); err != nil {
+		return err
+	}
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	frame := [][]byte{record, newline}
+	if s.config.Format == Protobuf {
+		frame = [][]byte{binary.AppendUvarint(nil, uint64(len(record))), record}
+	}
+	for _, b := range frame {
+		n, err := s.compressor.Write(b)
+		s.written += int64(n)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", s.path, err)
+		}
+	}
+	s.records++
+
+	if s.config.MaxBytes > 0 && s.written >= s.config.MaxBytes {
+		return s.closeFile()
+	}
+	return nil
+}
+
+// Rotate closes the current file when its time window has passed. It is called by Write and
+// should be called periodically, so that files are closed on time when no records arrive.
+func (s *Sink) Rotate() error {
+	if s.file == nil || s.config.Interval <= 0 {
+		return nil
+	}
+	if s.now().Truncate(s.config.Interval).Equal(s.window) {
+		return nil
+	}
+	return s.closeFile()
+}
+
+// open starts a new file named after its opening time and a sequence number
+func (s *Sink) open() error {
+	s.seq++
+	s.opened = s.now().UTC()
+	s.window = s.opened
+	if s.config.Interval > 0 {
+		s.window = s.opened.Truncate(s.config.Interval)
+	}
+	name := fmt.Sprintf("%s-%s-%04d%s", s.config.Prefix, s.opened.Format("20060102T150405Z"), s.seq, s.extension())
+	s.path = filepath.Join(s.config.Dir, name)
+
+	file, err := os.Create(s.path + ".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	buf := bufio.NewWriterSize(file, 256<<10)
+	var compressor io.WriteCloser
+	switch s.config.Compression {
+	case Gzip:
+		compressor = gzip.NewWriter(buf)
+	case Zstd:
+		if compressor, err = zstd.NewWriter(buf); err != nil {
+			file.Close()
+			return err
+		}
+	default:
+		compressor = nopCloser{buf}
+	}
+	s.file, s.buf, s.compressor = file, buf, compressor
+	s.records, s.written = 0, 0
+	return nil
+}
+
+// extension returns the extension of the files, e.g. .ndjson.gz
+func (s *Sink) extension() string {
+	return formatExtensions[s.config.Format] + extensions[s.config.Compression]
+}
+
+var newline = []byte{'\n'}
+
+// nopCloser writes uncompressed files
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// closeFile completes the current file, syncs it to disk, moves it to its final name and adds
+// it to the manifest. Old files are removed afterwards.
+func (s *Sink) closeFile() error {
+	file := s.file
+	s.file = nil
+
+	if err := s.compressor.Close(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compress %s: %w", s.path, err)
+	}
+	if err := s.buf.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync %s: %w", s.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", s.path, err)
+	}
+	if err := os.Rename(file.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", s.path, err)
+	}
+	if err := syncDir(s.config.Dir); err != nil {
+		return err
+	}
+	s.closed++
+
+	if s.config.Manifest != "" {
+		entry := ManifestEntry{
+			File:    filepath.Base(s.path),
+			Records: s.records,
+			Bytes:   info.Size(),
+			Opened:  s.opened,
+			Closed:  s.now().UTC(),
+		}
+		if err := s.appendManifest(entry); err != nil {
+			return err
+		}
+	}
+	return s.removeOld()
+}
+
+// syncDir makes a rename in a directory durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
+
+// appendManifest adds a closed file to the manifest and syncs it
+func (s *Sink) appendManifest(entry ManifestEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.config.Dir, s.config.Manifest)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync manifest: %w", err)
+	}
+	return f.Close()
+}
+
+// removeOld removes the closed files of the prefix beyond MaxFiles or older than MaxAge. Files
+// left by earlier runs are included, their names sort by opening time.
+func (s *Sink) removeOld() error {
+	if s.config.MaxFiles <= 0 && s.config.MaxAge <= 0 {
+		return nil
+	}
+	pattern := filepath.Join(s.config.Dir, s.config.Prefix+"-*"+s.extension())
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	now := s.now()
+	for i, path := range files {
+		remove := s.config.MaxFiles > 0 && len(files)-i > s.config.MaxFiles
+		if !remove && s.config.MaxAge > 0 {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			remove = now.Sub(info.ModTime()) > s.config.MaxAge
+		}
+		if remove {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove old file: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// FilesWritten returns the number of closed files
+func (s *Sink) FilesWritten() int {
+	return s.closed
+}
+
+// Close completes the current file
+func (s *Sink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.closeFile()
+}
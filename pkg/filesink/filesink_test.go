@@ -0,0 +1,275 @@
+package filesink
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// readAll returns the uncompressed content of a closed file
+func readAll(t *testing.T, path string) []byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		if r, err = gzip.NewReader(f); err != nil {
+			t.Fatal(err)
+		}
+	case strings.HasSuffix(path, ".zst"):
+		dec, err := zstd.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dec.Close()
+		r = dec
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// readFile returns the lines of a closed file
+func readFile(t *testing.T, path string) []string {
+	t.Helper()
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(readAll(t, path)))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+// readManifest returns the entries of the manifest
+func readManifest(t *testing.T, path string) []ManifestEntry {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []ManifestEntry
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var entry ManifestEntry
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSink(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 59, 58, 0, time.UTC)
+	tests := []struct {
+		name   string
+		config Config
+		// steps holds a record to write or, as a duration, a step of the clock
+		steps     []interface{}
+		wantFiles map[string][]string
+	}{
+		{
+			name:   "Gzip Size Rotation",
+			config: Config{Compression: Gzip, MaxBytes: 20},
+			steps:  []interface{}{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`, `{"n":5}`},
+			wantFiles: map[string][]string{
+				"gtp-20240501T105958Z-0001.ndjson.gz": {`{"n":1}`, `{"n":2}`, `{"n":3}`},
+				"gtp-20240501T105958Z-0002.ndjson.gz": {`{"n":4}`, `{"n":5}`},
+			},
+		},
+		{
+			name:   "Zstd Time Rotation",
+			config: Config{Compression: Zstd, Interval: time.Hour},
+			steps:  []interface{}{`{"n":1}`, time.Second, `{"n":2}`, time.Second, `{"n":3}`, time.Hour},
+			wantFiles: map[string][]string{
+				"gtp-20240501T105958Z-0001.ndjson.zst": {`{"n":1}`, `{"n":2}`},
+				"gtp-20240501T110000Z-0002.ndjson.zst": {`{"n":3}`},
+			},
+		},
+		{
+			name:   "Retention by Count",
+			config: Config{MaxBytes: 1, MaxFiles: 2},
+			steps:  []interface{}{`{"n":1}`, `{"n":2}`, `{"n":3}`},
+			wantFiles: map[string][]string{
+				"gtp-20240501T105958Z-0002.ndjson": {`{"n":2}`},
+				"gtp-20240501T105958Z-0003.ndjson": {`{"n":3}`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			config := tt.config
+			config.Dir, config.Prefix, config.Manifest = dir, "gtp", "manifest.ndjson"
+			sink, err := New(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := start
+			sink.now = func() time.Time { return now }
+
+			for _, step := range tt.steps {
+				switch step := step.(type) {
+				case string:
+					if err := sink.Write([]byte(step)); err != nil {
+						t.Fatal(err)
+					}
+				case time.Duration:
+					now = now.Add(step)
+					if err := sink.Rotate(); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if err := sink.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string][]string{}
+			matches, _ := filepath.Glob(filepath.Join(dir, "gtp-*"))
+			for _, path := range matches {
+				got[filepath.Base(path)] = readFile(t, path)
+			}
+			if !reflect.DeepEqual(got, tt.wantFiles) {
+				t.Errorf("files = %v, want %v", got, tt.wantFiles)
+			}
+
+			entries := readManifest(t, filepath.Join(dir, "manifest.ndjson"))
+			if len(entries) != sink.FilesWritten() {
+				t.Fatalf("%d manifest entries for %d files", len(entries), sink.FilesWritten())
+			}
+			var records int64
+			for _, entry := range entries {
+				records += entry.Records
+				if lines, ok := tt.wantFiles[entry.File]; ok {
+					info, err := os.Stat(filepath.Join(dir, entry.File))
+					if err != nil || entry.Records != int64(len(lines)) || entry.Bytes != info.Size() {
+						t.Errorf("manifest entry %+v does not match the file", entry)
+					}
+				}
+			}
+			if want := countRecords(tt.steps); records != want {
+				t.Errorf("manifest counts %d records, want %d", records, want)
+			}
+		})
+	}
+}
+
+func countRecords(steps []interface{}) int64 {
+	var n int64
+	for _, step := range steps {
+		if _, ok := step.(string); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func TestProtobufFormat(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := New(Config{Dir: dir, Prefix: "gtp", Format: Protobuf, Compression: Gzip, MaxFiles: 1, Manifest: "manifest.ndjson"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.now = func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) }
+	records := [][]byte{{0x08, 0x01, 0x0a}, bytes.Repeat([]byte{0x12}, 200)}
+	for _, record := range records {
+		if err := sink.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "gtp-20240501T000000Z-0001.pb.gz")
+	data := readAll(t, path)
+	var got [][]byte
+	for len(data) > 0 {
+		size, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < size {
+			t.Fatalf("invalid length prefix in %x", data)
+		}
+		got = append(got, data[n:n+int(size)])
+		data = data[n+int(size):]
+	}
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("records = %x, want %x", got, records)
+	}
+	if entries := readManifest(t, filepath.Join(dir, "manifest.ndjson")); len(entries) != 1 || entries[0].File != filepath.Base(path) || entries[0].Records != 2 {
+		t.Errorf("manifest = %+v", entries)
+	}
+}
+
+func TestRetentionByAge(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := filepath.Join(dir, "gtp-20240101T000000Z-0001.ndjson")
+	recent := filepath.Join(dir, "gtp-20240102T000000Z-0001.ndjson")
+	other := filepath.Join(dir, "other-20240101T000000Z-0001.ndjson")
+	for _, path := range []string{old, recent, other} {
+		if err := os.WriteFile(path, []byte("{}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-48*time.Hour), now.Add(-48*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(recent, now.Add(-time.Hour), now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := New(Config{Dir: dir, Prefix: "gtp", MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write([]byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	if len(names) != 3 || names[0] != "gtp-20240102T000000Z-0001.ndjson" || names[2] != "other-20240101T000000Z-0001.ndjson" {
+		t.Errorf("files after retention = %v", names)
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	for name, want := range map[string]Compression{"none": None, "GZIP": Gzip, "zstd": Zstd} {
+		if got, err := ParseCompression(name); err != nil || got != want {
+			t.Errorf("ParseCompression(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := ParseCompression("lz4"); err == nil {
+		t.Error("ParseCompression(lz4) did not fail")
+	}
+}
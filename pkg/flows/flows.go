@@ -0,0 +1,228 @@
+package flows
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/vagabundor/gtp2json/pkg/gtpu"
+)
+
+// Key identifies a flow by the tunnel endpoint it was sent to and the inner 5-tuple
+type Key struct {
+	TunnelDst string
+	TEID      uint32
+	SrcIP     string
+	DstIP     string
+	SrcPort   uint16
+	DstPort   uint16
+	Protocol  uint8
+}
+
+// Record holds the counters of a single G-PDU flow
+type Record struct {
+	RecordType string    `json:"recordType"`
+	TunnelSrc  string    `json:"tunnelSrc"`
+	TunnelDst  string    `json:"tunnelDst"`
+	TEID       uint32    `json:"teid"`
+	QFI        *uint8    `json:"qfi,omitempty"`
+	Direction  string    `json:"direction,omitempty"`
+	SrcIP      string    `json:"srcIP"`
+	DstIP      string    `json:"dstIP"`
+	SrcPort    uint16    `json:"srcPort"`
+	DstPort    uint16    `json:"dstPort"`
+	Protocol   uint8     `json:"protocol"`
+	Bytes      uint64    `json:"bytes"`
+	Packets    uint64    `json:"packets"`
+	FirstSeen  time.Time `json:"firstSeen"`
+	LastSeen   time.Time `json:"lastSeen"`
+}
+
+// Aggregator accumulates G-PDUs into per-TEID flow records and expires them after
+// the idle or active timeout, like a NetFlow cache does
+type Aggregator struct {
+	mu            sync.Mutex
+	flows         map[Key]*Record
+	activeTimeout time.Duration
+	idleTimeout   time.Duration
+	maxFlows      int
+
+	// The clock follows packet timestamps so that pcap files are aggregated in capture time,
+	// it keeps running in wall time while no packets arrive
+	lastPacket time.Time
+	lastWall   time.Time
+
+	dropped atomic.Uint64
+}
+
+// NewAggregator creates an Aggregator holding at most maxFlows flows, 0 means unlimited
+func NewAggregator(activeTimeout, idleTimeout time.Duration, maxFlows int) *Aggregator {
+	return &Aggregator{
+		flows:         make(map[Key]*Record),
+		activeTimeout: activeTimeout,
+		idleTimeout:   idleTimeout,
+		maxFlows:      maxFlows,
+	}
+}
+
+// Add accounts a packet if it is a G-PDU and reports whether it was one
+func (a *Aggregator) Add(packet gopacket.Packet) bool {
+	gtpLayer, ok := packet.Layer(gtpu.LayerTypeGTPU).(*gtpu.GTPU)
+	if !ok || !gtpLayer.IsGPDU() {
+		return false
+	}
+
+	key, tunnelSrc, ok := flowKey(packet, gtpLayer)
+	if !ok {
+		return true
+	}
+
+	ts := packet.Metadata().Timestamp
+	length := uint64(len(gtpLayer.Payload))
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if ts.After(a.lastPacket) {
+		a.lastPacket = ts
+		a.lastWall = time.Now()
+	}
+
+	record, exists := a.flows[key]
+	if !exists {
+		if a.maxFlows > 0 && len(a.flows) >= a.maxFlows {
+			a.dropped.Add(1)
+			return true
+		}
+		record = &Record{
+			RecordType: "gtpuFlow",
+			TunnelSrc:  tunnelSrc,
+			TunnelDst:  key.TunnelDst,
+			TEID:       key.TEID,
+			SrcIP:      key.SrcIP,
+			DstIP:      key.DstIP,
+			SrcPort:    key.SrcPort,
+			DstPort:    key.DstPort,
+			Protocol:   key.Protocol,
+			FirstSeen:  ts,
+		}
+		a.flows[key] = record
+	}
+
+	if container, ok := gtpLayer.PDUSessionContainer(); ok {
+		qfi := container.QFI
+		record.QFI = &qfi
+		record.Direction = "downlink"
+		if container.PDUType == gtpu.PDUTypeUplink {
+			record.Direction = "uplink"
+		}
+	}
+
+	record.Bytes += length
+	record.Packets++
+	if ts.After(record.LastSeen) {
+		record.LastSeen = ts
+	}
+
+	return true
+}
+
+// Now returns the current time of the aggregator clock
+func (a *Aggregator) Now() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.lastPacket.IsZero() {
+		return time.Now()
+	}
+	return a.lastPacket.Add(time.Since(a.lastWall))
+}
+
+// Expire removes and returns the flows that were idle longer than the idle timeout
+// or active longer than the active timeout at the given time
+func (a *Aggregator) Expire(now time.Time) []Record {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var expired []Record
+	for key, record := range a.flows {
+		if now.Sub(record.LastSeen) >= a.idleTimeout || now.Sub(record.FirstSeen) >= a.activeTimeout {
+			expired = append(expired, *record)
+			delete(a.flows, key)
+		}
+	}
+	return expired
+}
+
+// Flush removes and returns all flows
+func (a *Aggregator) Flush() []Record {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	records := make([]Record, 0, len(a.flows))
+	for _, record := range a.flows {
+		records = append(records, *record)
+	}
+	a.flows = make(map[Key]*Record)
+	return records
+}
+
+// Len returns the number of active flows
+func (a *Aggregator) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.flows)
+}
+
+// Dropped returns the number of packets not accounted because the flow table was full
+func (a *Aggregator) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// flowKey builds the flow key from the outer and inner layers around the GTP-U layer
+func flowKey(packet gopacket.Packet, gtpLayer *gtpu.GTPU) (Key, string, bool) {
+	key := Key{TEID: gtpLayer.TEID}
+	var tunnelSrc string
+	inner := false
+
+	for _, layer := range packet.Layers() {
+		if layer == gtpLayer {
+			inner = true
+			continue
+		}
+		switch l := layer.(type) {
+		case *layers.IPv4:
+			if inner {
+				key.SrcIP, key.DstIP, key.Protocol = l.SrcIP.String(), l.DstIP.String(), uint8(l.Protocol)
+			} else {
+				tunnelSrc, key.TunnelDst = l.SrcIP.String(), l.DstIP.String()
+			}
+		case *layers.IPv6:
+			if inner {
+				key.SrcIP, key.DstIP, key.Protocol = l.SrcIP.String(), l.DstIP.String(), uint8(l.NextHeader)
+			} else {
+				tunnelSrc, key.TunnelDst = l.SrcIP.String(), l.DstIP.String()
+			}
+		case *layers.TCP:
+			if inner {
+				key.SrcPort, key.DstPort = uint16(l.SrcPort), uint16(l.DstPort)
+			}
+		case *layers.UDP:
+			if inner {
+				key.SrcPort, key.DstPort = uint16(l.SrcPort), uint16(l.DstPort)
+			}
+		case *layers.SCTP:
+			if inner {
+				key.SrcPort, key.DstPort = uint16(l.SrcPort), uint16(l.DstPort)
+			}
+		}
+	}
+
+	// G-PDUs without a decodable inner IP packet cannot be keyed
+	if key.SrcIP == "" {
+		return Key{}, "", false
+	}
+	return key, tunnelSrc, true
+}
@@ -0,0 +1,127 @@
+package flows
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/vagabundor/gtp2json/pkg/gtpu"
+)
+
+var baseTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// buildGPDU serialises a G-PDU carrying an inner UDP datagram, optionally with a PDU Session Container
+func buildGPDU(t *testing.T, teid uint32, innerSrc string, payloadSize int, container []byte, ts time.Time) gopacket.Packet {
+	t.Helper()
+	opts := gopacket.SerializeOptions{FixLengths: true}
+
+	innerIP := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.ParseIP(innerSrc), DstIP: net.ParseIP("8.8.8.8")}
+	innerUDP := &layers.UDP{SrcPort: 40000, DstPort: 53}
+	innerUDP.SetNetworkLayerForChecksum(innerIP)
+	inner := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(inner, opts, innerIP, innerUDP, gopacket.Payload(make([]byte, payloadSize))); err != nil {
+		t.Fatal(err)
+	}
+
+	header := []byte{0x30, 0xff, 0, 0, 0, 0, 0, 0}
+	if container != nil {
+		header[0] |= 0x04
+		header = append(header, 0, 0, 0, gtpu.ExtensionHeaderPDUSessionContainer, 1)
+		header = append(header, container...)
+		header = append(header, 0)
+	}
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(header)-8+len(inner.Bytes())))
+	binary.BigEndian.PutUint32(header[4:8], teid)
+
+	outerIP := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.ParseIP("10.0.0.2"), DstIP: net.ParseIP("10.0.0.1")}
+	outerUDP := &layers.UDP{SrcPort: 2152, DstPort: 2152}
+	outer := gopacket.NewSerializeBuffer()
+	eth := &layers.Ethernet{SrcMAC: net.HardwareAddr{0, 1, 2, 3, 4, 5}, DstMAC: net.HardwareAddr{0, 1, 2, 3, 4, 6}, EthernetType: layers.EthernetTypeIPv4}
+	if err := gopacket.SerializeLayers(outer, opts, eth, outerIP, outerUDP, gopacket.Payload(append(header, inner.Bytes()...))); err != nil {
+		t.Fatal(err)
+	}
+
+	packet := gopacket.NewPacket(outer.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+	packet.Metadata().Timestamp = ts
+	return packet
+}
+
+func TestAggregator(t *testing.T) {
+	gtpu.Enable()
+
+	a := NewAggregator(time.Minute, 10*time.Second, 0)
+	packets := []gopacket.Packet{
+		buildGPDU(t, 0x100, "192.168.1.1", 100, nil, baseTime),
+		buildGPDU(t, 0x100, "192.168.1.1", 50, nil, baseTime.Add(2*time.Second)),
+		buildGPDU(t, 0x200, "192.168.1.2", 10, []byte{0x10, 0x09}, baseTime.Add(3*time.Second)),
+	}
+	for _, packet := range packets {
+		if !a.Add(packet) {
+			t.Fatal("G-PDU not accounted")
+		}
+	}
+
+	if got := a.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+
+	// Only the first flow has been idle for 10 seconds
+	expired := a.Expire(baseTime.Add(12 * time.Second))
+	if len(expired) != 1 {
+		t.Fatalf("Expire() returned %d records, want 1", len(expired))
+	}
+	record := expired[0]
+	// Inner IPv4 + UDP headers are 28 bytes
+	if record.TEID != 0x100 || record.Packets != 2 || record.Bytes != 100+50+2*28 {
+		t.Errorf("unexpected record counters: %+v", record)
+	}
+	if record.SrcIP != "192.168.1.1" || record.DstIP != "8.8.8.8" || record.SrcPort != 40000 || record.DstPort != 53 || record.Protocol != 17 {
+		t.Errorf("unexpected record 5-tuple: %+v", record)
+	}
+	if record.TunnelSrc != "10.0.0.2" || record.TunnelDst != "10.0.0.1" {
+		t.Errorf("unexpected tunnel endpoints: %+v", record)
+	}
+	if !record.FirstSeen.Equal(baseTime) || !record.LastSeen.Equal(baseTime.Add(2*time.Second)) {
+		t.Errorf("unexpected first/last seen: %v %v", record.FirstSeen, record.LastSeen)
+	}
+
+	flushed := a.Flush()
+	if len(flushed) != 1 {
+		t.Fatalf("Flush() returned %d records, want 1", len(flushed))
+	}
+	if flushed[0].QFI == nil || *flushed[0].QFI != 9 || flushed[0].Direction != "uplink" {
+		t.Errorf("unexpected PDU Session Container data: %+v", flushed[0])
+	}
+	if a.Len() != 0 {
+		t.Errorf("Len() after Flush() = %d, want 0", a.Len())
+	}
+}
+
+func TestAggregatorActiveTimeout(t *testing.T) {
+	gtpu.Enable()
+
+	a := NewAggregator(5*time.Second, time.Minute, 0)
+	for i := 0; i < 6; i++ {
+		a.Add(buildGPDU(t, 0x100, "192.168.1.1", 10, nil, baseTime.Add(time.Duration(i)*time.Second)))
+	}
+
+	if expired := a.Expire(baseTime.Add(5 * time.Second)); len(expired) != 1 || expired[0].Packets != 6 {
+		t.Errorf("Expire() = %+v, want a single record with 6 packets", expired)
+	}
+}
+
+func TestAggregatorMaxFlows(t *testing.T) {
+	gtpu.Enable()
+
+	a := NewAggregator(time.Minute, time.Minute, 1)
+	a.Add(buildGPDU(t, 0x100, "192.168.1.1", 10, nil, baseTime))
+	a.Add(buildGPDU(t, 0x200, "192.168.1.2", 10, nil, baseTime))
+	a.Add(buildGPDU(t, 0x100, "192.168.1.1", 10, nil, baseTime))
+
+	if a.Len() != 1 || a.Dropped() != 1 {
+		t.Errorf("Len() = %d, Dropped() = %d, want 1 and 1", a.Len(), a.Dropped())
+	}
+}
@@ -0,0 +1,241 @@
+package gtp1
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/vagabundor/gtp2json/pkg/gtp2"
+)
+
+// LayerTypeGTPv1 registers GTPv1-C layer type for use with GoPacket
+var LayerTypeGTPv1 = gopacket.RegisterLayerType(1011,
+	gopacket.LayerTypeMetadata{Name: "GTPv1", Decoder: gopacket.DecodeFunc(decodeGTPv1)})
+
+const gtpMinimumSizeInBytes int = 8
+
+// tvLengths holds the fixed content lengths of TV format IEs (3GPP TS 29.060 7.7), types below 128
+var tvLengths = map[uint8]int{
+	1:   1,  // Cause
+	2:   8,  // IMSI
+	3:   6,  // Routeing Area Identity
+	4:   4,  // TLLI
+	5:   4,  // P-TMSI
+	8:   1,  // Reordering Required
+	9:   28, // Authentication Triplet
+	11:  1,  // MAP Cause
+	12:  3,  // P-TMSI Signature
+	13:  1,  // MS Validated
+	14:  1,  // Recovery
+	15:  1,  // Selection Mode
+	16:  4,  // TEID Data I
+	17:  4,  // TEID Control Plane
+	18:  5,  // TEID Data II
+	19:  1,  // Teardown Ind
+	20:  1,  // NSAPI
+	21:  1,  // RANAP Cause
+	22:  9,  // RAB Context
+	23:  1,  // Radio Priority SMS
+	24:  1,  // Radio Priority
+	25:  2,  // Packet Flow Id
+	26:  2,  // Charging Characteristics
+	27:  2,  // Trace Reference
+	28:  2,  // Trace Type
+	29:  1,  // MS Not Reachable Reason
+	126: 1,  // Packet Transfer Command
+	127: 4,  // Charging ID
+}
+
+// ieTypeExtensionHeaderTypeList is the only TLV IE with a 1-octet length (3GPP TS 29.281 8.5)
+const ieTypeExtensionHeaderTypeList = 141
+
+// IE represents an Information Element in GTPv1, either in TV or TLV format
+type IE struct {
+	Type    uint8
+	Content []byte
+}
+
+// ExtensionHeader represents a GTPv1 extension header
+type ExtensionHeader struct {
+	Type    uint8
+	Content []byte
+}
+
+// GTPv1 is the control plane protocol of the GPRS core network used on the Gn and Gp interfaces.
+// Defined in the 3GPP TS 29.060 specification
+type GTPv1 struct {
+	Version             uint8
+	ProtocolType        uint8
+	ExtensionHeaderFlag bool
+	SequenceNumberFlag  bool
+	NPDUNumberFlag      bool
+	MessageType         uint8
+	MessageLength       uint16
+	TEID                uint32
+	SequenceNumber      uint16
+	NPDUNumber          uint8
+	ExtensionHeaders    []ExtensionHeader
+	IEs                 []IE
+
+	Contents []byte
+	Payload  []byte
+}
+
+func init() {
+	// GTPv1-C shares UDP port 2123 with GTPv2, the GTPv2 decoder hands version 1 packets over to this layer
+	gtp2.RegisterVersionDecoder(1, LayerTypeGTPv1)
+}
+
+// DecodeHeader decodes the GTPv1 header and returns the index of the first byte after it.
+// The header format is shared by GTPv1-C and GTP-U.
+func (g *GTPv1) DecodeHeader(data []byte) (int, error) {
+	hLen := gtpMinimumSizeInBytes
+	dLen := len(data)
+	if dLen < hLen {
+		return 0, fmt.Errorf("GTP packet too small: %d bytes", dLen)
+	}
+	g.Version = (data[0] >> 5) & 0x07
+	g.ProtocolType = (data[0] >> 4) & 0x01
+	g.ExtensionHeaderFlag = ((data[0] >> 2) & 0x01) == 1
+	g.SequenceNumberFlag = ((data[0] >> 1) & 0x01) == 1
+	g.NPDUNumberFlag = (data[0] & 0x01) == 1
+	g.MessageType = data[1]
+	g.MessageLength = binary.BigEndian.Uint16(data[2:4])
+	g.TEID = binary.BigEndian.Uint32(data[4:8])
+
+	pLen := hLen + int(g.MessageLength)
+	if dLen < pLen {
+		return 0, fmt.Errorf("GTP packet too small: %d bytes", dLen)
+	}
+
+	cIndex := hLen
+	if g.ExtensionHeaderFlag || g.SequenceNumberFlag || g.NPDUNumberFlag {
+		// The optional fields are present as a whole if any of the flags is set
+		cIndex += 4
+		if pLen < cIndex {
+			return 0, fmt.Errorf("GTP packet too small: %d bytes", dLen)
+		}
+		if g.SequenceNumberFlag {
+			g.SequenceNumber = binary.BigEndian.Uint16(data[8:10])
+		}
+		if g.NPDUNumberFlag {
+			g.NPDUNumber = data[10]
+		}
+
+		nextType := data[11]
+		for g.ExtensionHeaderFlag && nextType != 0 {
+			if cIndex >= pLen {
+				return 0, fmt.Errorf("GTP extension header %d exceeds packet length", nextType)
+			}
+			// Extension header length is in 4-octet units and covers the length and next type octets
+			ehLen := int(data[cIndex]) * 4
+			if ehLen == 0 || cIndex+ehLen > pLen {
+				return 0, fmt.Errorf("GTP extension header %d has invalid length", nextType)
+			}
+			g.ExtensionHeaders = append(g.ExtensionHeaders, ExtensionHeader{
+				Type:    nextType,
+				Content: data[cIndex+1 : cIndex+ehLen-1],
+			})
+			nextType = data[cIndex+ehLen-1]
+			cIndex += ehLen
+		}
+	}
+
+	return cIndex, nil
+}
+
+// DecodeFromBytes analyses a byte slice and attempts to decode it as a GTPv1-C packet
+func (g *GTPv1) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	cIndex, err := g.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	end := gtpMinimumSizeInBytes + int(g.MessageLength)
+	ies, err := DecodeIEs(data[cIndex:end])
+	if err != nil {
+		return err
+	}
+	g.IEs = ies
+
+	g.Contents = data[:end]
+	g.Payload = data[end:]
+	return nil
+}
+
+// DecodeIEs splits a byte slice into GTPv1 TV and TLV Information Elements
+func DecodeIEs(data []byte) ([]IE, error) {
+	var ies []IE
+	index := 0
+
+	for index < len(data) {
+		ieType := data[index]
+
+		var ieLength, headerLength int
+		if ieType < 128 {
+			length, ok := tvLengths[ieType]
+			if !ok {
+				return nil, fmt.Errorf("unknown TV IE %d, cannot determine its length", ieType)
+			}
+			ieLength, headerLength = length, 1
+		} else if ieType == ieTypeExtensionHeaderTypeList {
+			if index+2 > len(data) {
+				return nil, fmt.Errorf("IE %d exceeds packet length", ieType)
+			}
+			ieLength, headerLength = int(data[index+1]), 2
+		} else {
+			if index+3 > len(data) {
+				return nil, fmt.Errorf("IE %d exceeds packet length", ieType)
+			}
+			ieLength, headerLength = int(binary.BigEndian.Uint16(data[index+1:index+3])), 3
+		}
+
+		if index+headerLength+ieLength > len(data) {
+			return nil, fmt.Errorf("IE %d exceeds packet length", ieType)
+		}
+		ies = append(ies, IE{
+			Type:    ieType,
+			Content: data[index+headerLength : index+headerLength+ieLength],
+		})
+		index += headerLength + ieLength
+	}
+
+	return ies, nil
+}
+
+// decodeGTPv1 is a utility function to facilitate the decoding of GTPv1 packets within GoPacket's framework
+func decodeGTPv1(data []byte, p gopacket.PacketBuilder) error {
+	gtp := &GTPv1{}
+
+	if err := gtp.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+
+	p.AddLayer(gtp)
+	return nil
+}
+
+// LayerType returns LayerTypeGTPv1
+func (g *GTPv1) LayerType() gopacket.LayerType {
+	return LayerTypeGTPv1
+}
+
+// LayerContents returns the contents of the GTPv1 layer.
+func (g *GTPv1) LayerContents() []byte {
+	return g.Contents
+}
+
+// LayerPayload returns the payload of the GTPv1 layer.
+func (g *GTPv1) LayerPayload() []byte {
+	return g.Payload
+}
+
+// CanDecode returns a set of layers that GTP objects can decode
+func (g *GTPv1) CanDecode() gopacket.LayerClass {
+	return LayerTypeGTPv1
+}
+
+// NextLayerType specifies the next layer that GoPacket should attempt to
+func (g *GTPv1) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
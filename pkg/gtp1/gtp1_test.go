@@ -0,0 +1,165 @@
+package gtp1
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// testGTPv1Packet is a Create PDP Context Request carrying IMSI, Recovery, Selection Mode,
+// TEID Data I, NSAPI, APN and GSN Address IEs
+var testGTPv1Packet = []byte{
+	0x84, 0xb5, 0xd1, 0x58, 0x1f, 0xa3, 0x84, 0xb5,
+	0x9c, 0x67, 0x9d, 0x29, 0x08, 0x00, 0x45, 0x00,
+	0x00, 0x4f, 0x00, 0x01, 0x00, 0x00, 0x40, 0x11,
+	0x00, 0x00, 0x0a, 0x00, 0x00, 0x02, 0x0a, 0x00,
+	0x00, 0x01, 0x08, 0x4b, 0x08, 0x4b, 0x00, 0x3b,
+	0x00, 0x00, 0x32, 0x10, 0x00, 0x2b, 0x00, 0x00,
+	0x00, 0x00, 0x12, 0x34, 0x00, 0x00, 0x02, 0x21,
+	0x43, 0x65, 0x87, 0x09, 0x21, 0x43, 0xf5, 0x0e,
+	0x05, 0x0f, 0xfc, 0x10, 0x00, 0x00, 0x00, 0x01,
+	0x14, 0x05, 0x83, 0x00, 0x09, 0x08, 0x69, 0x6e,
+	0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x85, 0x00,
+	0x04, 0x0a, 0x00, 0x00, 0x01,
+}
+
+func TestGTPv1Packet(t *testing.T) {
+	p := gopacket.NewPacket(testGTPv1Packet, layers.LayerTypeEthernet, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Error("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+
+	if got, ok := p.Layer(LayerTypeGTPv1).(*GTPv1); ok {
+		want := &GTPv1{
+			Version:            1,
+			ProtocolType:       1,
+			SequenceNumberFlag: true,
+			MessageType:        16,
+			MessageLength:      43,
+			TEID:               0,
+			SequenceNumber:     0x1234,
+			IEs: []IE{
+				{2, []byte{0x21, 0x43, 0x65, 0x87, 0x09, 0x21, 0x43, 0xf5}},
+				{14, []byte{0x05}},
+				{15, []byte{0xfc}},
+				{16, []byte{0x00, 0x00, 0x00, 0x01}},
+				{20, []byte{0x05}},
+				{131, []byte{0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74}},
+				{133, []byte{0x0a, 0x00, 0x00, 0x01}},
+			},
+
+			Contents: testGTPv1Packet[42:93],
+			Payload:  []uint8{},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GTP packet mismatch:\ngot  :\n%#v\n\nwant :\n%#v\n\n", got, want)
+		}
+	} else {
+		t.Error("Incorrect gtp packet")
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		wantIndex int
+		want      GTPv1
+		wantErr   bool
+	}{
+		{
+			name:      "Mandatory header only",
+			data:      []byte{0x30, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+			wantIndex: 8,
+			want:      GTPv1{Version: 1, ProtocolType: 1, MessageType: 20, TEID: 1},
+		},
+		{
+			name: "Extension header chain",
+			data: []byte{
+				0x34, 0xff, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x02,
+				0x00, 0x00, 0x00, 0x85, // next extension header: PDU Session Container
+				0x01, 0x10, 0x09, 0x00, // length 1, QFI 9, no next header
+				0xde, 0xad, 0xbe, 0xef,
+			},
+			wantIndex: 16,
+			want: GTPv1{
+				Version:             1,
+				ProtocolType:        1,
+				ExtensionHeaderFlag: true,
+				MessageType:         255,
+				MessageLength:       12,
+				TEID:                2,
+				ExtensionHeaders:    []ExtensionHeader{{Type: 0x85, Content: []byte{0x10, 0x09}}},
+			},
+		},
+		{
+			name:    "Truncated header",
+			data:    []byte{0x30, 0x14, 0x00},
+			wantErr: true,
+		},
+		{
+			name:    "Extension header with zero length",
+			data:    []byte{0x34, 0xff, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x85, 0x00, 0x00, 0x00, 0x00},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got GTPv1
+			index, err := got.DecodeHeader(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if index != tt.wantIndex {
+				t.Errorf("DecodeHeader() index = %d, want %d", index, tt.wantIndex)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeHeader() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeIEs(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []IE
+		wantErr bool
+	}{
+		{
+			name: "TV and TLV",
+			data: []byte{0x01, 0x80, 0x85, 0x00, 0x04, 0x0a, 0x00, 0x00, 0x01},
+			want: []IE{{1, []byte{0x80}}, {133, []byte{0x0a, 0x00, 0x00, 0x01}}},
+		},
+		{
+			name:    "Unknown TV type",
+			data:    []byte{0x7d, 0x00},
+			wantErr: true,
+		},
+		{
+			name:    "TLV exceeds data",
+			data:    []byte{0x85, 0x00, 0x10, 0x0a},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeIEs(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeIEs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeIEs() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
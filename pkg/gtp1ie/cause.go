@@ -0,0 +1,95 @@
+package gtp1ie
+
+import (
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+)
+
+// CauseDescriptions maps a byte to a GTPv1 cause description (3GPP TS 29.060 7.7.1)
+var CauseDescriptions = map[byte]string{
+	0:   "Request IMSI",
+	1:   "Request IMEI",
+	2:   "Request IMSI and IMEI",
+	3:   "No identity needed",
+	4:   "MS Refuses",
+	5:   "MS is not GPRS Responding",
+	6:   "Reactivation Requested",
+	7:   "PDP address inactivity timer expires",
+	8:   "Network failure",
+	9:   "QoS parameter mismatch",
+	128: "Request accepted",
+	129: "New PDP type due to network preference",
+	130: "New PDP type due to single address bearer only",
+	192: "Non-existent",
+	193: "Invalid message format",
+	194: "IMSI/IMEI not known",
+	195: "MS is GPRS Detached",
+	196: "MS is not GPRS Responding",
+	197: "MS Refuses",
+	198: "Version not supported",
+	199: "No resources available",
+	200: "Service not supported",
+	201: "Mandatory IE incorrect",
+	202: "Mandatory IE missing",
+	203: "Optional IE incorrect",
+	204: "System failure",
+	205: "Roaming restriction",
+	206: "P-TMSI Signature mismatch",
+	207: "GPRS connection suspended",
+	208: "Authentication failure",
+	209: "User authentication failed",
+	210: "Context not found",
+	211: "All dynamic PDP addresses are occupied",
+	212: "No memory is available",
+	213: "Relocation failure",
+	214: "Unknown mandatory extension header",
+	215: "Semantic error in the TFT operation",
+	216: "Syntactic error in the TFT operation",
+	217: "Semantic errors in packet filter(s)",
+	218: "Syntactic errors in packet filter(s)",
+	219: "Missing or unknown APN",
+	220: "Unknown PDP address or PDP type",
+	221: "PDP context without TFT already activated",
+	222: "APN access denied – no subscription",
+	223: "APN Restriction type incompatibility with currently active PDP Contexts",
+	224: "MS MBMS Capabilities Insufficient",
+	225: "Invalid Correlation-ID",
+	226: "MBMS Bearer Context Superseded",
+	227: "Bearer Control Mode violation",
+	228: "Collision with network initiated request",
+	229: "APN Congestion",
+	230: "Bearer handling not supported",
+	231: "Target access restricted for the subscriber",
+	232: "UE is temporarily not reachable due to power saving",
+	233: "Relocation failure due to NAS message redirection",
+}
+
+// DecodeCause decodes the GTPv1 Cause IE into the GTPv2 Cause structure, GTPv1 has no flags
+func DecodeCause(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Cause")
+	}
+
+	causeValue := data[0]
+	description, exists := CauseDescriptions[causeValue]
+	if !exists {
+		description = fmt.Sprintf("Unknown Cause (%d)", causeValue)
+	}
+
+	cause := gtp2ie.Cause{}
+
+	format := config.GetOutputFormat()
+	switch format {
+	case "numeric":
+		cause.CauseValue = causeValue
+	case "text":
+		cause.CauseValue = description
+	case "mixed":
+		cause.CauseValue = fmt.Sprintf("%s (%d)", description, causeValue)
+	default:
+		cause.CauseValue = causeValue
+	}
+
+	return cause, nil
+}
@@ -0,0 +1,77 @@
+package gtp1ie
+
+import (
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+	"net"
+)
+
+// PDPTypeNames maps PDP Type Organisation and PDP Type Number to their descriptions (3GPP TS 29.060 7.7.27)
+var PDPTypeNames = map[uint8]map[uint8]string{
+	0: { // ETSI
+		0x01: "PPP",
+		0x02: "Non-IP",
+	},
+	1: { // IETF
+		0x21: "IPv4",
+		0x57: "IPv6",
+		0x8D: "IPv4v6",
+	},
+}
+
+// EndUserAddress represents End User Address IE (3GPP TS 29.060 7.7.27)
+type EndUserAddress struct {
+	PDPTypeOrganization uint8       `json:"pdpTypeOrganization"`
+	PDPType             interface{} `json:"pdpType"`
+	IPv4                string      `json:"ipv4,omitempty"`
+	IPv6                string      `json:"ipv6,omitempty"`
+}
+
+// DecodeEndUserAddress decodes the End User Address IE from a byte slice
+func DecodeEndUserAddress(data []byte) (interface{}, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("insufficient data for End User Address")
+	}
+
+	organization := data[0] & 0x0F
+	pdpType := data[1]
+	description, exists := PDPTypeNames[organization][pdpType]
+	if !exists {
+		description = fmt.Sprintf("Unknown PDP Type (%d)", pdpType)
+	}
+
+	format := config.GetOutputFormat()
+	var pdpTypeFormatted interface{}
+	switch format {
+	case "numeric":
+		pdpTypeFormatted = pdpType
+	case "text":
+		pdpTypeFormatted = description
+	case "mixed":
+		pdpTypeFormatted = fmt.Sprintf("%s (%d)", description, pdpType)
+	default:
+		pdpTypeFormatted = pdpType
+	}
+
+	eua := EndUserAddress{
+		PDPTypeOrganization: organization,
+		PDPType:             pdpTypeFormatted,
+	}
+
+	// The address is omitted when it is requested for dynamic allocation
+	address := data[2:]
+	switch len(address) {
+	case 0:
+	case 4:
+		eua.IPv4 = net.IP(address).String()
+	case 16:
+		eua.IPv6 = net.IP(address).String()
+	case 20:
+		eua.IPv4 = net.IP(address[:4]).String()
+		eua.IPv6 = net.IP(address[4:]).String()
+	default:
+		return nil, fmt.Errorf("invalid PDP address length: %d", len(address))
+	}
+
+	return eua, nil
+}
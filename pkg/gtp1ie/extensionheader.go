@@ -0,0 +1,56 @@
+package gtp1ie
+
+import (
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+)
+
+// ExtensionHeaderTypeNames maps GTP extension header types to their descriptions (3GPP TS 29.281 5.2.1)
+var ExtensionHeaderTypeNames = map[uint8]string{
+	0x00: "No more extension headers",
+	0x03: "Long PDCP PDU Number",
+	0x20: "Service Class Indicator",
+	0x40: "UDP Port",
+	0x81: "RAN Container",
+	0x82: "Long PDCP PDU Number",
+	0x83: "Xw RAN Container",
+	0x84: "NR RAN Container",
+	0x85: "PDU Session Container",
+	0xC0: "PDCP PDU Number",
+	0xC1: "Suspend Request",
+	0xC2: "Suspend Response",
+}
+
+// FormatExtensionHeaderType returns the formatted extension header type based on the selected format
+func FormatExtensionHeaderType(headerType uint8) interface{} {
+	description, exists := ExtensionHeaderTypeNames[headerType]
+	if !exists {
+		description = fmt.Sprintf("Unknown Extension Header (%d)", headerType)
+	}
+
+	switch config.GetOutputFormat() {
+	case "numeric":
+		return headerType
+	case "text":
+		return description
+	case "mixed":
+		return fmt.Sprintf("%s (%d)", description, headerType)
+	default:
+		return headerType
+	}
+}
+
+// DecodeExtensionHeaderTypeList decodes the Extension Header Type List IE sent in Supported Extension Headers Notification,
+// the content is the list of extension header types
+func DecodeExtensionHeaderTypeList(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Extension Header Type List")
+	}
+
+	types := make([]interface{}, 0, len(data))
+	for _, headerType := range data {
+		types = append(types, FormatExtensionHeaderType(headerType))
+	}
+
+	return types, nil
+}
@@ -0,0 +1,155 @@
+package gtp1ie
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/vagabundor/gtp2json/pkg/gtp1"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+)
+
+const (
+	IETypeCause                   = 1
+	IETypeIMSI                    = 2
+	IETypeRAI                     = 3
+	IETypeReorderingRequired      = 8
+	IETypeRecovery                = 14
+	IETypeSelectionMode           = 15
+	IETypeTEIDDataI               = 16
+	IETypeTEIDControlPlane        = 17
+	IETypeTeardownInd             = 19
+	IETypeNSAPI                   = 20
+	IETypeChargingChars           = 26
+	IETypeChargingID              = 127
+	IETypeEndUserAddress          = 128
+	IETypeAPN                     = 131
+	IETypePCO                     = 132
+	IETypeGSNAddress              = 133
+	IETypeMSISDN                  = 134
+	IETypeQoSProfile              = 135
+	IETypeCommonFlags             = 148
+	IETypeRATType                 = 151
+	IETypeULI                     = 152
+	IETypeMSTimeZone              = 153
+	IETypeIMEISV                  = 154
+	IETypeExtensionHeaderTypeList = 141
+	IETypePrivateExtension        = 255
+)
+
+// ieTypeNames maps IE types to their string representations, names overlapping with GTPv2 are kept identical
+var ieTypeNames = map[uint8]string{
+	IETypeCause:                   "Cause",
+	IETypeIMSI:                    "IMSI",
+	IETypeRAI:                     "RAI",
+	IETypeReorderingRequired:      "ReorderingRequired",
+	IETypeRecovery:                "Recovery",
+	IETypeSelectionMode:           "SelectionMode",
+	IETypeTEIDDataI:               "TEIDDataI",
+	IETypeTEIDControlPlane:        "TEIDControlPlane",
+	IETypeTeardownInd:             "TeardownInd",
+	IETypeNSAPI:                   "NSAPI",
+	IETypeChargingChars:           "ChargingCharacteristics",
+	IETypeChargingID:              "ChargingID",
+	IETypeEndUserAddress:          "EndUserAddress",
+	IETypeAPN:                     "APN",
+	IETypePCO:                     "PCO",
+	IETypeGSNAddress:              "GSNAddress",
+	IETypeMSISDN:                  "MSISDN",
+	IETypeQoSProfile:              "QoSProfile",
+	IETypeCommonFlags:             "CommonFlags",
+	IETypeRATType:                 "RATType",
+	IETypeULI:                     "ULI",
+	IETypeMSTimeZone:              "MSTimeZone",
+	IETypeIMEISV:                  "MEI",
+	IETypeExtensionHeaderTypeList: "ExtensionHeaderTypeList",
+	IETypePrivateExtension:        "PrivateExtension",
+}
+
+// messageDirections maps GTPv1 messages carrying PCO to the direction of that IE. Update and Delete
+// PDP Context may also be initiated by the GGSN, the SGSN initiated direction is assumed.
+var messageDirections = map[uint8]gtp2ie.Direction{
+	16: gtp2ie.DirectionMSToNetwork, // Create PDP Context Request
+	17: gtp2ie.DirectionNetworkToMS, // Create PDP Context Response
+	18: gtp2ie.DirectionMSToNetwork, // Update PDP Context Request
+	19: gtp2ie.DirectionNetworkToMS, // Update PDP Context Response
+	20: gtp2ie.DirectionMSToNetwork, // Delete PDP Context Request
+	21: gtp2ie.DirectionNetworkToMS, // Delete PDP Context Response
+}
+
+// MessageDirection returns the direction of the PCO IE carried in the given GTPv1 message type
+func MessageDirection(messageType uint8) gtp2ie.Direction {
+	return messageDirections[messageType]
+}
+
+// ProcessIE decodes the content of a given IE based on its type
+func ProcessIE(ie gtp1.IE) (string, interface{}, error) {
+	return ProcessIEWithDirection(ie, gtp2ie.DirectionUnknown)
+}
+
+// ProcessIEWithDirection decodes the content of a given IE, interpreting PCO for the given direction
+func ProcessIEWithDirection(ie gtp1.IE, direction gtp2ie.Direction) (string, interface{}, error) {
+
+	ieName, ok := ieTypeNames[ie.Type]
+	if !ok {
+		// Unknown type encode to hex
+		return fmt.Sprintf("unknown_type_%d", ie.Type), hex.EncodeToString(ie.Content), nil
+	}
+
+	var decodeFunc func([]byte) (interface{}, error)
+	switch ie.Type {
+	case IETypeCause:
+		decodeFunc = DecodeCause
+	case IETypeIMSI, IETypeIMEISV:
+		decodeFunc = gtp2ie.DecodeBCD
+	case IETypeRAI:
+		decodeFunc = DecodeRAI
+	case IETypeReorderingRequired, IETypeTeardownInd:
+		decodeFunc = DecodeFlag
+	case IETypeRecovery:
+		decodeFunc = gtp2ie.DecodeRecovery
+	case IETypeSelectionMode:
+		decodeFunc = DecodeSelectionMode
+	case IETypeTEIDDataI, IETypeTEIDControlPlane:
+		decodeFunc = DecodeTEID
+	case IETypeNSAPI:
+		decodeFunc = DecodeNSAPI
+	case IETypeChargingChars:
+		decodeFunc = gtp2ie.DecodeChargingChars
+	case IETypeChargingID:
+		decodeFunc = DecodeChargingID
+	case IETypeEndUserAddress:
+		decodeFunc = DecodeEndUserAddress
+	case IETypeAPN:
+		decodeFunc = gtp2ie.DecodeAPN
+	case IETypePCO:
+		decodeFunc = func(data []byte) (interface{}, error) {
+			return gtp2ie.DecodePCOWithDirection(data, direction)
+		}
+	case IETypeGSNAddress:
+		decodeFunc = DecodeGSNAddress
+	case IETypeMSISDN:
+		decodeFunc = DecodeMSISDN
+	case IETypeQoSProfile:
+		decodeFunc = DecodeQoSProfile
+	case IETypeCommonFlags:
+		decodeFunc = DecodeCommonFlags
+	case IETypeRATType:
+		decodeFunc = gtp2ie.DecodeRATType
+	case IETypeULI:
+		decodeFunc = DecodeULI
+	case IETypeMSTimeZone:
+		decodeFunc = gtp2ie.DecodeUETimeZone
+	case IETypeExtensionHeaderTypeList:
+		decodeFunc = DecodeExtensionHeaderTypeList
+	case IETypePrivateExtension:
+		decodeFunc = gtp2ie.DecodePrivateExtension
+	default:
+		return ieName, hex.EncodeToString(ie.Content), nil
+	}
+
+	decodedContent, err := decodeFunc(ie.Content)
+	if err != nil {
+		return ieName, nil, fmt.Errorf("failed to decode %s: %w", ieName, err)
+	}
+
+	return ieName, decodedContent, nil
+}
@@ -0,0 +1,273 @@
+package gtp1ie
+
+import (
+	"github.com/vagabundor/gtp2json/config"
+	"github.com/vagabundor/gtp2json/pkg/gtp1"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+	"reflect"
+	"testing"
+)
+
+func TestProcessIE(t *testing.T) {
+	type args struct {
+		ie     gtp1.IE
+		format string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		want1   interface{}
+		wantErr bool
+	}{
+		{
+			name: "Test IMSI Decoding",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeIMSI, Content: []byte{0x21, 0x43, 0x65, 0x87, 0x09, 0x21, 0x43, 0xf5}},
+				format: "numeric",
+			},
+			want:  "IMSI",
+			want1: "123456789012345",
+		},
+		{
+			name: "Test MSISDN Decoding",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeMSISDN, Content: []byte{0x91, 0x21, 0x43, 0x65, 0x87}},
+				format: "numeric",
+			},
+			want:  "MSISDN",
+			want1: "12345678",
+		},
+		{
+			name: "Test IMEISV Decoding",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeIMEISV, Content: []byte{0x68, 0x65, 0x82, 0x50, 0x03, 0x91, 0x48, 0x65}},
+				format: "numeric",
+			},
+			want:  "MEI",
+			want1: "8656280530198456",
+		},
+		{
+			name: "Test APN Decoding",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeAPN, Content: []byte{0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74}},
+				format: "numeric",
+			},
+			want:  "APN",
+			want1: "internet",
+		},
+		{
+			name: "Test Cause Numeric",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeCause, Content: []byte{0x80}},
+				format: "numeric",
+			},
+			want:  "Cause",
+			want1: gtp2ie.Cause{CauseValue: uint8(128)},
+		},
+		{
+			name: "Test Cause Mixed",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeCause, Content: []byte{0xdb}},
+				format: "mixed",
+			},
+			want:  "Cause",
+			want1: gtp2ie.Cause{CauseValue: "Missing or unknown APN (219)"},
+		},
+		{
+			name: "Test ULI CGI",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeULI, Content: []byte{0x00, 0x21, 0xf3, 0x54, 0x00, 0x01, 0x00, 0x02}},
+				format: "numeric",
+			},
+			want: "ULI",
+			want1: gtp2ie.ULI{
+				CGI: &gtp2ie.CGI{MCCMNC: gtp2ie.MCCMNC{MCC: "123", MNC: "45"}, LAC: "1", CI: "2"},
+			},
+		},
+		{
+			name: "Test ULI RAI",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeULI, Content: []byte{0x02, 0x21, 0xf3, 0x54, 0x00, 0x01, 0x05, 0xff}},
+				format: "numeric",
+			},
+			want: "ULI",
+			want1: gtp2ie.ULI{
+				RAI: &gtp2ie.RAI{MCCMNC: gtp2ie.MCCMNC{MCC: "123", MNC: "45"}, LAC: "1", RAC: "5"},
+			},
+		},
+		{
+			name: "Test ULI Unknown Location Type",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeULI, Content: []byte{0x05, 0x21, 0xf3, 0x54, 0x00, 0x01, 0x05, 0xff}},
+				format: "numeric",
+			},
+			want:    "ULI",
+			wantErr: true,
+		},
+		{
+			name: "Test RAI",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeRAI, Content: []byte{0x21, 0xf3, 0x54, 0x00, 0x01, 0x05}},
+				format: "numeric",
+			},
+			want:  "RAI",
+			want1: gtp2ie.RAI{MCCMNC: gtp2ie.MCCMNC{MCC: "123", MNC: "45"}, LAC: "1", RAC: "5"},
+		},
+		{
+			name: "Test End User Address IPv4",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeEndUserAddress, Content: []byte{0xf1, 0x21, 0x0a, 0x00, 0x00, 0x01}},
+				format: "text",
+			},
+			want:  "EndUserAddress",
+			want1: EndUserAddress{PDPTypeOrganization: 1, PDPType: "IPv4", IPv4: "10.0.0.1"},
+		},
+		{
+			name: "Test End User Address Dynamic IPv4v6",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeEndUserAddress, Content: []byte{0xf1, 0x8d}},
+				format: "numeric",
+			},
+			want:  "EndUserAddress",
+			want1: EndUserAddress{PDPTypeOrganization: 1, PDPType: uint8(0x8d)},
+		},
+		{
+			name: "Test End User Address Invalid Length",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeEndUserAddress, Content: []byte{0xf1, 0x21, 0x0a, 0x00}},
+				format: "numeric",
+			},
+			want:    "EndUserAddress",
+			wantErr: true,
+		},
+		{
+			name: "Test GSN Address",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeGSNAddress, Content: []byte{0xc0, 0xa8, 0x00, 0x01}},
+				format: "numeric",
+			},
+			want:  "GSNAddress",
+			want1: "192.168.0.1",
+		},
+		{
+			name: "Test TEID Data I",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeTEIDDataI, Content: []byte{0x00, 0x00, 0x10, 0x01}},
+				format: "numeric",
+			},
+			want:  "TEIDDataI",
+			want1: "00001001",
+		},
+		{
+			name: "Test Charging ID",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeChargingID, Content: []byte{0x00, 0x01, 0x00, 0x00}},
+				format: "numeric",
+			},
+			want:  "ChargingID",
+			want1: uint32(65536),
+		},
+		{
+			name: "Test Selection Mode With Spare Bits",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeSelectionMode, Content: []byte{0xfd}},
+				format: "numeric",
+			},
+			want:  "SelectionMode",
+			want1: uint8(1),
+		},
+		{
+			name: "Test NSAPI",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeNSAPI, Content: []byte{0x05}},
+				format: "numeric",
+			},
+			want:  "NSAPI",
+			want1: uint8(5),
+		},
+		{
+			name: "Test Teardown Ind",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeTeardownInd, Content: []byte{0xff}},
+				format: "numeric",
+			},
+			want:  "TeardownInd",
+			want1: true,
+		},
+		{
+			name: "Test QoS Profile",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeQoSProfile, Content: []byte{0x03, 0x23, 0x92, 0x1f}},
+				format: "numeric",
+			},
+			want:  "QoSProfile",
+			want1: QoSProfile{ARP: 3, Profile: "23921f"},
+		},
+		{
+			name: "Test Common Flags",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeCommonFlags, Content: []byte{0x81}},
+				format: "numeric",
+			},
+			want:  "CommonFlags",
+			want1: CommonFlags{DualAddressBearer: true, ProhibitPayloadCompression: true},
+		},
+		{
+			name: "Test RAT Type",
+			args: args{
+				ie:     gtp1.IE{Type: IETypeRATType, Content: []byte{0x01}},
+				format: "text",
+			},
+			want:  "RATType",
+			want1: "UTRAN",
+		},
+		{
+			name: "Test Unknown IE Type",
+			args: args{
+				ie:     gtp1.IE{Type: 200, Content: []byte{0x01, 0x02}},
+				format: "numeric",
+			},
+			want:  "unknown_type_200",
+			want1: "0102",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.SetOutputFormat(tt.args.format)
+			got, got1, err := ProcessIE(tt.args.ie)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ProcessIE() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ProcessIE() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(got1, tt.want1) {
+				t.Errorf("ProcessIE() got1 = %#v, want %#v", got1, tt.want1)
+			}
+		})
+	}
+}
+
+func TestMessageDirection(t *testing.T) {
+	tests := []struct {
+		messageType uint8
+		want        gtp2ie.Direction
+	}{
+		{16, gtp2ie.DirectionMSToNetwork},
+		{17, gtp2ie.DirectionNetworkToMS},
+		{18, gtp2ie.DirectionMSToNetwork},
+		{19, gtp2ie.DirectionNetworkToMS},
+		{20, gtp2ie.DirectionMSToNetwork},
+		{21, gtp2ie.DirectionNetworkToMS},
+		{1, gtp2ie.DirectionUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := MessageDirection(tt.messageType); got != tt.want {
+			t.Errorf("MessageDirection(%d) = %v, want %v", tt.messageType, got, tt.want)
+		}
+	}
+}
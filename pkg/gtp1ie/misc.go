@@ -0,0 +1,111 @@
+package gtp1ie
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+	"net"
+)
+
+// QoSProfile represents Quality of Service Profile IE (3GPP TS 29.060 7.7.34)
+type QoSProfile struct {
+	ARP     uint8  `json:"ARP"` // Allocation/Retention Priority
+	Profile string `json:"Profile"`
+}
+
+// CommonFlags represents Common Flags IE (3GPP TS 29.060 7.7.48)
+type CommonFlags struct {
+	DualAddressBearer          bool `json:"DualAddressBearer"`          // Dual Address Bearer Flag
+	UpgradeQoSSupported        bool `json:"UpgradeQoSSupported"`        // Upgrade QoS Supported
+	NRSN                       bool `json:"NRSN"`                       // Network Request Support Network
+	NoQoSNegotiation           bool `json:"NoQoSNegotiation"`           // No QoS negotiation
+	MBMSCountingInformation    bool `json:"MBMSCountingInformation"`    // MBMS Counting Information
+	RANProceduresReady         bool `json:"RANProceduresReady"`         // RAN Procedures Ready
+	MBMSServiceType            bool `json:"MBMSServiceType"`            // MBMS Service Type
+	ProhibitPayloadCompression bool `json:"ProhibitPayloadCompression"` // Prohibit Payload Compression
+}
+
+// DecodeFlag decodes single bit TV IEs such as Reordering Required and Teardown Ind
+func DecodeFlag(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for flag")
+	}
+	return data[0]&0x01 != 0, nil
+}
+
+// DecodeSelectionMode decodes the GTPv1 Selection Mode, whose spare bits are set to 1
+func DecodeSelectionMode(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return "", fmt.Errorf("insufficient data for Selection Mode")
+	}
+	return gtp2ie.DecodeSelectionMode([]byte{data[0] & 0x03})
+}
+
+// DecodeTEID decodes TEID Data I and TEID Control Plane IEs the same way GTPv2 F-TEID keys are shown
+func DecodeTEID(data []byte) (interface{}, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("insufficient data for TEID: expected 4 bytes, got %d", len(data))
+	}
+	return hex.EncodeToString(data[:4]), nil
+}
+
+// DecodeNSAPI decodes the NSAPI IE
+func DecodeNSAPI(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for NSAPI")
+	}
+	return data[0] & 0x0F, nil
+}
+
+// DecodeChargingID decodes the Charging ID IE
+func DecodeChargingID(data []byte) (interface{}, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("insufficient data for Charging ID: expected 4 bytes, got %d", len(data))
+	}
+	return binary.BigEndian.Uint32(data[:4]), nil
+}
+
+// DecodeGSNAddress decodes the GSN Address IE holding an IPv4 or IPv6 address
+func DecodeGSNAddress(data []byte) (interface{}, error) {
+	if len(data) != 4 && len(data) != 16 {
+		return nil, fmt.Errorf("invalid GSN Address length: %d", len(data))
+	}
+	return net.IP(data).String(), nil
+}
+
+// DecodeMSISDN decodes the MSISDN IE, coded as a MAP AddressString with a leading nature of address octet
+func DecodeMSISDN(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for MSISDN")
+	}
+	return gtp2ie.DecodeBCD(data[1:])
+}
+
+// DecodeQoSProfile decodes the Quality of Service Profile IE
+func DecodeQoSProfile(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for QoS Profile")
+	}
+	return QoSProfile{
+		ARP:     data[0],
+		Profile: hex.EncodeToString(data[1:]),
+	}, nil
+}
+
+// DecodeCommonFlags decodes the bits of the Common Flags IE
+func DecodeCommonFlags(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Common Flags")
+	}
+	return CommonFlags{
+		DualAddressBearer:          data[0]&0x80 != 0,
+		UpgradeQoSSupported:        data[0]&0x40 != 0,
+		NRSN:                       data[0]&0x20 != 0,
+		NoQoSNegotiation:           data[0]&0x10 != 0,
+		MBMSCountingInformation:    data[0]&0x08 != 0,
+		RANProceduresReady:         data[0]&0x04 != 0,
+		MBMSServiceType:            data[0]&0x02 != 0,
+		ProhibitPayloadCompression: data[0]&0x01 != 0,
+	}, nil
+}
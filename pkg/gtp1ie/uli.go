@@ -0,0 +1,51 @@
+package gtp1ie
+
+import (
+	"fmt"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+)
+
+// DecodeULI decodes the GTPv1 User Location Information (3GPP TS 29.060 7.7.51) into the GTPv2 ULI structure
+func DecodeULI(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return gtp2ie.ULI{}, fmt.Errorf("not enough data to decode ULI")
+	}
+
+	uli := gtp2ie.ULI{}
+	location := data[1:]
+
+	switch data[0] {
+	case 0:
+		cgi, _, err := gtp2ie.DecodeCGI(location)
+		if err != nil {
+			return gtp2ie.ULI{}, fmt.Errorf("error decoding CGI: %v", err)
+		}
+		uli.CGI = &cgi
+	case 1:
+		sai, _, err := gtp2ie.DecodeSAI(location)
+		if err != nil {
+			return gtp2ie.ULI{}, fmt.Errorf("error decoding SAI: %v", err)
+		}
+		uli.SAI = &sai
+	case 2:
+		// RAC occupies a single octet, the following octet is spare
+		rai, _, err := gtp2ie.DecodeShortRAI(location)
+		if err != nil {
+			return gtp2ie.ULI{}, fmt.Errorf("error decoding RAI: %v", err)
+		}
+		uli.RAI = &rai
+	default:
+		return gtp2ie.ULI{}, fmt.Errorf("unknown geographic location type: %d", data[0])
+	}
+
+	return uli, nil
+}
+
+// DecodeRAI decodes the Routeing Area Identity IE
+func DecodeRAI(data []byte) (interface{}, error) {
+	rai, _, err := gtp2ie.DecodeShortRAI(data)
+	if err != nil {
+		return nil, err
+	}
+	return rai, nil
+}
@@ -39,10 +39,66 @@ type GTPv2 struct {
 	Payload  []byte
 }
 
+// versionDecoders holds decoders for other GTP versions sharing the GTPv2 port
+var versionDecoders = map[uint8]gopacket.Decoder{}
+
+// RegisterVersionDecoder registers a decoder for packets on the GTPv2 port carrying another GTP version.
+// It is meant to be called from init functions, before any packet is decoded.
+func RegisterVersionDecoder(version uint8, decoder gopacket.Decoder) {
+	versionDecoders[version] = decoder
+}
+
 func init() {
 	// Registers GTPv2 to be identified and processed over its standard UDP port, 2123
-	udpPort := layers.UDPPort(2123)
-	layers.RegisterUDPPortLayerType(udpPort, LayerTypeGTPv2)
+	RegisterPort(2123)
+}
+
+// RegisterPort registers an additional UDP port on which GTPv2 packets are decoded.
+// It must be called before any packet is decoded.
+func RegisterPort(port uint16) {
+	layers.RegisterUDPPortLayerType(layers.UDPPort(port), LayerTypeGTPv2)
+}
+
+// IsGTPv2 reports whether a UDP payload looks like a GTPv2 message. The version, the spare bits,
+// the message length and the IE chain are validated, the IEs have to end exactly at the message end.
+// With the piggybacking flag set the rest of the payload has to be a GTPv2 message as well.
+func IsGTPv2(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	if (data[0]>>5)&0x07 != 2 || data[0]&0x03 != 0 || data[1] == 0 {
+		return false
+	}
+
+	pLen := 4 + int(binary.BigEndian.Uint16(data[2:4]))
+	if (data[0]>>4)&0x01 == 1 {
+		if pLen >= len(data) || !IsGTPv2(data[pLen:]) {
+			return false
+		}
+	} else if pLen != len(data) {
+		return false
+	}
+
+	index := 8
+	if (data[0]>>3)&0x01 == 1 {
+		index += 4
+	}
+	if index > pLen {
+		return false
+	}
+
+	for index < pLen {
+		if index+4 > pLen {
+			return false
+		}
+		// The upper half of the fourth octet is spare and has to be zero
+		if data[index] == 0 || data[index+3]&0xf0 != 0 {
+			return false
+		}
+		index += 4 + int(binary.BigEndian.Uint16(data[index+1:index+3]))
+	}
+
+	return index == pLen
 }
 
 // DecodeFromBytes analyses a byte slice and attempts to decode it as a GTPv2 packet
@@ -96,8 +152,19 @@ func (g *GTPv2) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
 
 }
 
-// decodeGTPv2 is a utility function to facilitate the decoding of GTPv2 packets within GoPacket's framework
+// decodeGTPv2 is a utility function to facilitate the decoding of GTPv2 packets within GoPacket's framework.
+// Packets of other GTP versions are dispatched on the version bits to their registered decoders.
 func decodeGTPv2(data []byte, p gopacket.PacketBuilder) error {
+	if len(data) > 0 {
+		if version := (data[0] >> 5) & 0x07; version != 2 {
+			decoder, ok := versionDecoders[version]
+			if !ok {
+				return fmt.Errorf("unsupported GTP version %d", version)
+			}
+			return decoder.Decode(data, p)
+		}
+	}
+
 	gtp := &GTPv2{}
 
 	if err := gtp.DecodeFromBytes(data, p); err != nil {
@@ -57,3 +57,90 @@ func TestGTPv2Packet(t *testing.T) {
 		t.Error("Incorrect gtp packet")
 	}
 }
+
+func TestIsGTPv2(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{
+			name: "Echo Request Without TEID",
+			data: []byte{0x40, 0x01, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05},
+			want: true,
+		},
+		{
+			name: "Message With TEID",
+			data: testGTPv2Packet[42:],
+			want: true,
+		},
+		{
+			name: "Wrong Version",
+			data: []byte{0x20, 0x01, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05},
+		},
+		{
+			name: "Length Mismatch",
+			data: []byte{0x40, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05},
+		},
+		{
+			name: "IE Exceeds Message",
+			data: []byte{0x40, 0x01, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x02, 0x00, 0x05},
+		},
+		{
+			name: "IE Spare Bits Set",
+			data: []byte{0x40, 0x01, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x10, 0x05},
+		},
+		{
+			name: "Piggybacked Message",
+			data: []byte{
+				0x50, 0x21, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05,
+				0x40, 0x01, 0x00, 0x09, 0x00, 0x00, 0x02, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05,
+			},
+			want: true,
+		},
+		{
+			name: "Piggybacking Flag Without Message",
+			data: []byte{0x50, 0x21, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05},
+		},
+		{
+			name: "Invalid Piggybacked Message",
+			data: []byte{
+				0x50, 0x21, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05,
+				0x40, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x02, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05,
+			},
+		},
+		{
+			name: "Trailing Data Without Piggybacking Flag",
+			data: []byte{
+				0x40, 0x21, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05,
+				0x40, 0x01, 0x00, 0x09, 0x00, 0x00, 0x02, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05,
+			},
+		},
+		{
+			name: "Too Short",
+			data: []byte{0x40, 0x01, 0x00, 0x00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsGTPv2(tt.data); got != tt.want {
+				t.Errorf("IsGTPv2() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterPort(t *testing.T) {
+	RegisterPort(2124)
+
+	data := append([]byte{}, testGTPv2Packet...)
+	// Move the destination port from 2123 to 2124
+	data[36], data[37] = 0x08, 0x4c
+	data[34], data[35] = 0x88, 0x30
+
+	p := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	if p.Layer(LayerTypeGTPv2) == nil {
+		t.Error("GTPv2 layer not decoded on registered port")
+	}
+}
@@ -0,0 +1,34 @@
+package gtp2ie
+
+import (
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+)
+
+// ChangeReportingActionNames maps Change Reporting Action values to their descriptions (3GPP TS 29.274 8.61)
+var ChangeReportingActionNames = map[uint8]string{
+	0: "Stop Reporting",
+	1: "Start Reporting CGI/SAI",
+	2: "Start Reporting RAI",
+	3: "Start Reporting TAI",
+	4: "Start Reporting ECGI",
+	5: "Start Reporting CGI/SAI and RAI",
+	6: "Start Reporting TAI and ECGI",
+	7: "Start Reporting Macro eNodeB ID and Extended Macro eNodeB ID",
+	8: "Start Reporting TAI, Macro eNodeB ID and Extended Macro eNodeB ID",
+}
+
+// DecodeChangeReportingAction decodes the Change Reporting Action IE from a byte slice
+func DecodeChangeReportingAction(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Change Reporting Action")
+	}
+
+	action := data[0]
+	description, exists := ChangeReportingActionNames[action]
+	if !exists {
+		description = fmt.Sprintf("Unknown Action (%d)", action)
+	}
+
+	return formatDescription(description, action, config.GetOutputFormat()), nil
+}
@@ -0,0 +1,71 @@
+package gtp2ie
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeIdentifier represents Node Identifier IE (3GPP TS 29.274 8.107)
+type NodeIdentifier struct {
+	NodeName   string `json:"NodeName"`
+	NodeRealm  string `json:"NodeRealm"`
+	NodeNumber string `json:"NodeNumber,omitempty"`
+}
+
+// DecodeFQDN decodes the FQDN IE (3GPP TS 29.274 8.66) using the APN label encoding
+func DecodeFQDN(data []byte) (interface{}, error) {
+	fqdn, err := DecodeAPN(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid FQDN: %w", err)
+	}
+
+	// Some nodes terminate the name with the zero-length root label
+	return strings.TrimSuffix(fqdn.(string), "."), nil
+}
+
+// DecodeNodeIdentifier decodes the Node Identifier IE from a byte slice
+func DecodeNodeIdentifier(data []byte) (interface{}, error) {
+	nodeName, index, err := decodeLengthPrefixedString(data, 0, "Node Name")
+	if err != nil {
+		return nil, err
+	}
+
+	nodeRealm, index, err := decodeLengthPrefixedString(data, index, "Node Realm")
+	if err != nil {
+		return nil, err
+	}
+
+	nodeID := NodeIdentifier{
+		NodeName:  nodeName,
+		NodeRealm: nodeRealm,
+	}
+
+	// Node Number is optional and carried as a digit count followed by BCD digits
+	if index < len(data) {
+		digits := int(data[index])
+		index++
+		numberLength := (digits + 1) / 2
+		if index+numberLength > len(data) {
+			return nil, fmt.Errorf("insufficient data for Node Number: expected %d bytes, got %d", numberLength, len(data)-index)
+		}
+		number, _ := DecodeBCD(data[index : index+numberLength])
+		nodeID.NodeNumber = number.(string)
+	}
+
+	return nodeID, nil
+}
+
+// decodeLengthPrefixedString reads a single length octet followed by that many bytes of text
+func decodeLengthPrefixedString(data []byte, index int, name string) (string, int, error) {
+	if index >= len(data) {
+		return "", 0, fmt.Errorf("insufficient data for %s length", name)
+	}
+
+	length := int(data[index])
+	index++
+	if index+length > len(data) {
+		return "", 0, fmt.Errorf("invalid %s length %d: exceeds remaining data %d", name, length, len(data)-index)
+	}
+
+	return string(data[index : index+length]), index + length, nil
+}
@@ -7,62 +7,87 @@ import (
 )
 
 const (
-	IETypeIMSI           = 1
-	IETypeMSISDN         = 76
-	IETypeMEI            = 75
-	IETypeFTEID          = 87
-	IETypeULI            = 86
-	IETypeServingNet     = 83
-	IETypeRATType        = 82
-	IETypeIndication     = 77
-	IETypeAPN            = 71
-	IETypeSelectionMode  = 128
-	IETypePDNType        = 99
-	IETypePAA            = 79
-	IETypeAPNRestriction = 127
-	IETypeAMBR           = 72
-	IETypePCO            = 78
-	IETypeCause          = 2
-	IETypeEBI            = 73
-	IETypeBearerQoS      = 80
-	IETypeBearerContext  = 93
-	IETypeRecovery       = 3
-	IETypeUETimeZone     = 114
-	IETypeChargingChars  = 95
-	IETypeULITimestamp   = 170
-	IETypeEPCO           = 197
+	IETypeIMSI                  = 1
+	IETypeMSISDN                = 76
+	IETypeMEI                   = 75
+	IETypeFTEID                 = 87
+	IETypeULI                   = 86
+	IETypeServingNet            = 83
+	IETypeRATType               = 82
+	IETypeIndication            = 77
+	IETypeAPN                   = 71
+	IETypeSelectionMode         = 128
+	IETypePDNType               = 99
+	IETypePAA                   = 79
+	IETypeAPNRestriction        = 127
+	IETypeAMBR                  = 72
+	IETypePCO                   = 78
+	IETypeCause                 = 2
+	IETypeEBI                   = 73
+	IETypeBearerQoS             = 80
+	IETypeBearerContext         = 93
+	IETypeRecovery              = 3
+	IETypeUETimeZone            = 114
+	IETypeChargingChars         = 95
+	IETypeULITimestamp          = 170
+	IETypeEPCO                  = 197
+	IETypeChangeReportingAction = 131
+	IETypePRAAction             = 177
+	IETypePRAInformation        = 178
+	IETypePrivateExtension      = 255
+	IETypeFQDN                  = 136
+	IETypeNodeFeatures          = 152
+	IETypeNodeIdentifier        = 176
+	IETypeAPCO                  = 163
+	IETypeMillisecondTimeStamp  = 188
+	IETypeSecondaryRATReport    = 201
 )
 
 // ieTypeNames maps IE types to their string representations
 var ieTypeNames = map[uint8]string{
-	IETypeIMSI:           "IMSI",
-	IETypeMSISDN:         "MSISDN",
-	IETypeMEI:            "MEI",
-	IETypeFTEID:          "F-TEID",
-	IETypeULI:            "ULI",
-	IETypeServingNet:     "ServingNetwork",
-	IETypeRATType:        "RATType",
-	IETypeIndication:     "Indication",
-	IETypeAPN:            "APN",
-	IETypeSelectionMode:  "SelectionMode",
-	IETypePDNType:        "PDNType",
-	IETypePAA:            "PAA",
-	IETypeAPNRestriction: "APNRestriction",
-	IETypeAMBR:           "AMBR",
-	IETypePCO:            "PCO",
-	IETypeCause:          "Cause",
-	IETypeEBI:            "EBI",
-	IETypeBearerQoS:      "BearerQoS",
-	IETypeBearerContext:  "BearerContext",
-	IETypeRecovery:       "Recovery",
-	IETypeUETimeZone:     "UETimeZone",
-	IETypeChargingChars:  "ChargingCharacteristics",
-	IETypeULITimestamp:   "ULITimestamp",
-	IETypeEPCO:           "ePCO",
+	IETypeIMSI:                  "IMSI",
+	IETypeMSISDN:                "MSISDN",
+	IETypeMEI:                   "MEI",
+	IETypeFTEID:                 "F-TEID",
+	IETypeULI:                   "ULI",
+	IETypeServingNet:            "ServingNetwork",
+	IETypeRATType:               "RATType",
+	IETypeIndication:            "Indication",
+	IETypeAPN:                   "APN",
+	IETypeSelectionMode:         "SelectionMode",
+	IETypePDNType:               "PDNType",
+	IETypePAA:                   "PAA",
+	IETypeAPNRestriction:        "APNRestriction",
+	IETypeAMBR:                  "AMBR",
+	IETypePCO:                   "PCO",
+	IETypeCause:                 "Cause",
+	IETypeEBI:                   "EBI",
+	IETypeBearerQoS:             "BearerQoS",
+	IETypeBearerContext:         "BearerContext",
+	IETypeRecovery:              "Recovery",
+	IETypeUETimeZone:            "UETimeZone",
+	IETypeChargingChars:         "ChargingCharacteristics",
+	IETypeULITimestamp:          "ULITimestamp",
+	IETypeEPCO:                  "ePCO",
+	IETypeChangeReportingAction: "ChangeReportingAction",
+	IETypePRAAction:             "PresenceReportingAreaAction",
+	IETypePRAInformation:        "PresenceReportingAreaInformation",
+	IETypePrivateExtension:      "PrivateExtension",
+	IETypeFQDN:                  "FQDN",
+	IETypeNodeFeatures:          "NodeFeatures",
+	IETypeNodeIdentifier:        "NodeIdentifier",
+	IETypeAPCO:                  "APCO",
+	IETypeMillisecondTimeStamp:  "MillisecondTimeStamp",
+	IETypeSecondaryRATReport:    "SecondaryRATUsageDataReport",
 }
 
 // ProcessIE decodes the content of a given IE based on its type
 func ProcessIE(ie gtp2.IE) (string, interface{}, error) {
+	return ProcessIEWithDirection(ie, DirectionUnknown)
+}
+
+// ProcessIEWithDirection decodes the content of a given IE, interpreting PCO, APCO and ePCO for the given direction
+func ProcessIEWithDirection(ie gtp2.IE, direction Direction) (string, interface{}, error) {
 
 	ieName, ok := ieTypeNames[ie.Type]
 	if !ok {
@@ -96,8 +121,10 @@ func ProcessIE(ie gtp2.IE) (string, interface{}, error) {
 		decodeFunc = DecodeAPNRestriction
 	case IETypeAMBR:
 		decodeFunc = DecodeAMBR
-	case IETypePCO:
-		decodeFunc = DecodePCO
+	case IETypePCO, IETypeAPCO:
+		decodeFunc = func(data []byte) (interface{}, error) {
+			return DecodePCOWithDirection(data, direction)
+		}
 	case IETypeCause:
 		decodeFunc = DecodeCause
 	case IETypeEBI:
@@ -114,8 +141,28 @@ func ProcessIE(ie gtp2.IE) (string, interface{}, error) {
 		decodeFunc = DecodeChargingChars
 	case IETypeULITimestamp:
 		decodeFunc = DecodeULITimestamp
+	case IETypeMillisecondTimeStamp:
+		decodeFunc = DecodeMillisecondTimeStamp
+	case IETypeSecondaryRATReport:
+		decodeFunc = DecodeSecondaryRATUsageDataReport
 	case IETypeEPCO:
-		decodeFunc = DecodePCO
+		decodeFunc = func(data []byte) (interface{}, error) {
+			return DecodeEPCOWithDirection(data, direction)
+		}
+	case IETypeChangeReportingAction:
+		decodeFunc = DecodeChangeReportingAction
+	case IETypePRAAction:
+		decodeFunc = DecodePresenceReportingAreaAction
+	case IETypePRAInformation:
+		decodeFunc = DecodePresenceReportingAreaInformation
+	case IETypePrivateExtension:
+		decodeFunc = DecodePrivateExtension
+	case IETypeFQDN:
+		decodeFunc = DecodeFQDN
+	case IETypeNodeFeatures:
+		decodeFunc = DecodeNodeFeatures
+	case IETypeNodeIdentifier:
+		decodeFunc = DecodeNodeIdentifier
 	default:
 		return ieName, hex.EncodeToString(ie.Content), nil
 	}
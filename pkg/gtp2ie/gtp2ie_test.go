@@ -3,8 +3,11 @@ package gtp2ie
 import (
 	"github.com/vagabundor/gtp2json/config"
 	"github.com/vagabundor/gtp2json/pkg/gtp2"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestProcessIE(t *testing.T) {
@@ -51,9 +54,9 @@ func TestProcessIE(t *testing.T) {
 		{
 			name: "Test Unknown IE Type",
 			args: args{
-				ie: gtp2.IE{Type: 255, Content: []byte{0x01, 0x02}},
+				ie: gtp2.IE{Type: 254, Content: []byte{0x01, 0x02}},
 			},
-			want:    "unknown_type_255",
+			want:    "unknown_type_254",
 			want1:   "0102",
 			wantErr: false,
 		},
@@ -328,7 +331,7 @@ func TestProcessIE(t *testing.T) {
 				ie: gtp2.IE{Type: IETypeULITimestamp, Content: []byte{0xE9, 0x27, 0xD8, 0xD4}},
 			},
 			want:    "ULITimestamp",
-			want1:   "Dec 16, 2023 08:05:40 UTC",
+			want1:   "2023-12-16T08:05:40Z",
 			wantErr: false,
 		},
 		{
@@ -340,6 +343,220 @@ func TestProcessIE(t *testing.T) {
 			want1:   nil,
 			wantErr: true,
 		},
+		{
+			name: "Test MillisecondTimeStamp Decoding",
+			args: args{
+				ie: gtp2.IE{Type: IETypeMillisecondTimeStamp, Content: []byte{0x03, 0x8e, 0xc3, 0xa6, 0xfc, 0x9b}},
+			},
+			want:    "MillisecondTimeStamp",
+			want1:   "2023-12-16T08:05:40.123Z",
+			wantErr: false,
+		},
+		{
+			name: "Test SecondaryRATUsageDataReport Decoding",
+			args: args{
+				ie: gtp2.IE{
+					Type: IETypeSecondaryRATReport,
+					Content: []byte{
+						0x03, 0x00, 0x05,
+						0xE9, 0x27, 0xD8, 0xD4,
+						0xE9, 0x27, 0xD8, 0xE0,
+						0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0x00,
+						0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x00,
+					},
+				},
+			},
+			want: "SecondaryRATUsageDataReport",
+			want1: SecondaryRATUsageDataReport{
+				IRPGW:          true,
+				IRSGW:          true,
+				RATType:        uint8(0),
+				EBI:            5,
+				StartTimestamp: "2023-12-16T08:05:40Z",
+				EndTimestamp:   "2023-12-16T08:05:52Z",
+				UsageDataDL:    1024,
+				UsageDataUL:    2048,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test ChangeReportingAction Decoding",
+			args: args{
+				ie: gtp2.IE{Type: IETypeChangeReportingAction, Content: []byte{0x06}},
+			},
+			want:    "ChangeReportingAction",
+			want1:   uint8(6),
+			wantErr: false,
+		},
+		{
+			name: "Test PresenceReportingAreaAction Decoding with TAI, Macro eNodeB ID and ECGI",
+			args: args{
+				ie: gtp2.IE{
+					Type: IETypePRAAction,
+					Content: []byte{
+						0x01, 0x00, 0x00, 0x64, 0x10, 0x01, 0x00, 0x01, 0x00, 0x00,
+						0x52, 0xf0, 0x53, 0x17, 0xfd,
+						0x52, 0xf0, 0x53, 0x01, 0xe2, 0x40,
+						0x52, 0xf0, 0x53, 0x03, 0xfd, 0x25, 0x02,
+					},
+				},
+			},
+			want: "PresenceReportingAreaAction",
+			want1: PresenceReportingAreaAction{
+				Action: uint8(1),
+				INAPRA: false,
+				PRAID:  ptr(uint32(100)),
+				TAI: []TAI{
+					{MCCMNC: MCCMNC{MCC: "250", MNC: "35"}, TAC: "6141"},
+				},
+				MacroENodebID: []MacroENodebID{
+					{MCCMNC: MCCMNC{MCC: "250", MNC: "35"}, MacroID: "123456"},
+				},
+				ECGI: []ECGI{
+					{MCCMNC: MCCMNC{MCC: "250", MNC: "35"}, ECI: "66921730"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test PresenceReportingAreaAction Stop without PRA ID",
+			args: args{
+				ie: gtp2.IE{Type: IETypePRAAction, Content: []byte{0x02}},
+			},
+			want:    "PresenceReportingAreaAction",
+			want1:   PresenceReportingAreaAction{Action: uint8(2)},
+			wantErr: false,
+		},
+		{
+			name: "Test PresenceReportingAreaAction Decoding with truncated TAI list",
+			args: args{
+				ie: gtp2.IE{
+					Type:    IETypePRAAction,
+					Content: []byte{0x01, 0x00, 0x00, 0x64, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x52, 0xf0, 0x53, 0x17, 0xfd},
+				},
+			},
+			want:    "PresenceReportingAreaAction",
+			want1:   nil,
+			wantErr: true,
+		},
+		{
+			name: "Test PresenceReportingAreaInformation Decoding with additional PRA",
+			args: args{
+				ie: gtp2.IE{Type: IETypePRAInformation, Content: []byte{0x00, 0x00, 0x64, 0x05, 0x00, 0x00, 0x65, 0x02}},
+			},
+			want: "PresenceReportingAreaInformation",
+			want1: PresenceReportingAreaInformation{
+				PRAID: 100,
+				IPRA:  true,
+				APRA:  true,
+				AdditionalPRA: []AdditionalPRA{
+					{PRAID: 101, OPRA: true},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test PresenceReportingAreaInformation Decoding with insufficient data",
+			args: args{
+				ie: gtp2.IE{Type: IETypePRAInformation, Content: []byte{0x00, 0x00}},
+			},
+			want:    "PresenceReportingAreaInformation",
+			want1:   nil,
+			wantErr: true,
+		},
+		{
+			name: "Test PrivateExtension Decoding with well-known vendor",
+			args: args{
+				ie: gtp2.IE{Type: IETypePrivateExtension, Content: []byte{0x07, 0xdb, 0x01, 0x02, 0x03}},
+			},
+			want: "PrivateExtension",
+			want1: PrivateExtension{
+				EnterpriseID: 2011,
+				Vendor:       "Huawei",
+				Value:        "010203",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test PrivateExtension Decoding with unknown vendor",
+			args: args{
+				ie: gtp2.IE{Type: IETypePrivateExtension, Content: []byte{0xfd, 0xe7, 0xab}},
+			},
+			want: "PrivateExtension",
+			want1: PrivateExtension{
+				EnterpriseID: 64999,
+				Value:        "ab",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test PrivateExtension Decoding with insufficient data",
+			args: args{
+				ie: gtp2.IE{Type: IETypePrivateExtension, Content: []byte{0x07}},
+			},
+			want:    "PrivateExtension",
+			want1:   nil,
+			wantErr: true,
+		},
+		{
+			name: "Test FQDN Decoding",
+			args: args{
+				ie: gtp2.IE{
+					Type:    IETypeFQDN,
+					Content: []byte{0x05, 0x74, 0x6f, 0x70, 0x6f, 0x6e, 0x03, 0x73, 0x31, 0x31, 0x03, 0x65, 0x70, 0x63, 0x00},
+				},
+			},
+			want:    "FQDN",
+			want1:   "topon.s11.epc",
+			wantErr: false,
+		},
+		{
+			name: "Test FQDN Decoding with invalid label length",
+			args: args{
+				ie: gtp2.IE{Type: IETypeFQDN, Content: []byte{0x05, 0x74, 0x6f}},
+			},
+			want:    "FQDN",
+			want1:   nil,
+			wantErr: true,
+		},
+		{
+			name: "Test NodeIdentifier Decoding with Node Number",
+			args: args{
+				ie: gtp2.IE{
+					Type: IETypeNodeIdentifier,
+					Content: []byte{
+						0x04, 0x6d, 0x6d, 0x65, 0x31,
+						0x07, 0x65, 0x70, 0x63, 0x2e, 0x6f, 0x72, 0x67,
+						0x05, 0x21, 0x43, 0xf5,
+					},
+				},
+			},
+			want: "NodeIdentifier",
+			want1: NodeIdentifier{
+				NodeName:   "mme1",
+				NodeRealm:  "epc.org",
+				NodeNumber: "12345",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test NodeIdentifier Decoding with truncated realm",
+			args: args{
+				ie: gtp2.IE{Type: IETypeNodeIdentifier, Content: []byte{0x01, 0x61, 0x05, 0x62}},
+			},
+			want:    "NodeIdentifier",
+			want1:   nil,
+			wantErr: true,
+		},
+		{
+			name: "Test NodeFeatures Decoding",
+			args: args{
+				ie: gtp2.IE{Type: IETypeNodeFeatures, Content: []byte{0x29}},
+			},
+			want:    "NodeFeatures",
+			want1:   []string{"PRN", "CIOT", "ETH"},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -559,6 +776,39 @@ func TestProcessIE_AllFormats(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Test ChangeReportingAction Text",
+			args: args{
+				ie:     gtp2.IE{Type: IETypeChangeReportingAction, Content: []byte{0x06}},
+				format: "text",
+			},
+			want:    "ChangeReportingAction",
+			want1:   "Start Reporting TAI and ECGI",
+			wantErr: false,
+		},
+		{
+			name: "Test ChangeReportingAction Mixed",
+			args: args{
+				ie:     gtp2.IE{Type: IETypeChangeReportingAction, Content: []byte{0x06}},
+				format: "mixed",
+			},
+			want:    "ChangeReportingAction",
+			want1:   "Start Reporting TAI and ECGI (6)",
+			wantErr: false,
+		},
+		{
+			name: "Test PresenceReportingAreaAction Text",
+			args: args{
+				ie:     gtp2.IE{Type: IETypePRAAction, Content: []byte{0x0a}},
+				format: "text",
+			},
+			want: "PresenceReportingAreaAction",
+			want1: PresenceReportingAreaAction{
+				Action: "Stop Reporting changes of UE presence in the PRA",
+				INAPRA: true,
+			},
+			wantErr: false,
+		},
 		{
 			name: "Test UETimeZone Numeric",
 			args: args{
@@ -1289,3 +1539,416 @@ func TestProcessIE_AllFormats(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadPrivateExtensionLayouts(t *testing.T) {
+	layouts := `{
+		"layouts": [
+			{
+				"enterpriseId": 65000,
+				"vendor": "Example Vendor",
+				"typeSize": 1,
+				"lengthSize": 1,
+				"fields": [
+					{"type": 1, "name": "RuleBase", "format": "string"},
+					{"type": 2, "name": "Gateway", "format": "ipv4"}
+				]
+			}
+		]
+	}`
+	path := filepath.Join(t.TempDir(), "layouts.json")
+	if err := os.WriteFile(path, []byte(layouts), 0o644); err != nil {
+		t.Fatalf("failed to write layouts: %v", err)
+	}
+	if err := LoadPrivateExtensionLayouts(path); err != nil {
+		t.Fatalf("LoadPrivateExtensionLayouts() error = %v", err)
+	}
+	for id, want := range map[uint16]string{65000: "Example Vendor", 2011: "Huawei", 65002: ""} {
+		if got := EnterpriseName(id); got != want {
+			t.Errorf("EnterpriseName(%d) = %q, want %q", id, got, want)
+		}
+	}
+
+	ie := gtp2.IE{
+		Type: IETypePrivateExtension,
+		Content: []byte{
+			0xfd, 0xe8,
+			0x01, 0x03, 0x61, 0x62, 0x63,
+			0x02, 0x04, 0x0a, 0x00, 0x00, 0x01,
+			0x09, 0x01, 0xff,
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   interface{}
+	}{
+		{
+			format: "numeric",
+			want: PrivateExtension{
+				EnterpriseID: 65000,
+				Vendor:       "Example Vendor",
+				Value: []TLVOption{
+					{Type: uint16(1), Data: "abc"},
+					{Type: uint16(2), Data: "10.0.0.1"},
+					{Type: uint16(9), Data: "ff"},
+				},
+			},
+		},
+		{
+			format: "mixed",
+			want: PrivateExtension{
+				EnterpriseID: 65000,
+				Vendor:       "Example Vendor",
+				Value: []TLVOption{
+					{Type: "RuleBase (1)", Data: "abc"},
+					{Type: "Gateway (2)", Data: "10.0.0.1"},
+					{Type: "Unknown Type (9)", Data: "ff"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		config.SetOutputFormat(tt.format)
+		t.Run(tt.format, func(t *testing.T) {
+			_, got, err := ProcessIE(ie)
+			if err != nil {
+				t.Fatalf("ProcessIE() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ProcessIE() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := RegisterTLVLayout(TLVLayout{EnterpriseID: 65001, TypeSize: 4}); err == nil {
+		t.Error("RegisterTLVLayout() expected error for invalid type size")
+	}
+	if err := RegisterTLVLayout(TLVLayout{EnterpriseID: 65001, Fields: []TLVField{{Type: 1, Format: "float"}}}); err == nil {
+		t.Error("RegisterTLVLayout() expected error for unknown format")
+	}
+}
+
+func TestProcessIEWithDirection(t *testing.T) {
+	type args struct {
+		ie        gtp2.IE
+		direction Direction
+		format    string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		want1   interface{}
+		wantErr bool
+	}{
+		{
+			name: "Test PCO Network to MS Text",
+			args: args{
+				ie: gtp2.IE{
+					Type: IETypePCO,
+					Content: []byte{
+						0x80,
+						0x00, 0x03, 0x10, 0x20, 0x01, 0x48, 0x60, 0x48, 0x60, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x88, 0x88,
+						0x00, 0x10, 0x02, 0x05, 0xdc,
+						0x00, 0x17, 0x00,
+					},
+				},
+				direction: MessageDirection(33),
+				format:    "text",
+			},
+			want: "PCO",
+			want1: PCO{
+				ConfigurationProtocol: 128,
+				Direction:             "network to MS",
+				Options: []PCOOption{
+					{ProtocolID: "DNS Server IPv6 Address", ProtocolContents: "2001:4860:4860::8888"},
+					{ProtocolID: "IPv4 Link MTU", ProtocolContents: uint16(1500)},
+					{ProtocolID: "3GPP PS data off support indication", ProtocolContents: nil},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test PCO MS to Network Text",
+			args: args{
+				ie: gtp2.IE{
+					Type:    IETypePCO,
+					Content: []byte{0x80, 0x00, 0x03, 0x00, 0x00, 0x17, 0x01, 0x01},
+				},
+				direction: MessageDirection(32),
+				format:    "text",
+			},
+			want: "PCO",
+			want1: PCO{
+				ConfigurationProtocol: 128,
+				Direction:             "MS to network",
+				Options: []PCOOption{
+					{ProtocolID: "DNS Server IPv6 Address Request", ProtocolContents: nil},
+					{ProtocolID: "3GPP PS data off UE status", ProtocolContents: "Activated"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test APCO MS to Network Mixed",
+			args: args{
+				ie:        gtp2.IE{Type: IETypeAPCO, Content: []byte{0x80, 0x00, 0x01, 0x00}},
+				direction: DirectionMSToNetwork,
+				format:    "mixed",
+			},
+			want: "APCO",
+			want1: PCO{
+				ConfigurationProtocol: 128,
+				Direction:             "MS to network",
+				Options: []PCOOption{
+					{ProtocolID: "P-CSCF IPv6 Address Request (1)", ProtocolContents: nil},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test PCO with APN rate control and MSISDN Numeric",
+			args: args{
+				ie: gtp2.IE{
+					Type: IETypePCO,
+					Content: []byte{
+						0x80,
+						0x00, 0x16, 0x04, 0x0a, 0x00, 0x27, 0x10,
+						0x00, 0x0e, 0x06, 0x91, 0x97, 0x21, 0x43, 0x65, 0xf7,
+					},
+				},
+				direction: DirectionNetworkToMS,
+				format:    "numeric",
+			},
+			want: "PCO",
+			want1: PCO{
+				ConfigurationProtocol: 128,
+				Direction:             "network to MS",
+				Options: []PCOOption{
+					{
+						ProtocolID: uint16(0x0016),
+						ProtocolContents: APNRateControl{
+							AER:               ptr(true),
+							UplinkTimeUnit:    uint8(2),
+							MaximumUplinkRate: 10000,
+						},
+					},
+					{ProtocolID: uint16(0x000E), ProtocolContents: "791234567"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test ePCO with QoS rules and QoS flow descriptions Numeric",
+			args: args{
+				ie: gtp2.IE{
+					Type: IETypeEPCO,
+					Content: []byte{
+						0x80,
+						0x00, 0x23, 0x00, 0x09, 0x01, 0x00, 0x06, 0x31, 0x31, 0x01, 0x01, 0xff, 0x09,
+						0x00, 0x24, 0x00, 0x0b, 0x09, 0x20, 0x42, 0x01, 0x01, 0x09, 0x04, 0x03, 0x06, 0x00, 0x64,
+					},
+				},
+				direction: DirectionNetworkToMS,
+				format:    "numeric",
+			},
+			want: "ePCO",
+			want1: PCO{
+				ConfigurationProtocol: 128,
+				Direction:             "network to MS",
+				Options: []PCOOption{
+					{
+						ProtocolID: uint16(0x0023),
+						ProtocolContents: []QoSRule{
+							{
+								Identifier: 1,
+								Operation:  uint8(1),
+								DQR:        true,
+								PacketFilters: []PacketFilter{
+									{Identifier: 1, Direction: uint8(3), Components: "01"},
+								},
+								Precedence: ptr(uint8(255)),
+								QFI:        ptr(uint8(9)),
+							},
+						},
+					},
+					{
+						ProtocolID: uint16(0x0024),
+						ProtocolContents: []QoSFlowDescription{
+							{
+								QFI:       9,
+								Operation: uint8(1),
+								E:         true,
+								Parameters: []QoSFlowParameter{
+									{Identifier: uint8(1), Value: uint8(9)},
+									{Identifier: uint8(4), Value: uint64(102400)},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test PCO with 5GSM containers Numeric",
+			args: args{
+				ie: gtp2.IE{
+					Type: IETypePCO,
+					Content: []byte{
+						0x80,
+						0x00, 0x1b, 0x04, 0x01, 0x00, 0x00, 0x01,
+						0x00, 0x1c, 0x09, 0x01, 0x00, 0x06, 0x31, 0x31, 0x01, 0x01, 0xff, 0x09,
+						0x00, 0x1d, 0x06, 0x06, 0x00, 0x64, 0x06, 0x00, 0x32,
+						0x00, 0x1e, 0x01, 0x21,
+						0x00, 0x1f, 0x06, 0x09, 0x20, 0x41, 0x01, 0x01, 0x09,
+					},
+				},
+				direction: DirectionNetworkToMS,
+				format:    "numeric",
+			},
+			want: "PCO",
+			want1: PCO{
+				ConfigurationProtocol: 128,
+				Direction:             "network to MS",
+				Options: []PCOOption{
+					{ProtocolID: uint16(0x001B), ProtocolContents: SNSSAI{SST: 1, SD: "000001"}},
+					{
+						ProtocolID: uint16(0x001C),
+						ProtocolContents: []QoSRule{
+							{
+								Identifier: 1,
+								Operation:  uint8(1),
+								DQR:        true,
+								PacketFilters: []PacketFilter{
+									{Identifier: 1, Direction: uint8(3), Components: "01"},
+								},
+								Precedence: ptr(uint8(255)),
+								QFI:        ptr(uint8(9)),
+							},
+						},
+					},
+					{ProtocolID: uint16(0x001D), ProtocolContents: SessionAMBR{Downlink: 102400, Uplink: 51200}},
+					{ProtocolID: uint16(0x001E), ProtocolContents: []byte{0x21}},
+					{
+						ProtocolID: uint16(0x001F),
+						ProtocolContents: []QoSFlowDescription{
+							{
+								QFI:        9,
+								Operation:  uint8(1),
+								E:          true,
+								Parameters: []QoSFlowParameter{{Identifier: uint8(1), Value: uint8(9)}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test ePCO with truncated option",
+			args: args{
+				ie:        gtp2.IE{Type: IETypeEPCO, Content: []byte{0x80, 0x00, 0x23, 0x00, 0x09, 0x01}},
+				direction: DirectionNetworkToMS,
+				format:    "numeric",
+			},
+			want:    "ePCO",
+			want1:   nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		config.SetOutputFormat(tt.args.format)
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1, err := ProcessIEWithDirection(tt.args.ie, tt.args.direction)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ProcessIEWithDirection() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ProcessIEWithDirection() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(got1, tt.want1) {
+				t.Errorf("ProcessIEWithDirection() got1 = %v, want %v", got1, tt.want1)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestTimeFormats(t *testing.T) {
+	tests := []struct {
+		name       string
+		ie         gtp2.IE
+		timeFormat string
+		location   *time.Location
+		want       interface{}
+	}{
+		{
+			name:       "ULITimestamp RFC 3339 with timezone",
+			ie:         gtp2.IE{Type: IETypeULITimestamp, Content: []byte{0xE9, 0x27, 0xD8, 0xD4}},
+			timeFormat: "rfc3339",
+			location:   time.FixedZone("MSK", 3*60*60),
+			want:       "2023-12-16T11:05:40+03:00",
+		},
+		{
+			name:       "ULITimestamp epoch milliseconds",
+			ie:         gtp2.IE{Type: IETypeULITimestamp, Content: []byte{0xE9, 0x27, 0xD8, 0xD4}},
+			timeFormat: "epochms",
+			location:   time.UTC,
+			want:       int64(1702713940000),
+		},
+		{
+			name:       "ULITimestamp after NTP era rollover",
+			ie:         gtp2.IE{Type: IETypeULITimestamp, Content: []byte{0x00, 0x00, 0x00, 0x10}},
+			timeFormat: "rfc3339",
+			location:   time.UTC,
+			want:       "2036-02-07T06:28:32Z",
+		},
+		{
+			name:       "ULITimestamp before NTP era rollover",
+			ie:         gtp2.IE{Type: IETypeULITimestamp, Content: []byte{0xFF, 0xFF, 0xFF, 0xFF}},
+			timeFormat: "rfc3339",
+			location:   time.UTC,
+			want:       "2036-02-07T06:28:15Z",
+		},
+		{
+			name:       "MillisecondTimeStamp epoch milliseconds",
+			ie:         gtp2.IE{Type: IETypeMillisecondTimeStamp, Content: []byte{0x03, 0x8e, 0xc3, 0xa6, 0xfc, 0x9b}},
+			timeFormat: "epochms",
+			location:   time.UTC,
+			want:       int64(1702713940123),
+		},
+		{
+			name:       "MillisecondTimeStamp largest value",
+			ie:         gtp2.IE{Type: IETypeMillisecondTimeStamp, Content: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+			timeFormat: "epochms",
+			location:   time.UTC,
+			want:       int64(1<<48 - 1 - 2208988800000),
+		},
+	}
+
+	defer func() {
+		config.SetTimeFormat("rfc3339")
+		config.SetTimeLocation(time.UTC)
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.SetTimeFormat(tt.timeFormat)
+			config.SetTimeLocation(tt.location)
+			_, got, err := ProcessIE(tt.ie)
+			if err != nil {
+				t.Fatalf("ProcessIE() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ProcessIE() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
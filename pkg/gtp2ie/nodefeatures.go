@@ -0,0 +1,30 @@
+package gtp2ie
+
+import "fmt"
+
+// NodeFeatureNames lists the Node Features bits from least significant upwards (3GPP TS 29.274 8.83)
+var NodeFeatureNames = []string{
+	"PRN",   // PGW Restart Notification
+	"MABR",  // Modify Access Bearers Request
+	"NTSR",  // Network Triggered Service Restoration
+	"CIOT",  // Cellular Internet of Things
+	"S1UN",  // S1-U path failure notification
+	"ETH",   // Ethernet PDN type support
+	"MTEDT", // Support of MT-EDT
+}
+
+// DecodeNodeFeatures decodes the Node Features IE into the list of supported features
+func DecodeNodeFeatures(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Node Features")
+	}
+
+	features := make([]string, 0)
+	for bit, name := range NodeFeatureNames {
+		if data[0]&(1<<bit) != 0 {
+			features = append(features, name)
+		}
+	}
+
+	return features, nil
+}
@@ -0,0 +1,52 @@
+package gtp2ie
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+	"time"
+)
+
+var (
+	// ntpEra0 is the NTP prime epoch, 1 January 1900
+	ntpEra0 = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	// ntpEra1 is where 32-bit NTP seconds roll over, 7 February 2036 06:28:16 UTC
+	ntpEra1 = ntpEra0.Add(time.Duration(1<<32) * time.Second)
+)
+
+// ntpSecondsToTime converts 32-bit NTP seconds to time.Time.
+// Following RFC 4330, values with the most significant bit cleared belong to the era
+// starting in 2036, so timestamps remain correct after the rollover.
+func ntpSecondsToTime(seconds uint32) time.Time {
+	if seconds&0x80000000 == 0 {
+		return ntpEra1.Add(time.Duration(seconds) * time.Second)
+	}
+	return ntpEra0.Add(time.Duration(seconds) * time.Second)
+}
+
+// ntpEpochOffsetMs is the number of milliseconds from the NTP prime epoch to the Unix epoch
+const ntpEpochOffsetMs = 2208988800000
+
+// ntpMillisecondsToTime converts milliseconds since the NTP prime epoch to time.Time. It is computed
+// in milliseconds, as 48-bit values exceed the range of time.Duration.
+func ntpMillisecondsToTime(milliseconds uint64) time.Time {
+	return time.UnixMilli(int64(milliseconds) - ntpEpochOffsetMs).UTC()
+}
+
+// formatTime returns the timestamp in the selected time format.
+func formatTime(t time.Time) interface{} {
+	switch config.GetTimeFormat() {
+	case "epochms":
+		return t.UnixMilli()
+	default:
+		return t.In(config.GetTimeLocation()).Format(time.RFC3339Nano)
+	}
+}
+
+// DecodeNTPTimestamp decodes a 32-bit NTP seconds timestamp, the format shared by PFCP time IEs
+func DecodeNTPTimestamp(data []byte) (interface{}, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("insufficient data for timestamp: expected at least 4 bytes, got %d", len(data))
+	}
+	return formatTime(ntpSecondsToTime(binary.BigEndian.Uint32(data[:4]))), nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"github.com/vagabundor/gtp2json/config"
+	"net"
 )
 
 // ProtocolIDNames maps protocol IDs to their descriptions (3GPP TS 24.008 10.5.6.3)
@@ -68,6 +69,7 @@ var SelectedBearerControlModeNames = map[uint8]string{
 // PCO represents the Protocol Configuration Options information element.
 type PCO struct {
 	ConfigurationProtocol uint8       `json:"ConfigurationProtocol"`
+	Direction             string      `json:"Direction,omitempty"`
 	Options               []PCOOption `json:"Options"`
 }
 
@@ -332,19 +334,291 @@ func DecodePCSCFIPv4Address(data []byte) (interface{}, error) {
 	return DecodeIPv4Address(data)
 }
 
+// DecodeIPv6Address decodes a generic IPv6 address
+func DecodeIPv6Address(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if len(data) != 16 {
+		return nil, fmt.Errorf("invalid length for IPv6 Address: expected 16 bytes, got %d", len(data))
+	}
+
+	return net.IP(data).String(), nil
+}
+
+// DecodeIPv6Prefix decodes an IPv6 prefix followed by a single prefix length octet
+func DecodeIPv6Prefix(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if len(data) != 17 {
+		return nil, fmt.Errorf("invalid length for IPv6 Prefix: expected 17 bytes, got %d", len(data))
+	}
+
+	return fmt.Sprintf("%s/%d", net.IP(data[:16]).String(), data[16]), nil
+}
+
+// DecodeLinkMTU decodes a 2-byte link MTU (IPv4, Non-IP, Ethernet and Unstructured)
+func DecodeLinkMTU(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if len(data) != 2 {
+		return nil, fmt.Errorf("invalid length for Link MTU: expected 2 bytes, got %d", len(data))
+	}
+
+	return binary.BigEndian.Uint16(data), nil
+}
+
+// DecodeSingleOctet decodes options carrying a single numeric octet (PDU session ID, 5GSM cause, etc.)
+func DecodeSingleOctet(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if len(data) != 1 {
+		return nil, fmt.Errorf("invalid length: expected 1 byte, got %d", len(data))
+	}
+
+	return data[0], nil
+}
+
+// DecodePCOMSISDN decodes the MSISDN option, coded as the contents of the Calling party BCD number
+func DecodePCOMSISDN(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	// Skip the type of number octet when the extension bit marks it as present
+	if data[0]&0x80 != 0 {
+		data = data[1:]
+	}
+
+	return DecodeBCD(data)
+}
+
+// PSDataOffStatusNames maps the 3GPP PS data off UE status to its descriptions
+var PSDataOffStatusNames = map[uint8]string{
+	0: "Deactivated",
+	1: "Activated",
+}
+
+// DecodePSDataOffUEStatus decodes the 3GPP PS data off UE status
+func DecodePSDataOffUEStatus(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	status := data[0] & 0x01
+	return formatDescription(PSDataOffStatusNames[status], status, config.GetOutputFormat()), nil
+}
+
+// APNRateControlTimeUnits maps the uplink time unit of APN rate control to its descriptions
+var APNRateControlTimeUnits = map[uint8]string{
+	0: "Unrestricted",
+	1: "Minute",
+	2: "Hour",
+	3: "Day",
+	4: "Week",
+}
+
+// APNRateControl represents APN rate control parameters (3GPP TS 24.008 10.5.6.3)
+type APNRateControl struct {
+	AER               *bool       `json:"AER,omitempty"` // Additional exception reports
+	UplinkTimeUnit    interface{} `json:"UplinkTimeUnit"`
+	MaximumUplinkRate uint32      `json:"MaximumUplinkRate"`
+}
+
+// DecodeAPNRateControl decodes the APN rate control parameters
+func DecodeAPNRateControl(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	rateControl, err := decodeRateControl(data)
+	if err != nil {
+		return nil, err
+	}
+
+	aer := data[0]&0x08 != 0
+	rateControl.AER = &aer
+
+	return rateControl, nil
+}
+
+// DecodeAdditionalAPNRateControl decodes the additional APN rate control for exception data parameters
+func DecodeAdditionalAPNRateControl(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	return decodeRateControl(data)
+}
+
+// decodeRateControl decodes the uplink time unit and maximum uplink rate shared by APN rate control options
+func decodeRateControl(data []byte) (APNRateControl, error) {
+	if len(data) < 4 {
+		return APNRateControl{}, fmt.Errorf("insufficient data for APN rate control: expected at least 4 bytes, got %d", len(data))
+	}
+
+	timeUnit := data[0] & 0x07
+	description, exists := APNRateControlTimeUnits[timeUnit]
+	if !exists {
+		description = fmt.Sprintf("Unknown Time Unit (%d)", timeUnit)
+	}
+
+	return APNRateControl{
+		UplinkTimeUnit:    formatDescription(description, timeUnit, config.GetOutputFormat()),
+		MaximumUplinkRate: uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3]),
+	}, nil
+}
+
+// ProtocolIDDecoders maps protocol IDs to decoders that apply in both directions
 var ProtocolIDDecoders = map[uint16]func([]byte) (interface{}, error){
 	0x8021: DecodeIPCP,
+	0x0001: DecodeIPv6Address,
+	0x0003: DecodeIPv6Address,
 	0x000D: DecodeDNSServerIPv4Address,
 	0x0005: DecodeSelectedBearerControlMode,
+	0x0007: DecodeIPv6Address,
+	0x0008: DecodeIPv6Prefix,
+	0x0009: DecodeIPv4Address,
+	0x000E: DecodePCOMSISDN,
 	0x0010: DecodeIPv4LinkMTU,
 	0x000C: DecodePCSCFIPv4Address,
+	0x0014: DecodeSingleOctet,
+	0x0015: DecodeLinkMTU,
+	0x001A: DecodeSingleOctet,
+	0x0020: DecodeLinkMTU,
+	0x0021: DecodeLinkMTU,
+	0x0022: DecodeSingleOctet,
 	0xC023: DecodePAP,
 	0xC223: DecodeCHAP,
 }
 
+// ProtocolIDDecodersMSToNetwork overrides ProtocolIDDecoders for options sent by the MS
+var ProtocolIDDecodersMSToNetwork = map[uint16]func([]byte) (interface{}, error){
+	0x0017: DecodePSDataOffUEStatus,
+}
+
+// ProtocolIDDecodersNetworkToMS overrides ProtocolIDDecoders for options sent by the network
+var ProtocolIDDecodersNetworkToMS = map[uint16]func([]byte) (interface{}, error){
+	0x0004: DecodeSingleOctet, // Policy Control rejection code
+	0x0016: DecodeAPNRateControl,
+	0x0019: DecodeAdditionalAPNRateControl,
+	0x001B: DecodeSNSSAI,
+	0x001C: DecodeQoSRules,
+	0x001D: DecodeSessionAMBR,
+	0x001F: DecodeQoSFlowDescriptions,
+	0x0023: DecodeQoSRules,
+	0x0024: DecodeQoSFlowDescriptions,
+}
+
+// ProtocolIDNamesMSToNetwork overrides ProtocolIDNames for options sent by the MS
+var ProtocolIDNamesMSToNetwork = map[uint16]string{
+	0x0001: "P-CSCF IPv6 Address Request",
+	0x0003: "DNS Server IPv6 Address Request",
+	0x0007: "DSMIPv6 Home Agent Address Request",
+	0x0008: "DSMIPv6 Home Network Prefix Request",
+	0x0009: "DSMIPv6 IPv4 Home Agent Address Request",
+	0x000C: "P-CSCF IPv4 Address Request",
+	0x000D: "DNS Server IPv4 Address Request",
+	0x000E: "MSISDN Request",
+	0x0010: "IPv4 Link MTU Request",
+	0x0013: "NBIFOM request indicator",
+	0x0015: "Non-IP Link MTU Request",
+	0x0018: "Reliable Data Service request indicator",
+	0x0019: "Additional APN rate control for exception data support indicator",
+	0x0020: "Ethernet Frame Payload MTU Request",
+	0x0021: "Unstructured Link MTU Request",
+	0x0023: "QoS rules with the length of two octets support indicator",
+	0x0024: "QoS flow descriptions with the length of two octets support indicator",
+}
+
+// ProtocolIDNamesNetworkToMS overrides ProtocolIDNames for options sent by the network
+var ProtocolIDNamesNetworkToMS = map[uint16]string{
+	0x0004: "Policy Control rejection code",
+	0x0005: "Selected Bearer Control Mode",
+	0x000F: "IFOM-Support",
+	0x0011: "Network support of Local address in TFT indicator",
+	0x0013: "NBIFOM accepted indicator",
+	0x0016: "APN rate control parameters",
+	0x0017: "3GPP PS data off support indication",
+	0x0018: "Reliable Data Service accepted indicator",
+	0x0019: "Additional APN rate control for exception data parameters",
+	0x001B: "S-NSSAI",
+	0x001C: "QoS rules",
+	0x001D: "Session-AMBR",
+	0x001E: "PDU session address lifetime",
+	0x001F: "QoS flow descriptions",
+	0x0023: "QoS rules with the length of two octets",
+	0x0024: "QoS flow descriptions with the length of two octets",
+}
+
+// Direction tells which way a PCO-like IE travels, since several protocol IDs differ per direction
+type Direction uint8
+
+const (
+	DirectionUnknown Direction = iota
+	DirectionMSToNetwork
+	DirectionNetworkToMS
+)
+
+// DirectionNames maps directions to the value emitted in the decoded PCO
+var DirectionNames = map[Direction]string{
+	DirectionMSToNetwork: "MS to network",
+	DirectionNetworkToMS: "network to MS",
+}
+
+// messageDirections maps GTPv2 messages carrying PCO, APCO or ePCO to the direction of those IEs
+var messageDirections = map[uint8]Direction{
+	32:  DirectionMSToNetwork, // Create Session Request
+	33:  DirectionNetworkToMS, // Create Session Response
+	34:  DirectionMSToNetwork, // Modify Bearer Request
+	35:  DirectionNetworkToMS, // Modify Bearer Response
+	36:  DirectionMSToNetwork, // Delete Session Request
+	37:  DirectionNetworkToMS, // Delete Session Response
+	68:  DirectionMSToNetwork, // Bearer Resource Command
+	69:  DirectionNetworkToMS, // Bearer Resource Failure Indication
+	95:  DirectionNetworkToMS, // Create Bearer Request
+	96:  DirectionMSToNetwork, // Create Bearer Response
+	97:  DirectionNetworkToMS, // Update Bearer Request
+	98:  DirectionMSToNetwork, // Update Bearer Response
+	99:  DirectionNetworkToMS, // Delete Bearer Request
+	100: DirectionMSToNetwork, // Delete Bearer Response
+}
+
+// MessageDirection returns the direction of PCO-like IEs carried in the given GTPv2 message type
+func MessageDirection(messageType uint8) Direction {
+	return messageDirections[messageType]
+}
+
 // DecodePCO decodes the Protocol Configuration Options (PCO) IE from a byte slice
 func DecodePCO(data []byte) (interface{}, error) {
-	if len(data) < 3 {
+	return decodeProtocolOptions(data, 1, DirectionUnknown)
+}
+
+// DecodePCOWithDirection decodes the PCO or APCO IE interpreting protocol IDs for the given direction
+func DecodePCOWithDirection(data []byte, direction Direction) (interface{}, error) {
+	return decodeProtocolOptions(data, 1, direction)
+}
+
+// DecodeEPCO decodes the Extended Protocol Configuration Options (ePCO) IE, which uses 2-byte option lengths
+func DecodeEPCO(data []byte) (interface{}, error) {
+	return decodeProtocolOptions(data, 2, DirectionUnknown)
+}
+
+// DecodeEPCOWithDirection decodes the ePCO IE interpreting protocol IDs for the given direction
+func DecodeEPCOWithDirection(data []byte, direction Direction) (interface{}, error) {
+	return decodeProtocolOptions(data, 2, direction)
+}
+
+// decodeProtocolOptions is the engine shared by PCO, APCO and ePCO
+func decodeProtocolOptions(data []byte, lengthSize int, direction Direction) (interface{}, error) {
+	if len(data) < 1+lengthSize+1 {
 		return nil, fmt.Errorf("insufficient data for PCO")
 	}
 
@@ -353,13 +627,18 @@ func DecodePCO(data []byte) (interface{}, error) {
 	index := 1
 
 	for index < len(data) {
-		if index+3 > len(data) {
+		if index+2+lengthSize > len(data) {
 			return nil, fmt.Errorf("truncated data at protocol ID at index %d", index)
 		}
 
 		protocolID := binary.BigEndian.Uint16(data[index : index+2])
-		contentLength := int(data[index+2])
-		index += 3
+		var contentLength int
+		if lengthSize == 2 {
+			contentLength = int(binary.BigEndian.Uint16(data[index+2 : index+4]))
+		} else {
+			contentLength = int(data[index+2])
+		}
+		index += 2 + lengthSize
 
 		if index+contentLength > len(data) {
 			return nil, fmt.Errorf("truncated data at protocol ID contents at index %d, contentLength %d", index, contentLength)
@@ -368,16 +647,11 @@ func DecodePCO(data []byte) (interface{}, error) {
 		protocolContents := data[index : index+contentLength]
 		index += contentLength
 
-		description, exists := ProtocolIDNames[protocolID]
-		if !exists {
-			description = fmt.Sprintf("Unknown Protocol (%04X)", protocolID)
-		}
-
 		option := PCOOption{
-			ProtocolID: formatProtocolID(protocolID, description, config.GetOutputFormat()),
+			ProtocolID: formatProtocolID(protocolID, protocolIDName(protocolID, direction), config.GetOutputFormat()),
 		}
 
-		if decodeFunc, exists := ProtocolIDDecoders[protocolID]; exists {
+		if decodeFunc := protocolIDDecoder(protocolID, direction); decodeFunc != nil {
 			decodedContent, err := decodeFunc(protocolContents)
 			if err != nil {
 				return nil, err
@@ -396,10 +670,46 @@ func DecodePCO(data []byte) (interface{}, error) {
 
 	return PCO{
 		ConfigurationProtocol: configurationProtocol,
+		Direction:             DirectionNames[direction],
 		Options:               options,
 	}, nil
 }
 
+// protocolIDName returns the protocol ID description for the given direction
+func protocolIDName(protocolID uint16, direction Direction) string {
+	var overrides map[uint16]string
+	switch direction {
+	case DirectionMSToNetwork:
+		overrides = ProtocolIDNamesMSToNetwork
+	case DirectionNetworkToMS:
+		overrides = ProtocolIDNamesNetworkToMS
+	}
+
+	if description, exists := overrides[protocolID]; exists {
+		return description
+	}
+	if description, exists := ProtocolIDNames[protocolID]; exists {
+		return description
+	}
+	return fmt.Sprintf("Unknown Protocol (%04X)", protocolID)
+}
+
+// protocolIDDecoder returns the protocol ID decoder for the given direction, or nil if there is none
+func protocolIDDecoder(protocolID uint16, direction Direction) func([]byte) (interface{}, error) {
+	var overrides map[uint16]func([]byte) (interface{}, error)
+	switch direction {
+	case DirectionMSToNetwork:
+		overrides = ProtocolIDDecodersMSToNetwork
+	case DirectionNetworkToMS:
+		overrides = ProtocolIDDecodersNetworkToMS
+	}
+
+	if decodeFunc, exists := overrides[protocolID]; exists {
+		return decodeFunc
+	}
+	return ProtocolIDDecoders[protocolID]
+}
+
 // formatProtocolID returns the formatted protocol ID based on the selected format.
 func formatProtocolID(id uint16, description string, format string) interface{} {
 	switch format {
@@ -0,0 +1,257 @@
+package gtp2ie
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+)
+
+// PRAActionNames maps Presence Reporting Area Action values to their descriptions (3GPP TS 29.274 8.108)
+var PRAActionNames = map[uint8]string{
+	1: "Start Reporting changes of UE presence in the PRA",
+	2: "Stop Reporting changes of UE presence in the PRA",
+	3: "Modify Presence Reporting Area elements",
+}
+
+// PresenceReportingAreaAction represents Presence Reporting Area Action IE (3GPP TS 29.274 8.108)
+type PresenceReportingAreaAction struct {
+	Action                interface{}             `json:"Action"`
+	INAPRA                bool                    `json:"INAPRA"`
+	PRAID                 *uint32                 `json:"PRAID,omitempty"`
+	TAI                   []TAI                   `json:"TAI,omitempty"`
+	MacroENodebID         []MacroENodebID         `json:"Macro_eNodebID,omitempty"`
+	HomeENodebID          []HomeENodebID          `json:"Home_eNodebID,omitempty"`
+	ECGI                  []ECGI                  `json:"ECGI,omitempty"`
+	RAI                   []RAI                   `json:"RAI,omitempty"`
+	SAI                   []SAI                   `json:"SAI,omitempty"`
+	CGI                   []CGI                   `json:"CGI,omitempty"`
+	ExtendedMacroENodebID []ExtendedMacroENodebID `json:"ExtendedMacroENodebID,omitempty"`
+}
+
+// PresenceReportingAreaInformation represents Presence Reporting Area Information IE (3GPP TS 29.274 8.109)
+type PresenceReportingAreaInformation struct {
+	PRAID         uint32          `json:"PRAID"`
+	IPRA          bool            `json:"IPRA"`   // Inside Presence Reporting Area
+	OPRA          bool            `json:"OPRA"`   // Outside Presence Reporting Area
+	APRA          bool            `json:"APRA"`   // Additional Presence Reporting Area
+	INAPRA        bool            `json:"INAPRA"` // Inactive Presence Reporting Area
+	AdditionalPRA []AdditionalPRA `json:"AdditionalPRA,omitempty"`
+}
+
+// AdditionalPRA represents an additional PRA entry within Presence Reporting Area Information
+type AdditionalPRA struct {
+	PRAID  uint32 `json:"PRAID"`
+	IPRA   bool   `json:"IPRA"`
+	OPRA   bool   `json:"OPRA"`
+	INAPRA bool   `json:"INAPRA"`
+}
+
+type HomeENodebID struct {
+	MCCMNC
+	HomeID string `json:"HomeID,omitempty"`
+}
+
+// decodePRAMacroENodeBID decodes a 6-byte Macro eNodeB ID as used in PRA element lists
+func decodePRAMacroENodeBID(data []byte) (MacroENodebID, int, error) {
+	// 6 bytes needed: 3 for MCC/MNC, 3 for Macro eNodeB ID
+	if len(data) < 6 {
+		return MacroENodebID{}, 0, fmt.Errorf("not enough data for Macro eNodeB ID")
+	}
+
+	mccmnc, err := DecodeMCCMNC(data[:3])
+	if err != nil {
+		return MacroENodebID{}, 0, fmt.Errorf("failed to decode MCC/MNC: %v", err)
+	}
+
+	macroID := (uint32(data[3])<<16 | uint32(data[4])<<8 | uint32(data[5])) & 0xFFFFF
+
+	return MacroENodebID{
+		MCCMNC:  mccmnc,
+		MacroID: fmt.Sprintf("%d", macroID),
+	}, 6, nil
+}
+
+// decodeHomeENodeBID decodes a 7-byte Home eNodeB ID as used in PRA element lists
+func decodeHomeENodeBID(data []byte) (HomeENodebID, int, error) {
+	// 7 bytes needed: 3 for MCC/MNC, 4 for Home eNodeB ID
+	if len(data) < 7 {
+		return HomeENodebID{}, 0, fmt.Errorf("not enough data for Home eNodeB ID")
+	}
+
+	mccmnc, err := DecodeMCCMNC(data[:3])
+	if err != nil {
+		return HomeENodebID{}, 0, fmt.Errorf("failed to decode MCC/MNC: %v", err)
+	}
+
+	homeID := binary.BigEndian.Uint32(data[3:7]) & 0xFFFFFFF
+
+	return HomeENodebID{
+		MCCMNC: mccmnc,
+		HomeID: fmt.Sprintf("%d", homeID),
+	}, 7, nil
+}
+
+// DecodeShortRAI decodes a 6-byte Routing Area Identity (single octet RAC) as used in PRA element lists and GTPv1
+func DecodeShortRAI(data []byte) (RAI, int, error) {
+	// 6 bytes needed: 3 for MCC/MNC, 2 for LAC, 1 for RAC
+	if len(data) < 6 {
+		return RAI{}, 0, fmt.Errorf("not enough data for RAI")
+	}
+
+	mccmnc, err := DecodeMCCMNC(data[:3])
+	if err != nil {
+		return RAI{}, 0, fmt.Errorf("failed to decode MCC/MNC: %v", err)
+	}
+
+	return RAI{
+		MCCMNC: mccmnc,
+		LAC:    fmt.Sprintf("%d", binary.BigEndian.Uint16(data[3:5])),
+		RAC:    fmt.Sprintf("%d", data[5]),
+	}, 6, nil
+}
+
+// decodePRAExtendedMacroENodeBID decodes a 6-byte Extended Macro eNodeB ID as used in PRA element lists
+func decodePRAExtendedMacroENodeBID(data []byte) (ExtendedMacroENodebID, int, error) {
+	// 6 bytes needed: 3 for MCC/MNC, 3 for SMeNB flag and Extended Macro eNodeB ID
+	if len(data) < 6 {
+		return ExtendedMacroENodebID{}, 0, fmt.Errorf("not enough data for Extended Macro eNodeB ID")
+	}
+
+	mccmnc, err := DecodeMCCMNC(data[:3])
+	if err != nil {
+		return ExtendedMacroENodebID{}, 0, fmt.Errorf("failed to decode MCC/MNC: %v", err)
+	}
+
+	extendedID := (uint32(data[3])<<16 | uint32(data[4])<<8 | uint32(data[5])) & 0x1FFFFF
+
+	return ExtendedMacroENodebID{
+		MCCMNC:     mccmnc,
+		ExtendedID: fmt.Sprintf("%d", extendedID),
+	}, 6, nil
+}
+
+// decodePRAList decodes count consecutive elements with the given decoder and returns the bytes consumed
+func decodePRAList[T any](data []byte, count int, name string, decode func([]byte) (T, int, error)) ([]T, int, error) {
+	var items []T
+	index := 0
+	for i := 0; i < count; i++ {
+		item, n, err := decode(data[index:])
+		if err != nil {
+			return nil, 0, fmt.Errorf("error decoding %s %d: %v", name, i, err)
+		}
+		items = append(items, item)
+		index += n
+	}
+	return items, index, nil
+}
+
+// DecodePresenceReportingAreaAction decodes the Presence Reporting Area Action IE from a byte slice
+func DecodePresenceReportingAreaAction(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Presence Reporting Area Action")
+	}
+
+	action := data[0] & 0x07
+	description, exists := PRAActionNames[action]
+	if !exists {
+		description = fmt.Sprintf("Unknown Action (%d)", action)
+	}
+
+	praa := PresenceReportingAreaAction{
+		Action: formatDescription(description, action, config.GetOutputFormat()),
+		INAPRA: data[0]&0x08 != 0,
+	}
+
+	// PRA Identifier and element lists are only present for Start and Modify actions
+	if len(data) < 4 {
+		return praa, nil
+	}
+
+	praID := uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+	praa.PRAID = &praID
+
+	if len(data) < 10 {
+		return praa, nil
+	}
+
+	numTAI := int(data[4] >> 4)
+	numRAI := int(data[4] & 0x0F)
+	numMacro := int(data[5] & 0x3F)
+	numHome := int(data[6] & 0x3F)
+	numECGI := int(data[7] & 0x3F)
+	numSAI := int(data[8] & 0x3F)
+	numCGI := int(data[9] & 0x3F)
+	index := 10
+
+	var err error
+	var n int
+
+	if praa.TAI, n, err = decodePRAList(data[index:], numTAI, "TAI", decodeTAI); err != nil {
+		return nil, err
+	}
+	index += n
+	if praa.MacroENodebID, n, err = decodePRAList(data[index:], numMacro, "Macro eNodeB ID", decodePRAMacroENodeBID); err != nil {
+		return nil, err
+	}
+	index += n
+	if praa.HomeENodebID, n, err = decodePRAList(data[index:], numHome, "Home eNodeB ID", decodeHomeENodeBID); err != nil {
+		return nil, err
+	}
+	index += n
+	if praa.ECGI, n, err = decodePRAList(data[index:], numECGI, "ECGI", decodeECGI); err != nil {
+		return nil, err
+	}
+	index += n
+	if praa.RAI, n, err = decodePRAList(data[index:], numRAI, "RAI", DecodeShortRAI); err != nil {
+		return nil, err
+	}
+	index += n
+	if praa.SAI, n, err = decodePRAList(data[index:], numSAI, "SAI", DecodeSAI); err != nil {
+		return nil, err
+	}
+	index += n
+	if praa.CGI, n, err = decodePRAList(data[index:], numCGI, "CGI", DecodeCGI); err != nil {
+		return nil, err
+	}
+	index += n
+
+	// Extended Macro eNodeB IDs were added in a later release and may be absent
+	if index < len(data) {
+		numExtended := int(data[index] & 0x3F)
+		index++
+		if praa.ExtendedMacroENodebID, _, err = decodePRAList(data[index:], numExtended, "Extended Macro eNodeB ID", decodePRAExtendedMacroENodeBID); err != nil {
+			return nil, err
+		}
+	}
+
+	return praa, nil
+}
+
+// DecodePresenceReportingAreaInformation decodes the Presence Reporting Area Information IE from a byte slice
+func DecodePresenceReportingAreaInformation(data []byte) (interface{}, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("insufficient data for Presence Reporting Area Information: expected at least 4 bytes, got %d", len(data))
+	}
+
+	prai := PresenceReportingAreaInformation{
+		PRAID:  uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2]),
+		INAPRA: data[3]&0x08 != 0,
+		APRA:   data[3]&0x04 != 0,
+		OPRA:   data[3]&0x02 != 0,
+		IPRA:   data[3]&0x01 != 0,
+	}
+
+	// Additional PRA entries follow when APRA is set, each 3 bytes of PRA ID and 1 byte of flags
+	index := 4
+	for prai.APRA && index+4 <= len(data) {
+		prai.AdditionalPRA = append(prai.AdditionalPRA, AdditionalPRA{
+			PRAID:  uint32(data[index])<<16 | uint32(data[index+1])<<8 | uint32(data[index+2]),
+			INAPRA: data[index+3]&0x08 != 0,
+			OPRA:   data[index+3]&0x02 != 0,
+			IPRA:   data[index+3]&0x01 != 0,
+		})
+		index += 4
+	}
+
+	return prai, nil
+}
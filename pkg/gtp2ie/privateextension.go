@@ -0,0 +1,263 @@
+package gtp2ie
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/vagabundor/gtp2json/config"
+)
+
+// enterpriseNames maps IANA Private Enterprise Numbers of common mobile core vendors to their names,
+// further vendors are added by RegisterTLVLayout
+var enterpriseNames = map[uint16]string{
+	9:     "Cisco",
+	94:    "Nokia",
+	193:   "Ericsson",
+	637:   "Alcatel-Lucent",
+	2011:  "Huawei",
+	2636:  "Juniper Networks",
+	3902:  "ZTE",
+	8164:  "Starent Networks",
+	10415: "3GPP",
+	28458: "Nokia Siemens Networks",
+}
+
+// PrivateExtension represents Private Extension IE (3GPP TS 29.274 8.67)
+type PrivateExtension struct {
+	EnterpriseID uint16      `json:"EnterpriseID"`
+	Vendor       string      `json:"Vendor,omitempty"`
+	Value        interface{} `json:"Value"`
+}
+
+// PrivateExtensionDecoder decodes the proprietary value that follows the enterprise ID
+type PrivateExtensionDecoder func([]byte) (interface{}, error)
+
+var (
+	privateExtensionMu       sync.RWMutex
+	privateExtensionDecoders = map[uint16]PrivateExtensionDecoder{}
+)
+
+// RegisterPrivateExtensionDecoder registers a decoder for the given enterprise ID, replacing any previous one
+func RegisterPrivateExtensionDecoder(enterpriseID uint16, decoder PrivateExtensionDecoder) {
+	privateExtensionMu.Lock()
+	defer privateExtensionMu.Unlock()
+	privateExtensionDecoders[enterpriseID] = decoder
+}
+
+// EnterpriseName returns the vendor name of an enterprise ID, empty when it is unknown
+func EnterpriseName(enterpriseID uint16) string {
+	privateExtensionMu.RLock()
+	defer privateExtensionMu.RUnlock()
+	return enterpriseNames[enterpriseID]
+}
+
+// DecodePrivateExtension decodes the Private Extension IE from a byte slice
+func DecodePrivateExtension(data []byte) (interface{}, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("insufficient data for Private Extension: expected at least 2 bytes, got %d", len(data))
+	}
+
+	enterpriseID := binary.BigEndian.Uint16(data[:2])
+	value := data[2:]
+
+	privateExtensionMu.RLock()
+	decoder, exists := privateExtensionDecoders[enterpriseID]
+	vendor := enterpriseNames[enterpriseID]
+	privateExtensionMu.RUnlock()
+
+	pe := PrivateExtension{
+		EnterpriseID: enterpriseID,
+		Vendor:       vendor,
+		Value:        hex.EncodeToString(value),
+	}
+
+	if exists {
+		decodedValue, err := decoder(value)
+		if err != nil {
+			return nil, fmt.Errorf("enterprise %d: %w", enterpriseID, err)
+		}
+		pe.Value = decodedValue
+	}
+
+	return pe, nil
+}
+
+// TLVLayout describes a simple vendor TLV layout carried inside a Private Extension
+type TLVLayout struct {
+	EnterpriseID uint16     `json:"enterpriseId"`
+	Vendor       string     `json:"vendor"`
+	TypeSize     int        `json:"typeSize"`   // 1 or 2 bytes, defaults to 1
+	LengthSize   int        `json:"lengthSize"` // 1 or 2 bytes, defaults to 2
+	Fields       []TLVField `json:"fields"`
+}
+
+// TLVField describes a single TLV type within a TLVLayout
+type TLVField struct {
+	Type   uint16 `json:"type"`
+	Name   string `json:"name"`
+	Format string `json:"format"` // hex, string, uint, ipv4, ipv6, bcd, apn
+}
+
+// TLVOption represents a single decoded vendor TLV
+type TLVOption struct {
+	Type interface{} `json:"Type"`
+	Data interface{} `json:"Data"`
+}
+
+// LoadPrivateExtensionLayouts reads TLV layouts from a JSON file and registers a decoder for each of them
+func LoadPrivateExtensionLayouts(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read private extension layouts: %w", err)
+	}
+
+	var file struct {
+		Layouts []TLVLayout `json:"layouts"`
+	}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return fmt.Errorf("failed to parse private extension layouts: %w", err)
+	}
+
+	for _, layout := range file.Layouts {
+		if err := RegisterTLVLayout(layout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterTLVLayout registers a decoder built from the given layout and names its vendor
+func RegisterTLVLayout(layout TLVLayout) error {
+	if layout.TypeSize == 0 {
+		layout.TypeSize = 1
+	}
+	if layout.LengthSize == 0 {
+		layout.LengthSize = 2
+	}
+	if layout.TypeSize > 2 || layout.LengthSize > 2 || layout.TypeSize < 0 || layout.LengthSize < 0 {
+		return fmt.Errorf("enterprise %d: type and length sizes must be 1 or 2 bytes", layout.EnterpriseID)
+	}
+
+	fields := make(map[uint16]TLVField, len(layout.Fields))
+	for _, field := range layout.Fields {
+		if _, exists := tlvFormatDecoders[field.Format]; !exists && field.Format != "" {
+			return fmt.Errorf("enterprise %d: unknown format %q for type %d", layout.EnterpriseID, field.Format, field.Type)
+		}
+		fields[field.Type] = field
+	}
+
+	RegisterPrivateExtensionDecoder(layout.EnterpriseID, func(data []byte) (interface{}, error) {
+		return decodeTLVLayout(data, layout, fields)
+	})
+
+	if layout.Vendor != "" {
+		privateExtensionMu.Lock()
+		enterpriseNames[layout.EnterpriseID] = layout.Vendor
+		privateExtensionMu.Unlock()
+	}
+
+	return nil
+}
+
+// tlvFormatDecoders maps layout field formats to their decoders
+var tlvFormatDecoders = map[string]func([]byte) (interface{}, error){
+	"hex": func(data []byte) (interface{}, error) {
+		return hex.EncodeToString(data), nil
+	},
+	"string": func(data []byte) (interface{}, error) {
+		return string(data), nil
+	},
+	"uint": func(data []byte) (interface{}, error) {
+		if len(data) > 8 {
+			return nil, fmt.Errorf("invalid length for uint: expected at most 8 bytes, got %d", len(data))
+		}
+		var value uint64
+		for _, b := range data {
+			value = value<<8 | uint64(b)
+		}
+		return value, nil
+	},
+	"ipv4": DecodeIPv4Address,
+	"ipv6": func(data []byte) (interface{}, error) {
+		if len(data) != 16 {
+			return nil, fmt.Errorf("invalid length for IPv6 Address: expected 16 bytes, got %d", len(data))
+		}
+		return net.IP(data).String(), nil
+	},
+	"bcd": DecodeBCD,
+	"apn": DecodeAPN,
+}
+
+// decodeTLVLayout walks the vendor TLVs and decodes the values of known types
+func decodeTLVLayout(data []byte, layout TLVLayout, fields map[uint16]TLVField) (interface{}, error) {
+	options := make([]TLVOption, 0)
+	headerSize := layout.TypeSize + layout.LengthSize
+	index := 0
+
+	for index < len(data) {
+		if index+headerSize > len(data) {
+			return nil, fmt.Errorf("truncated TLV header at index %d", index)
+		}
+
+		tlvType := readUintN(data[index : index+layout.TypeSize])
+		length := int(readUintN(data[index+layout.TypeSize : index+headerSize]))
+		index += headerSize
+
+		if index+length > len(data) {
+			return nil, fmt.Errorf("TLV %d length %d exceeds remaining data %d", tlvType, length, len(data)-index)
+		}
+
+		value := data[index : index+length]
+		index += length
+
+		field, exists := fields[tlvType]
+		decodeFunc := tlvFormatDecoders["hex"]
+		if exists && field.Format != "" {
+			decodeFunc = tlvFormatDecoders[field.Format]
+		}
+
+		decodedValue, err := decodeFunc(value)
+		if err != nil {
+			return nil, fmt.Errorf("TLV %d: %w", tlvType, err)
+		}
+
+		options = append(options, TLVOption{
+			Type: formatTLVType(tlvType, field.Name),
+			Data: decodedValue,
+		})
+	}
+
+	return options, nil
+}
+
+// readUintN reads a 1 or 2 byte big-endian unsigned integer
+func readUintN(data []byte) uint16 {
+	if len(data) == 1 {
+		return uint16(data[0])
+	}
+	return binary.BigEndian.Uint16(data)
+}
+
+// formatTLVType returns the formatted vendor TLV type based on the selected format.
+func formatTLVType(tlvType uint16, name string) interface{} {
+	if name == "" {
+		name = "Unknown Type"
+	}
+
+	switch config.GetOutputFormat() {
+	case "numeric":
+		return tlvType
+	case "text":
+		return name
+	case "mixed":
+		return fmt.Sprintf("%s (%d)", name, tlvType)
+	default:
+		return tlvType
+	}
+}
@@ -0,0 +1,316 @@
+package gtp2ie
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+)
+
+// QoSRuleOperationNames maps QoS rule operation codes to their descriptions (3GPP TS 24.501 9.11.4.13)
+var QoSRuleOperationNames = map[uint8]string{
+	1: "Create new QoS rule",
+	2: "Delete existing QoS rule",
+	3: "Modify existing QoS rule and add packet filters",
+	4: "Modify existing QoS rule and replace all packet filters",
+	5: "Modify existing QoS rule and delete packet filters",
+	6: "Modify existing QoS rule without modifying packet filters",
+}
+
+// PacketFilterDirectionNames maps packet filter directions to their descriptions
+var PacketFilterDirectionNames = map[uint8]string{
+	0: "Reserved",
+	1: "Downlink only",
+	2: "Uplink only",
+	3: "Bidirectional",
+}
+
+// QoSFlowOperationNames maps QoS flow description operation codes to their descriptions (3GPP TS 24.501 9.11.4.12)
+var QoSFlowOperationNames = map[uint8]string{
+	1: "Create new QoS flow description",
+	2: "Delete existing QoS flow description",
+	3: "Modify existing QoS flow description",
+}
+
+// QoSFlowParameterNames maps QoS flow parameter identifiers to their descriptions
+var QoSFlowParameterNames = map[uint8]string{
+	0x01: "5QI",
+	0x02: "GFBR uplink",
+	0x03: "GFBR downlink",
+	0x04: "MFBR uplink",
+	0x05: "MFBR downlink",
+	0x06: "Averaging window",
+	0x07: "EPS bearer identity",
+}
+
+// QoSRule represents a single QoS rule carried in the QoS rules PCO option
+type QoSRule struct {
+	Identifier    uint8          `json:"Identifier"`
+	Operation     interface{}    `json:"Operation"`
+	DQR           bool           `json:"DQR"` // Default QoS rule
+	PacketFilters []PacketFilter `json:"PacketFilters,omitempty"`
+	Precedence    *uint8         `json:"Precedence,omitempty"`
+	Segregation   bool           `json:"Segregation"`
+	QFI           *uint8         `json:"QFI,omitempty"`
+}
+
+// PacketFilter represents a packet filter within a QoS rule
+type PacketFilter struct {
+	Identifier uint8       `json:"Identifier"`
+	Direction  interface{} `json:"Direction,omitempty"`
+	Components string      `json:"Components,omitempty"`
+}
+
+// QoSFlowDescription represents a single QoS flow description carried in the QoS flow descriptions PCO option
+type QoSFlowDescription struct {
+	QFI        uint8              `json:"QFI"`
+	Operation  interface{}        `json:"Operation"`
+	E          bool               `json:"E"`
+	Parameters []QoSFlowParameter `json:"Parameters,omitempty"`
+}
+
+// QoSFlowParameter represents a single parameter of a QoS flow description
+type QoSFlowParameter struct {
+	Identifier interface{} `json:"Identifier"`
+	Value      interface{} `json:"Value"`
+}
+
+// DecodeQoSRules decodes the QoS rules PCO option
+func DecodeQoSRules(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	format := config.GetOutputFormat()
+	rules := make([]QoSRule, 0)
+	index := 0
+
+	for index < len(data) {
+		if index+4 > len(data) {
+			return nil, fmt.Errorf("truncated QoS rule header at index %d", index)
+		}
+
+		ruleLength := int(binary.BigEndian.Uint16(data[index+1 : index+3]))
+		if index+3+ruleLength > len(data) || ruleLength < 1 {
+			return nil, fmt.Errorf("QoS rule length %d exceeds remaining data %d at index %d", ruleLength, len(data)-index-3, index)
+		}
+
+		ruleData := data[index+3 : index+3+ruleLength]
+		operation := ruleData[0] >> 5
+		description, exists := QoSRuleOperationNames[operation]
+		if !exists {
+			description = fmt.Sprintf("Unknown Operation (%d)", operation)
+		}
+
+		rule := QoSRule{
+			Identifier: data[index],
+			Operation:  formatDescription(description, operation, format),
+			DQR:        ruleData[0]&0x10 != 0,
+		}
+
+		filters, ruleIndex, err := decodePacketFilters(ruleData, int(ruleData[0]&0x0F), operation == 5)
+		if err != nil {
+			return nil, fmt.Errorf("QoS rule %d: %w", rule.Identifier, err)
+		}
+		rule.PacketFilters = filters
+
+		// Precedence and QFI are not included when the rule is being deleted
+		if ruleIndex+2 <= len(ruleData) {
+			precedence := ruleData[ruleIndex]
+			qfi := ruleData[ruleIndex+1] & 0x3F
+			rule.Precedence = &precedence
+			rule.Segregation = ruleData[ruleIndex+1]&0x40 != 0
+			rule.QFI = &qfi
+		}
+
+		rules = append(rules, rule)
+		index += 3 + ruleLength
+	}
+
+	return rules, nil
+}
+
+// decodePacketFilters decodes the packet filter list that follows the QoS rule flags octet
+func decodePacketFilters(ruleData []byte, count int, identifiersOnly bool) ([]PacketFilter, int, error) {
+	format := config.GetOutputFormat()
+	var filters []PacketFilter
+	index := 1
+
+	for i := 0; i < count; i++ {
+		if index >= len(ruleData) {
+			return nil, 0, fmt.Errorf("truncated packet filter %d", i)
+		}
+
+		filter := PacketFilter{Identifier: ruleData[index] & 0x0F}
+		if identifiersOnly {
+			filters = append(filters, filter)
+			index++
+			continue
+		}
+
+		if index+2 > len(ruleData) {
+			return nil, 0, fmt.Errorf("truncated packet filter %d", i)
+		}
+
+		direction := (ruleData[index] >> 4) & 0x03
+		filter.Direction = formatDescription(PacketFilterDirectionNames[direction], direction, format)
+		length := int(ruleData[index+1])
+		index += 2
+
+		if index+length > len(ruleData) {
+			return nil, 0, fmt.Errorf("packet filter %d length %d exceeds remaining data %d", i, length, len(ruleData)-index)
+		}
+		filter.Components = hex.EncodeToString(ruleData[index : index+length])
+		index += length
+
+		filters = append(filters, filter)
+	}
+
+	return filters, index, nil
+}
+
+// DecodeQoSFlowDescriptions decodes the QoS flow descriptions PCO option
+func DecodeQoSFlowDescriptions(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	format := config.GetOutputFormat()
+	descriptions := make([]QoSFlowDescription, 0)
+	index := 0
+
+	for index < len(data) {
+		if index+3 > len(data) {
+			return nil, fmt.Errorf("truncated QoS flow description at index %d", index)
+		}
+
+		operation := data[index+1] >> 5
+		description, exists := QoSFlowOperationNames[operation]
+		if !exists {
+			description = fmt.Sprintf("Unknown Operation (%d)", operation)
+		}
+
+		flow := QoSFlowDescription{
+			QFI:       data[index] & 0x3F,
+			Operation: formatDescription(description, operation, format),
+			E:         data[index+2]&0x40 != 0,
+		}
+		count := int(data[index+2] & 0x3F)
+		index += 3
+
+		for i := 0; i < count; i++ {
+			if index+2 > len(data) {
+				return nil, fmt.Errorf("truncated QoS flow parameter at index %d", index)
+			}
+
+			parameterID := data[index]
+			length := int(data[index+1])
+			index += 2
+			if index+length > len(data) {
+				return nil, fmt.Errorf("QoS flow parameter length %d exceeds remaining data %d at index %d", length, len(data)-index, index)
+			}
+
+			value, err := decodeQoSFlowParameter(parameterID, data[index:index+length])
+			if err != nil {
+				return nil, fmt.Errorf("QoS flow %d: %w", flow.QFI, err)
+			}
+			index += length
+
+			name, exists := QoSFlowParameterNames[parameterID]
+			if !exists {
+				name = fmt.Sprintf("Unknown Parameter (%d)", parameterID)
+			}
+
+			flow.Parameters = append(flow.Parameters, QoSFlowParameter{
+				Identifier: formatDescription(name, parameterID, format),
+				Value:      value,
+			})
+		}
+
+		descriptions = append(descriptions, flow)
+	}
+
+	return descriptions, nil
+}
+
+// decodeQoSFlowParameter decodes a QoS flow parameter value, bit rates are returned in kbps
+func decodeQoSFlowParameter(parameterID uint8, data []byte) (interface{}, error) {
+	switch parameterID {
+	case 0x01:
+		if len(data) != 1 {
+			return nil, fmt.Errorf("invalid length for 5QI: expected 1 byte, got %d", len(data))
+		}
+		return data[0], nil
+	case 0x02, 0x03, 0x04, 0x05:
+		if len(data) != 3 {
+			return nil, fmt.Errorf("invalid length for bit rate: expected 3 bytes, got %d", len(data))
+		}
+		return decodeBitRate(data[0], binary.BigEndian.Uint16(data[1:3])), nil
+	case 0x06:
+		if len(data) != 2 {
+			return nil, fmt.Errorf("invalid length for averaging window: expected 2 bytes, got %d", len(data))
+		}
+		return binary.BigEndian.Uint16(data), nil
+	case 0x07:
+		if len(data) != 1 {
+			return nil, fmt.Errorf("invalid length for EPS bearer identity: expected 1 byte, got %d", len(data))
+		}
+		return EBI(data[0] >> 4), nil
+	default:
+		return hex.EncodeToString(data), nil
+	}
+}
+
+// SNSSAI represents the S-NSSAI PCO option, the value part of the S-NSSAI IE (3GPP TS 24.501 9.11.2.8)
+type SNSSAI struct {
+	SST            uint8  `json:"SST"`
+	SD             string `json:"SD,omitempty"`
+	MappedHPLMNSST *uint8 `json:"MappedHPLMNSST,omitempty"`
+	MappedHPLMNSD  string `json:"MappedHPLMNSD,omitempty"`
+}
+
+// DecodeSNSSAI decodes the S-NSSAI PCO option, whose length tells which fields are present
+func DecodeSNSSAI(data []byte) (interface{}, error) {
+	snssai := SNSSAI{}
+	switch len(data) {
+	case 1:
+		snssai.SST = data[0]
+	case 2:
+		snssai.SST, snssai.MappedHPLMNSST = data[0], &data[1]
+	case 4:
+		snssai.SST, snssai.SD = data[0], hex.EncodeToString(data[1:4])
+	case 5:
+		snssai.SST, snssai.SD, snssai.MappedHPLMNSST = data[0], hex.EncodeToString(data[1:4]), &data[4]
+	case 8:
+		snssai.SST, snssai.SD, snssai.MappedHPLMNSST = data[0], hex.EncodeToString(data[1:4]), &data[4]
+		snssai.MappedHPLMNSD = hex.EncodeToString(data[5:8])
+	default:
+		return nil, fmt.Errorf("invalid length for S-NSSAI: %d bytes", len(data))
+	}
+	return snssai, nil
+}
+
+// SessionAMBR represents the Session-AMBR PCO option in kbps (3GPP TS 24.501 9.11.4.14)
+type SessionAMBR struct {
+	Downlink uint64 `json:"Downlink"`
+	Uplink   uint64 `json:"Uplink"`
+}
+
+// DecodeSessionAMBR decodes the Session-AMBR PCO option, a unit and a value per direction
+func DecodeSessionAMBR(data []byte) (interface{}, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("insufficient data for Session-AMBR: expected 6 bytes, got %d", len(data))
+	}
+	return SessionAMBR{
+		Downlink: decodeBitRate(data[0], binary.BigEndian.Uint16(data[1:3])),
+		Uplink:   decodeBitRate(data[3], binary.BigEndian.Uint16(data[4:6])),
+	}, nil
+}
+
+// decodeBitRate converts a 5GS bit rate unit and value into kbps, each unit step multiplies by 4
+func decodeBitRate(unit uint8, value uint16) uint64 {
+	if unit == 0 || unit > 25 {
+		return 0
+	}
+	return uint64(value) << (2 * uint64(unit-1))
+}
@@ -0,0 +1,49 @@
+package gtp2ie
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+)
+
+// SecondaryRATTypeNames maps Secondary RAT Type values to their descriptions
+var SecondaryRATTypeNames = map[uint8]string{
+	0: "NR",
+	1: "Unlicensed Spectrum",
+}
+
+// SecondaryRATUsageDataReport represents Secondary RAT Usage Data Report IE (3GPP TS 29.274 8.127)
+type SecondaryRATUsageDataReport struct {
+	IRPGW          bool        `json:"IRPGW"` // Intended Receiver PGW
+	IRSGW          bool        `json:"IRSGW"` // Intended Receiver SGW
+	RATType        interface{} `json:"RATType"`
+	EBI            EBI         `json:"EBI"`
+	StartTimestamp interface{} `json:"StartTimestamp"`
+	EndTimestamp   interface{} `json:"EndTimestamp"`
+	UsageDataDL    uint64      `json:"UsageDataDL"`
+	UsageDataUL    uint64      `json:"UsageDataUL"`
+}
+
+// DecodeSecondaryRATUsageDataReport decodes the Secondary RAT Usage Data Report IE from a byte slice
+func DecodeSecondaryRATUsageDataReport(data []byte) (interface{}, error) {
+	if len(data) < 27 {
+		return nil, fmt.Errorf("insufficient data for Secondary RAT Usage Data Report: expected at least 27 bytes, got %d", len(data))
+	}
+
+	ratType := data[1]
+	description, exists := SecondaryRATTypeNames[ratType]
+	if !exists {
+		description = fmt.Sprintf("Unknown Secondary RAT Type (%d)", ratType)
+	}
+
+	return SecondaryRATUsageDataReport{
+		IRPGW:          data[0]&0x01 != 0,
+		IRSGW:          data[0]&0x02 != 0,
+		RATType:        formatDescription(description, ratType, config.GetOutputFormat()),
+		EBI:            EBI(data[2] & 0x0F),
+		StartTimestamp: formatTime(ntpSecondsToTime(binary.BigEndian.Uint32(data[3:7]))),
+		EndTimestamp:   formatTime(ntpSecondsToTime(binary.BigEndian.Uint32(data[7:11]))),
+		UsageDataDL:    binary.BigEndian.Uint64(data[11:19]),
+		UsageDataUL:    binary.BigEndian.Uint64(data[19:27]),
+	}, nil
+}
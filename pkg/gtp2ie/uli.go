@@ -98,8 +98,8 @@ func decodeLAI(data []byte) (LAI, int, error) {
 	}, 5, nil
 }
 
-// decodeCGI decodes Cell Global Identity from a slice of bytes
-func decodeCGI(data []byte) (CGI, int, error) {
+// DecodeCGI decodes Cell Global Identity from a slice of bytes
+func DecodeCGI(data []byte) (CGI, int, error) {
 	// 3 for MCC/MNC, 2 for LAC, 2 for CI
 	if len(data) < 7 {
 		return CGI{}, 0, fmt.Errorf("not enough data for CGI")
@@ -120,8 +120,8 @@ func decodeCGI(data []byte) (CGI, int, error) {
 	}, 7, nil
 }
 
-// decodeSAI decodes Service Area Identity from a slice of bytes
-func decodeSAI(data []byte) (SAI, int, error) {
+// DecodeSAI decodes Service Area Identity from a slice of bytes
+func DecodeSAI(data []byte) (SAI, int, error) {
 	// 7 bytes needed: 3 for MCC/MNC, 2 for LAC, 2 for SAC
 	if len(data) < 7 {
 		return SAI{}, 0, fmt.Errorf("not enough data for SAI")
@@ -236,7 +236,7 @@ func DecodeULI(data []byte) (interface{}, error) {
 
 	// decode CGI if present
 	if flags&0x01 != 0 && len(data) > index {
-		cgi, nextIndex, err := decodeCGI(data[index:])
+		cgi, nextIndex, err := DecodeCGI(data[index:])
 		if err != nil {
 			return ULI{}, fmt.Errorf("error decoding CGI: %v", err)
 		}
@@ -246,7 +246,7 @@ func DecodeULI(data []byte) (interface{}, error) {
 
 	// decode SAI if present
 	if flags&0x02 != 0 && len(data) > index {
-		sai, nextIndex, err := decodeSAI(data[index:])
+		sai, nextIndex, err := DecodeSAI(data[index:])
 		if err != nil {
 			return ULI{}, fmt.Errorf("error decoding SAI: %v", err)
 		}
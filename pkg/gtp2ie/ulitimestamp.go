@@ -3,7 +3,6 @@ package gtp2ie
 import (
 	"encoding/binary"
 	"fmt"
-	"time"
 )
 
 // DecodeULITimestamp decodes the ULI Timestamp IE from a byte slice
@@ -12,14 +11,20 @@ func DecodeULITimestamp(data []byte) (interface{}, error) {
 		return nil, fmt.Errorf("insufficient data for ULI Timestamp: expected at least 4 bytes, got %d", len(data))
 	}
 
-	// Read the timestamp value
+	// The timestamp is in NTP seconds relative to 1 January 1900
 	timestamp := binary.BigEndian.Uint32(data[:4])
-	// Convert the timestamp to time.Time relative to 1 January 1900
-	epoch := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
-	decodedTimestamp := epoch.Add(time.Duration(timestamp) * time.Second)
 
-	// Format the timestamp
-	formattedTime := decodedTimestamp.Format("Jan 2, 2006 15:04:05 UTC")
+	return formatTime(ntpSecondsToTime(timestamp)), nil
+}
+
+// DecodeMillisecondTimeStamp decodes the Millisecond Time Stamp IE (3GPP TS 29.274 8.119) from a byte slice
+func DecodeMillisecondTimeStamp(data []byte) (interface{}, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("insufficient data for Millisecond Time Stamp: expected at least 6 bytes, got %d", len(data))
+	}
+
+	// 48-bit number of milliseconds since 1 January 1900
+	milliseconds := uint64(binary.BigEndian.Uint16(data[:2]))<<32 | uint64(binary.BigEndian.Uint32(data[2:6]))
 
-	return formattedTime, nil
+	return formatTime(ntpMillisecondsToTime(milliseconds)), nil
 }
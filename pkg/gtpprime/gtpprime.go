@@ -0,0 +1,118 @@
+package gtpprime
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/vagabundor/gtp2json/pkg/gtp1"
+)
+
+// LayerTypeGTPPrime registers GTP' layer type for use with GoPacket
+var LayerTypeGTPPrime = gopacket.RegisterLayerType(1014,
+	gopacket.LayerTypeMetadata{Name: "GTPPrime", Decoder: gopacket.DecodeFunc(decodeGTPPrime)})
+
+const (
+	// shortHeaderSize is the size of the 6-octet header, longHeaderSize of the legacy 20-octet header
+	shortHeaderSize int = 6
+	longHeaderSize  int = 20
+)
+
+// GTPPrime is the protocol used to transfer charging data records to the Charging Gateway Function.
+// Defined in the 3GPP TS 32.295 specification, its IEs share the TV and TLV format with GTPv1.
+type GTPPrime struct {
+	Version        uint8
+	ProtocolType   uint8
+	ShortHeader    bool
+	MessageType    uint8
+	MessageLength  uint16
+	SequenceNumber uint16
+	IEs            []gtp1.IE
+
+	Contents []byte
+	Payload  []byte
+}
+
+func init() {
+	// Registers GTP' to be identified and processed over its standard UDP port, 3386
+	udpPort := layers.UDPPort(3386)
+	layers.RegisterUDPPortLayerType(udpPort, LayerTypeGTPPrime)
+}
+
+// DecodeFromBytes analyses a byte slice and attempts to decode it as a GTP' packet
+func (g *GTPPrime) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	dLen := len(data)
+	if dLen < shortHeaderSize {
+		return fmt.Errorf("GTP' packet too small: %d bytes", dLen)
+	}
+
+	g.Version = (data[0] >> 5) & 0x07
+	g.ProtocolType = (data[0] >> 4) & 0x01
+	// Only version 0 has the 20-octet header, selected when bit 1 is 0 (3GPP TS 32.295 6.1.1).
+	// Versions 1 and 2 send the bit as 0 and always use the 6-octet header.
+	g.ShortHeader = g.Version != 0 || (data[0]&0x01) == 1
+	g.MessageType = data[1]
+	g.MessageLength = binary.BigEndian.Uint16(data[2:4])
+	g.SequenceNumber = binary.BigEndian.Uint16(data[4:6])
+
+	if g.ProtocolType != 0 {
+		return fmt.Errorf("not a GTP' packet: protocol type %d", g.ProtocolType)
+	}
+
+	hLen := longHeaderSize
+	if g.ShortHeader {
+		hLen = shortHeaderSize
+	}
+	pLen := hLen + int(g.MessageLength)
+	if dLen < pLen {
+		return fmt.Errorf("GTP' packet too small: %d bytes", dLen)
+	}
+
+	ies, err := gtp1.DecodeIEs(data[hLen:pLen])
+	if err != nil {
+		return err
+	}
+	g.IEs = ies
+
+	g.Contents = data[:pLen]
+	g.Payload = data[pLen:]
+	return nil
+}
+
+// decodeGTPPrime is a utility function to facilitate the decoding of GTP' packets within GoPacket's framework
+func decodeGTPPrime(data []byte, p gopacket.PacketBuilder) error {
+	gtp := &GTPPrime{}
+
+	if err := gtp.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+
+	p.AddLayer(gtp)
+	return nil
+}
+
+// LayerType returns LayerTypeGTPPrime
+func (g *GTPPrime) LayerType() gopacket.LayerType {
+	return LayerTypeGTPPrime
+}
+
+// LayerContents returns the contents of the GTP' layer.
+func (g *GTPPrime) LayerContents() []byte {
+	return g.Contents
+}
+
+// LayerPayload returns the payload of the GTP' layer.
+func (g *GTPPrime) LayerPayload() []byte {
+	return g.Payload
+}
+
+// CanDecode returns a set of layers that GTP' objects can decode
+func (g *GTPPrime) CanDecode() gopacket.LayerClass {
+	return LayerTypeGTPPrime
+}
+
+// NextLayerType specifies the next layer that GoPacket should attempt to
+func (g *GTPPrime) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
@@ -0,0 +1,89 @@
+package gtpprime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/vagabundor/gtp2json/pkg/gtp1"
+)
+
+// testGTPPrimePacket is a Data Record Transfer Request with a 6-octet header carrying
+// Packet Transfer Command and a Data Record Packet with two records
+var testGTPPrimePacket = []byte{
+	0x84, 0xb5, 0xd1, 0x58, 0x1f, 0xa3, 0x84, 0xb5,
+	0x9c, 0x67, 0x9d, 0x29, 0x08, 0x00, 0x45, 0x00,
+	0x00, 0x36, 0x00, 0x01, 0x00, 0x00, 0x40, 0x11,
+	0x00, 0x00, 0x0a, 0x00, 0x00, 0x02, 0x0a, 0x00,
+	0x00, 0x01, 0x0d, 0x3a, 0x0d, 0x3a, 0x00, 0x22,
+	0x00, 0x00, 0x4f, 0xf0, 0x00, 0x14, 0x00, 0x07,
+	0x7e, 0x01, 0xfc, 0x00, 0x0f, 0x02, 0x01, 0x2a,
+	0x05, 0x00, 0x05, 0x30, 0x03, 0x80, 0x01, 0x05,
+	0x00, 0x02, 0x30, 0x00,
+}
+
+func TestGTPPrimePacket(t *testing.T) {
+	p := gopacket.NewPacket(testGTPPrimePacket, layers.LayerTypeEthernet, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Error("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+
+	if got, ok := p.Layer(LayerTypeGTPPrime).(*GTPPrime); ok {
+		want := &GTPPrime{
+			Version:        2,
+			ShortHeader:    true,
+			MessageType:    240,
+			MessageLength:  20,
+			SequenceNumber: 7,
+			IEs: []gtp1.IE{
+				{Type: 126, Content: []byte{0x01}},
+				{Type: 252, Content: testGTPPrimePacket[53:68]},
+			},
+
+			Contents: testGTPPrimePacket[42:68],
+			Payload:  []uint8{},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GTP' packet mismatch:\ngot  :\n%#v\n\nwant :\n%#v\n\n", got, want)
+		}
+	} else {
+		t.Error("Incorrect gtp' packet")
+	}
+}
+
+func TestLongHeader(t *testing.T) {
+	// Node Alive Request with the legacy 20-octet header and a Charging Gateway Address IE
+	data := append([]byte{0x0e, 0x04, 0x00, 0x07, 0x00, 0x01}, make([]byte, 14)...)
+	data = append(data, 0xfb, 0x00, 0x04, 0x0a, 0x00, 0x00, 0x09)
+
+	var got GTPPrime
+	if err := got.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatalf("DecodeFromBytes() error = %v", err)
+	}
+	if got.ShortHeader || got.MessageType != 4 || len(got.IEs) != 1 || got.IEs[0].Type != 251 {
+		t.Errorf("unexpected GTP' packet: %#v", got)
+	}
+
+	if err := got.DecodeFromBytes(data[:20], gopacket.NilDecodeFeedback); err == nil {
+		t.Error("expected error for truncated packet")
+	}
+}
+
+func TestShortHeaderVersions(t *testing.T) {
+	// Node Alive Request carrying a Charging Gateway Address IE, the header bit is 0 in versions 1 and 2
+	ie := []byte{0xfb, 0x00, 0x04, 0x0a, 0x00, 0x00, 0x09}
+	for _, first := range []byte{0x2e, 0x4e, 0x4f} {
+		data := append([]byte{first, 0x04, 0x00, 0x07, 0x00, 0x01}, ie...)
+
+		var got GTPPrime
+		if err := got.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+			t.Fatalf("DecodeFromBytes(%#x) error = %v", first, err)
+		}
+		want := []gtp1.IE{{Type: 251, Content: []byte{0x0a, 0x00, 0x00, 0x09}}}
+		if !got.ShortHeader || got.Version != first>>5 || !reflect.DeepEqual(got.IEs, want) {
+			t.Errorf("DecodeFromBytes(%#x) = %#v", first, got)
+		}
+	}
+}
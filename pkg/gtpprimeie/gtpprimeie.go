@@ -0,0 +1,212 @@
+package gtpprimeie
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+	"github.com/vagabundor/gtp2json/pkg/gtp1"
+	"github.com/vagabundor/gtp2json/pkg/gtp1ie"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+)
+
+const (
+	IETypeCause                      = 1
+	IETypeRecovery                   = 14
+	IETypePacketTransferCommand      = 126
+	IETypeSequenceNumbersOfCancelled = 249
+	IETypeSequenceNumbersOfReleased  = 250
+	IETypeChargingGatewayAddress     = 251
+	IETypeDataRecordPacket           = 252
+	IETypeRequestsResponded          = 253
+	IETypeAddressOfRecommendedNode   = 254
+	IETypePrivateExtension           = 255
+)
+
+// ieTypeNames maps IE types to their string representations
+var ieTypeNames = map[uint8]string{
+	IETypeCause:                      "Cause",
+	IETypeRecovery:                   "Recovery",
+	IETypePacketTransferCommand:      "PacketTransferCommand",
+	IETypeSequenceNumbersOfCancelled: "SequenceNumbersOfCancelledPackets",
+	IETypeSequenceNumbersOfReleased:  "SequenceNumbersOfReleasedPackets",
+	IETypeChargingGatewayAddress:     "ChargingGatewayAddress",
+	IETypeDataRecordPacket:           "DataRecordPacket",
+	IETypeRequestsResponded:          "RequestsResponded",
+	IETypeAddressOfRecommendedNode:   "AddressOfRecommendedNode",
+	IETypePrivateExtension:           "PrivateExtension",
+}
+
+// CauseDescriptions maps GTP' specific cause values (3GPP TS 32.295 6.2.4.1), other values follow GTPv1
+var CauseDescriptions = map[byte]string{
+	59:  "System failure",
+	60:  "The transmit buffers are becoming full",
+	61:  "The receive buffers are becoming full",
+	62:  "Another node is about to go down",
+	63:  "This node is about to go down",
+	177: "CDR decoding error",
+	252: "Request related to possibly duplicated packets already fulfilled",
+	253: "Request already fulfilled",
+	254: "Sequence numbers of released/cancelled packets IE incorrect",
+	255: "Request not fulfilled",
+}
+
+// PacketTransferCommandNames maps Packet Transfer Command values (3GPP TS 32.295 6.2.4.4)
+var PacketTransferCommandNames = map[uint8]string{
+	1: "Send Data Record Packet",
+	2: "Send possibly duplicated Data Record Packet",
+	3: "Cancel Data Record Packet",
+	4: "Release Data Record Packet",
+}
+
+// DataRecordFormatNames maps Data Record Format values (3GPP TS 32.295 6.2.4.5.3)
+var DataRecordFormatNames = map[uint8]string{
+	1: "ASN.1 BER",
+	2: "ASN.1 unaligned PER",
+	3: "ASN.1 aligned PER",
+}
+
+// DataRecordPacket represents Data Record Packet IE (3GPP TS 32.295 6.2.4.5.3)
+type DataRecordPacket struct {
+	NumberOfDataRecords   uint8       `json:"NumberOfDataRecords"`
+	DataRecordFormat      interface{} `json:"DataRecordFormat"`
+	ApplicationIdentifier uint8       `json:"ApplicationIdentifier"`
+	ReleaseIdentifier     uint8       `json:"ReleaseIdentifier"`
+	VersionIdentifier     uint8       `json:"VersionIdentifier"`
+	DataRecords           []string    `json:"DataRecords"` // base64 encoded ASN.1 CDRs
+}
+
+// ProcessIE decodes the content of a given IE based on its type
+func ProcessIE(ie gtp1.IE) (string, interface{}, error) {
+
+	ieName, ok := ieTypeNames[ie.Type]
+	if !ok {
+		// Unknown type encode to hex
+		return fmt.Sprintf("unknown_type_%d", ie.Type), hex.EncodeToString(ie.Content), nil
+	}
+
+	var decodeFunc func([]byte) (interface{}, error)
+	switch ie.Type {
+	case IETypeCause:
+		decodeFunc = DecodeCause
+	case IETypeRecovery:
+		decodeFunc = gtp2ie.DecodeRecovery
+	case IETypePacketTransferCommand:
+		decodeFunc = DecodePacketTransferCommand
+	case IETypeSequenceNumbersOfCancelled, IETypeSequenceNumbersOfReleased, IETypeRequestsResponded:
+		decodeFunc = DecodeSequenceNumbers
+	case IETypeChargingGatewayAddress, IETypeAddressOfRecommendedNode:
+		decodeFunc = gtp1ie.DecodeGSNAddress
+	case IETypeDataRecordPacket:
+		decodeFunc = DecodeDataRecordPacket
+	case IETypePrivateExtension:
+		decodeFunc = gtp2ie.DecodePrivateExtension
+	default:
+		return ieName, hex.EncodeToString(ie.Content), nil
+	}
+
+	decodedContent, err := decodeFunc(ie.Content)
+	if err != nil {
+		return ieName, nil, fmt.Errorf("failed to decode %s: %w", ieName, err)
+	}
+
+	return ieName, decodedContent, nil
+}
+
+// formatValue returns the formatted value based on the selected format
+func formatValue(description string, value uint8) interface{} {
+	switch config.GetOutputFormat() {
+	case "numeric":
+		return value
+	case "text":
+		return description
+	case "mixed":
+		return fmt.Sprintf("%s (%d)", description, value)
+	default:
+		return value
+	}
+}
+
+// DecodeCause decodes the GTP' Cause IE
+func DecodeCause(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Cause")
+	}
+
+	description, exists := CauseDescriptions[data[0]]
+	if !exists {
+		description, exists = gtp1ie.CauseDescriptions[data[0]]
+	}
+	if !exists {
+		description = fmt.Sprintf("Unknown Cause (%d)", data[0])
+	}
+
+	return gtp2ie.Cause{CauseValue: formatValue(description, data[0])}, nil
+}
+
+// DecodePacketTransferCommand decodes the Packet Transfer Command IE
+func DecodePacketTransferCommand(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Packet Transfer Command")
+	}
+
+	description, exists := PacketTransferCommandNames[data[0]]
+	if !exists {
+		description = fmt.Sprintf("Unknown Command (%d)", data[0])
+	}
+
+	return formatValue(description, data[0]), nil
+}
+
+// DecodeSequenceNumbers decodes IEs holding a list of 2-octet GTP' sequence numbers
+func DecodeSequenceNumbers(data []byte) (interface{}, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("invalid length for sequence number list: %d", len(data))
+	}
+
+	numbers := make([]uint16, 0, len(data)/2)
+	for i := 0; i < len(data); i += 2 {
+		numbers = append(numbers, binary.BigEndian.Uint16(data[i:i+2]))
+	}
+
+	return numbers, nil
+}
+
+// DecodeDataRecordPacket decodes the Data Record Packet IE, each data record is emitted as base64
+func DecodeDataRecordPacket(data []byte) (interface{}, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("insufficient data for Data Record Packet: expected at least 4 bytes, got %d", len(data))
+	}
+
+	format := data[1]
+	description, exists := DataRecordFormatNames[format]
+	if !exists {
+		description = fmt.Sprintf("Operator specific (%d)", format)
+	}
+
+	packet := DataRecordPacket{
+		NumberOfDataRecords:   data[0],
+		DataRecordFormat:      formatValue(description, format),
+		ApplicationIdentifier: data[2] >> 4,
+		ReleaseIdentifier:     data[2] & 0x0F,
+		VersionIdentifier:     data[3],
+		DataRecords:           make([]string, 0, data[0]),
+	}
+
+	index := 4
+	for i := 0; i < int(packet.NumberOfDataRecords); i++ {
+		if index+2 > len(data) {
+			return nil, fmt.Errorf("truncated length of data record %d", i)
+		}
+		length := int(binary.BigEndian.Uint16(data[index : index+2]))
+		index += 2
+		if index+length > len(data) {
+			return nil, fmt.Errorf("data record %d length %d exceeds remaining data %d", i, length, len(data)-index)
+		}
+		packet.DataRecords = append(packet.DataRecords, base64.StdEncoding.EncodeToString(data[index:index+length]))
+		index += length
+	}
+
+	return packet, nil
+}
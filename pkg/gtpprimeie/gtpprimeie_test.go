@@ -0,0 +1,101 @@
+package gtpprimeie
+
+import (
+	"github.com/vagabundor/gtp2json/config"
+	"github.com/vagabundor/gtp2json/pkg/gtp1"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+	"reflect"
+	"testing"
+)
+
+func TestProcessIE(t *testing.T) {
+	tests := []struct {
+		name    string
+		ie      gtp1.IE
+		format  string
+		want    string
+		want1   interface{}
+		wantErr bool
+	}{
+		{
+			name:   "Data Record Packet",
+			ie:     gtp1.IE{Type: IETypeDataRecordPacket, Content: []byte{0x02, 0x01, 0x2a, 0x05, 0x00, 0x05, 0x30, 0x03, 0x80, 0x01, 0x05, 0x00, 0x02, 0x30, 0x00}},
+			format: "mixed",
+			want:   "DataRecordPacket",
+			want1: DataRecordPacket{
+				NumberOfDataRecords:   2,
+				DataRecordFormat:      "ASN.1 BER (1)",
+				ApplicationIdentifier: 2,
+				ReleaseIdentifier:     10,
+				VersionIdentifier:     5,
+				DataRecords:           []string{"MAOAAQU=", "MAA="},
+			},
+		},
+		{
+			name:    "Data Record Packet Truncated Record",
+			ie:      gtp1.IE{Type: IETypeDataRecordPacket, Content: []byte{0x01, 0x01, 0x2a, 0x05, 0x00, 0x05, 0x30}},
+			format:  "numeric",
+			want:    "DataRecordPacket",
+			wantErr: true,
+		},
+		{
+			name:   "Packet Transfer Command",
+			ie:     gtp1.IE{Type: IETypePacketTransferCommand, Content: []byte{0x02}},
+			format: "text",
+			want:   "PacketTransferCommand",
+			want1:  "Send possibly duplicated Data Record Packet",
+		},
+		{
+			name:   "Cause GTP' Specific",
+			ie:     gtp1.IE{Type: IETypeCause, Content: []byte{0xfd}},
+			format: "text",
+			want:   "Cause",
+			want1:  gtp2ie.Cause{CauseValue: "Request already fulfilled"},
+		},
+		{
+			name:   "Cause Shared With GTPv1",
+			ie:     gtp1.IE{Type: IETypeCause, Content: []byte{0x80}},
+			format: "text",
+			want:   "Cause",
+			want1:  gtp2ie.Cause{CauseValue: "Request accepted"},
+		},
+		{
+			name:   "Requests Responded",
+			ie:     gtp1.IE{Type: IETypeRequestsResponded, Content: []byte{0x00, 0x07, 0x00, 0x08}},
+			format: "numeric",
+			want:   "RequestsResponded",
+			want1:  []uint16{7, 8},
+		},
+		{
+			name:   "Charging Gateway Address",
+			ie:     gtp1.IE{Type: IETypeChargingGatewayAddress, Content: []byte{0x0a, 0x00, 0x00, 0x09}},
+			format: "numeric",
+			want:   "ChargingGatewayAddress",
+			want1:  "10.0.0.9",
+		},
+		{
+			name:   "Unknown IE Type",
+			ie:     gtp1.IE{Type: 200, Content: []byte{0x01}},
+			format: "numeric",
+			want:   "unknown_type_200",
+			want1:  "01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.SetOutputFormat(tt.format)
+			got, got1, err := ProcessIE(tt.ie)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ProcessIE() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ProcessIE() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(got1, tt.want1) {
+				t.Errorf("ProcessIE() got1 = %#v, want %#v", got1, tt.want1)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package gtpu
+
+import (
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/vagabundor/gtp2json/pkg/gtp1"
+)
+
+// LayerTypeGTPU registers GTP-U layer type for use with GoPacket
+var LayerTypeGTPU = gopacket.RegisterLayerType(1012,
+	gopacket.LayerTypeMetadata{Name: "GTPU", Decoder: gopacket.DecodeFunc(decodeGTPU)})
+
+// GTP-U message types (3GPP TS 29.281 6.1)
+const (
+	MessageTypeEchoRequest                           = 1
+	MessageTypeEchoResponse                          = 2
+	MessageTypeErrorIndication                       = 26
+	MessageTypeSupportedExtensionHeadersNotification = 31
+	MessageTypeEndMarker                             = 254
+	MessageTypeGPDU                                  = 255
+)
+
+// GTPU is the user plane protocol carrying subscriber traffic between the RAN and the core network.
+// Defined in the 3GPP TS 29.281 specification, the header is shared with GTPv1-C.
+type GTPU struct {
+	gtp1.GTPv1
+}
+
+// Enable registers GTP-U on its standard UDP port, 2152, replacing the GoPacket built-in decoder
+func Enable() {
+	layers.RegisterUDPPortLayerType(layers.UDPPort(2152), LayerTypeGTPU)
+}
+
+// IsGPDU reports whether the packet carries user data rather than a control message
+func (g *GTPU) IsGPDU() bool {
+	return g.MessageType == MessageTypeGPDU
+}
+
+// DecodeFromBytes analyses a byte slice and attempts to decode it as a GTP-U packet
+func (g *GTPU) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	cIndex, err := g.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	if g.Version != 1 || g.ProtocolType != 1 {
+		return fmt.Errorf("not a GTP-U packet: version %d, protocol type %d", g.Version, g.ProtocolType)
+	}
+
+	end := 8 + int(g.MessageLength)
+	if g.IsGPDU() {
+		// The T-PDU follows the header and is left for the next layer
+		g.Contents = data[:cIndex]
+		g.Payload = data[cIndex:end]
+		return nil
+	}
+
+	ies, err := gtp1.DecodeIEs(data[cIndex:end])
+	if err != nil {
+		return err
+	}
+	g.IEs = ies
+
+	g.Contents = data[:end]
+	g.Payload = data[end:]
+	return nil
+}
+
+// decodeGTPU is a utility function to facilitate the decoding of GTP-U packets within GoPacket's framework
+func decodeGTPU(data []byte, p gopacket.PacketBuilder) error {
+	gtp := &GTPU{}
+
+	if err := gtp.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+
+	p.AddLayer(gtp)
+	if len(gtp.Payload) == 0 {
+		return nil
+	}
+	return p.NextDecoder(gtp.NextLayerType())
+}
+
+// LayerType returns LayerTypeGTPU
+func (g *GTPU) LayerType() gopacket.LayerType {
+	return LayerTypeGTPU
+}
+
+// CanDecode returns a set of layers that GTP-U objects can decode
+func (g *GTPU) CanDecode() gopacket.LayerClass {
+	return LayerTypeGTPU
+}
+
+// NextLayerType specifies the next layer that GoPacket should attempt to decode, the inner IP packet of a G-PDU
+func (g *GTPU) NextLayerType() gopacket.LayerType {
+	if !g.IsGPDU() || len(g.Payload) == 0 {
+		return gopacket.LayerTypePayload
+	}
+	switch g.Payload[0] >> 4 {
+	case 4:
+		return layers.LayerTypeIPv4
+	case 6:
+		return layers.LayerTypeIPv6
+	default:
+		return gopacket.LayerTypePayload
+	}
+}
@@ -0,0 +1,179 @@
+package gtpu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/vagabundor/gtp2json/config"
+	"github.com/vagabundor/gtp2json/pkg/gtp1"
+	"github.com/vagabundor/gtp2json/pkg/gtp1ie"
+)
+
+// testErrorIndicationPacket is an Error Indication carrying TEID Data I and GSN Address IEs
+var testErrorIndicationPacket = []byte{
+	0x84, 0xb5, 0xd1, 0x58, 0x1f, 0xa3, 0x84, 0xb5,
+	0x9c, 0x67, 0x9d, 0x29, 0x08, 0x00, 0x45, 0x00,
+	0x00, 0x34, 0x00, 0x01, 0x00, 0x00, 0x40, 0x11,
+	0x00, 0x00, 0x0a, 0x00, 0x00, 0x02, 0x0a, 0x00,
+	0x00, 0x01, 0x08, 0x68, 0x08, 0x68, 0x00, 0x20,
+	0x00, 0x00, 0x32, 0x1a, 0x00, 0x10, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x10, 0x00,
+	0x00, 0x0a, 0xbc, 0x85, 0x00, 0x04, 0x0a, 0x00,
+	0x00, 0x01,
+}
+
+// testGPDUPacket is a G-PDU carrying an inner IPv4 header
+var testGPDUPacket = []byte{
+	0x84, 0xb5, 0xd1, 0x58, 0x1f, 0xa3, 0x84, 0xb5,
+	0x9c, 0x67, 0x9d, 0x29, 0x08, 0x00, 0x45, 0x00,
+	0x00, 0x38, 0x00, 0x01, 0x00, 0x00, 0x40, 0x11,
+	0x00, 0x00, 0x0a, 0x00, 0x00, 0x02, 0x0a, 0x00,
+	0x00, 0x01, 0x08, 0x68, 0x08, 0x68, 0x00, 0x24,
+	0x00, 0x00, 0x30, 0xff, 0x00, 0x14, 0x00, 0x00,
+	0x12, 0x34, 0x45, 0x00, 0x00, 0x14, 0x00, 0x00,
+	0x00, 0x00, 0x40, 0x06, 0x00, 0x00, 0xc0, 0xa8,
+	0x01, 0x01, 0x08, 0x08, 0x08, 0x08,
+}
+
+// testSupportedExtensionHeadersNotificationPacket lists the PDCP PDU Number and PDU Session Container headers
+var testSupportedExtensionHeadersNotificationPacket = []byte{
+	0x84, 0xb5, 0xd1, 0x58, 0x1f, 0xa3, 0x84, 0xb5,
+	0x9c, 0x67, 0x9d, 0x29, 0x08, 0x00, 0x45, 0x00,
+	0x00, 0x2c, 0x00, 0x01, 0x00, 0x00, 0x40, 0x11,
+	0x00, 0x00, 0x0a, 0x00, 0x00, 0x02, 0x0a, 0x00,
+	0x00, 0x01, 0x08, 0x68, 0x08, 0x68, 0x00, 0x18,
+	0x00, 0x00, 0x32, 0x1f, 0x00, 0x08, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x8d, 0x02,
+	0xc0, 0x85,
+}
+
+func TestErrorIndicationPacket(t *testing.T) {
+	Enable()
+
+	p := gopacket.NewPacket(testErrorIndicationPacket, layers.LayerTypeEthernet, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Error("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+
+	if got, ok := p.Layer(LayerTypeGTPU).(*GTPU); ok {
+		want := &GTPU{gtp1.GTPv1{
+			Version:            1,
+			ProtocolType:       1,
+			SequenceNumberFlag: true,
+			MessageType:        MessageTypeErrorIndication,
+			MessageLength:      16,
+			SequenceNumber:     1,
+			IEs: []gtp1.IE{
+				{Type: 16, Content: []byte{0x00, 0x00, 0x0a, 0xbc}},
+				{Type: 133, Content: []byte{0x0a, 0x00, 0x00, 0x01}},
+			},
+
+			Contents: testErrorIndicationPacket[42:66],
+			Payload:  []uint8{},
+		}}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GTP packet mismatch:\ngot  :\n%#v\n\nwant :\n%#v\n\n", got, want)
+		}
+	} else {
+		t.Error("Incorrect gtp packet")
+	}
+}
+
+func TestSupportedExtensionHeadersNotificationPacket(t *testing.T) {
+	Enable()
+
+	p := gopacket.NewPacket(testSupportedExtensionHeadersNotificationPacket, layers.LayerTypeEthernet, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Fatal("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+
+	got, ok := p.Layer(LayerTypeGTPU).(*GTPU)
+	if !ok {
+		t.Fatal("Incorrect gtp packet")
+	}
+	if got.MessageType != MessageTypeSupportedExtensionHeadersNotification || got.SequenceNumber != 7 {
+		t.Errorf("unexpected header: %#v", got.GTPv1)
+	}
+	want := []gtp1.IE{{Type: 141, Content: []byte{0xc0, 0x85}}}
+	if !reflect.DeepEqual(got.IEs, want) {
+		t.Fatalf("IEs = %#v, want %#v", got.IEs, want)
+	}
+
+	config.SetOutputFormat("numeric")
+	types, err := gtp1ie.DecodeExtensionHeaderTypeList(got.IEs[0].Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantTypes := []interface{}{uint8(0xc0), uint8(0x85)}; !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("extension header types = %v, want %v", types, wantTypes)
+	}
+}
+
+func TestGPDUPacket(t *testing.T) {
+	Enable()
+
+	p := gopacket.NewPacket(testGPDUPacket, layers.LayerTypeEthernet, gopacket.Default)
+
+	got, ok := p.Layer(LayerTypeGTPU).(*GTPU)
+	if !ok {
+		t.Fatal("Incorrect gtp packet")
+	}
+	if !got.IsGPDU() || got.TEID != 0x1234 || len(got.IEs) != 0 {
+		t.Errorf("unexpected G-PDU header: %#v", got.GTPv1)
+	}
+
+	// The outer IPv4 layer comes first, the inner one follows the GTP-U layer
+	var ipLayers []*layers.IPv4
+	for _, layer := range p.Layers() {
+		if ip, ok := layer.(*layers.IPv4); ok {
+			ipLayers = append(ipLayers, ip)
+		}
+	}
+	if len(ipLayers) != 2 {
+		t.Fatalf("expected outer and inner IPv4 layers, got %d", len(ipLayers))
+	}
+	inner := ipLayers[1]
+	if inner.SrcIP.String() != "192.168.1.1" || inner.DstIP.String() != "8.8.8.8" {
+		t.Errorf("unexpected inner IPv4 addresses: %s -> %s", inner.SrcIP, inner.DstIP)
+	}
+}
+
+func TestDecodePDUSessionContainer(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    PDUSessionContainer
+		wantErr bool
+	}{
+		{
+			name:    "Downlink with RQI",
+			content: []byte{0x00, 0x45},
+			want:    PDUSessionContainer{PDUType: PDUTypeDownlink, QFI: 5, RQI: true},
+		},
+		{
+			name:    "Uplink",
+			content: []byte{0x10, 0x09},
+			want:    PDUSessionContainer{PDUType: PDUTypeUplink, QFI: 9},
+		},
+		{
+			name:    "Truncated",
+			content: []byte{0x10},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodePDUSessionContainer(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodePDUSessionContainer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DecodePDUSessionContainer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
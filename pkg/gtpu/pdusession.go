@@ -0,0 +1,53 @@
+package gtpu
+
+import "fmt"
+
+// Extension header types carried in G-PDUs (3GPP TS 29.281 5.2.1)
+const (
+	ExtensionHeaderPDUSessionContainer = 0x85
+)
+
+// PDU types of the PDU Session Container (3GPP TS 38.415 5.5.3.1)
+const (
+	PDUTypeDownlink = 0
+	PDUTypeUplink   = 1
+)
+
+// PDUSessionContainer represents the PDU Session Container extension header used on N3 and N9 (3GPP TS 38.415 5.5.2)
+type PDUSessionContainer struct {
+	PDUType uint8
+	QFI     uint8 // QoS Flow Identifier
+	RQI     bool  // Reflective QoS Indicator, downlink only
+}
+
+// DecodePDUSessionContainer decodes the content of a PDU Session Container extension header
+func DecodePDUSessionContainer(content []byte) (PDUSessionContainer, error) {
+	if len(content) < 2 {
+		return PDUSessionContainer{}, fmt.Errorf("insufficient data for PDU Session Container: expected at least 2 bytes, got %d", len(content))
+	}
+
+	container := PDUSessionContainer{
+		PDUType: content[0] >> 4,
+		QFI:     content[1] & 0x3F,
+	}
+	if container.PDUType == PDUTypeDownlink {
+		container.RQI = content[1]&0x40 != 0
+	}
+
+	return container, nil
+}
+
+// PDUSessionContainer returns the PDU Session Container extension header of the packet, if present
+func (g *GTPU) PDUSessionContainer() (PDUSessionContainer, bool) {
+	for _, eh := range g.ExtensionHeaders {
+		if eh.Type != ExtensionHeaderPDUSessionContainer {
+			continue
+		}
+		container, err := DecodePDUSessionContainer(eh.Content)
+		if err != nil {
+			return PDUSessionContainer{}, false
+		}
+		return container, true
+	}
+	return PDUSessionContainer{}, false
+}
@@ -0,0 +1,226 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RecordSchema is the table written for GTP records. The most used IEs are flattened into
+// columns, the full IE list is kept in the repeated ies group with the values as JSON.
+var RecordSchema = []Node{
+	{Name: "timestamp", Type: Int64, Repetition: Required, Annotation: TimestampMicros},
+	{Name: "protocol", Type: ByteArray, Repetition: Required, Annotation: String},
+	{Name: "message_type", Type: Int32, Repetition: Optional},
+	{Name: "src_ip", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "dst_ip", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "src_port", Type: Int32, Repetition: Optional},
+	{Name: "dst_port", Type: Int32, Repetition: Optional},
+	{Name: "src_node", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "dst_node", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "teid", Type: Int64, Repetition: Optional},
+	{Name: "sequence_number", Type: Int64, Repetition: Optional},
+	{Name: "imsi", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "msisdn", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "apn", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "rat", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "cause", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "teids", Type: ByteArray, Repetition: Repeated, Annotation: String},
+	{Name: "tai", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "ecgi", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "ies", Repetition: Repeated, Fields: []Node{
+		{Name: "type", Type: ByteArray, Repetition: Required, Annotation: String},
+		{Name: "value", Type: ByteArray, Repetition: Optional, Annotation: JSON},
+	}},
+}
+
+// jsonRecord holds the fields of a JSON record that are written to columns
+type jsonRecord struct {
+	Timestamp      *time.Time `json:"timestamp"`
+	FirstSeen      *time.Time `json:"firstSeen"`
+	MessageType    *int32     `json:"messageType"`
+	SrcIP          string     `json:"srcIP"`
+	DstIP          string     `json:"dstIP"`
+	SrcPort        *int32     `json:"srcPort"`
+	DstPort        *int32     `json:"dstPort"`
+	SrcNode        string     `json:"srcNode"`
+	DstNode        string     `json:"dstNode"`
+	TEID           *int64     `json:"teid"`
+	SequenceNumber *int64     `json:"sequenceNumber"`
+	IEs            []struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	} `json:"ies"`
+}
+
+// RecordRow converts a JSON record of the given protocol to a row of RecordSchema.
+// The timestamp of the record is returned for the rollover of the files.
+func RecordRow(protocol string, data []byte) (time.Time, []interface{}, error) {
+	var r jsonRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to parse record: %w", err)
+	}
+
+	var ts time.Time
+	switch {
+	case r.Timestamp != nil:
+		ts = *r.Timestamp
+	case r.FirstSeen != nil:
+		// G-PDU flow records have no timestamp of their own
+		ts = *r.FirstSeen
+	}
+
+	var imsi, msisdn, apn, rat, cause, tai, ecgi string
+	var teids, ies []interface{}
+	for _, ie := range r.IEs {
+		ies = append(ies, []interface{}{ie.Type, nullableJSON(ie.Value)})
+
+		switch ie.Type {
+		case "IMSI":
+			first(&imsi, text(ie.Value))
+		case "MSISDN":
+			first(&msisdn, text(ie.Value))
+		case "APN":
+			first(&apn, text(ie.Value))
+		case "RATType":
+			first(&rat, text(ie.Value))
+		case "Cause":
+			first(&cause, causeText(ie.Value))
+		case "F-TEID":
+			teids = appendTEIDs(teids, ie.Value)
+		case "BearerContext":
+			var bearer struct {
+				FTEIDs []json.RawMessage `json:"FTEIDs"`
+			}
+			if json.Unmarshal(ie.Value, &bearer) == nil {
+				for _, fteid := range bearer.FTEIDs {
+					teids = appendTEIDs(teids, fteid)
+				}
+			}
+		case "ULI":
+			first(&tai, locationText(ie.Value, "TAI", "TAC"))
+			first(&ecgi, locationText(ie.Value, "ECGI", "ECI"))
+		}
+	}
+
+	row := []interface{}{
+		ts,
+		protocol,
+		optional(r.MessageType),
+		optionalString(r.SrcIP),
+		optionalString(r.DstIP),
+		optional(r.SrcPort),
+		optional(r.DstPort),
+		optionalString(r.SrcNode),
+		optionalString(r.DstNode),
+		optional(r.TEID),
+		optional(r.SequenceNumber),
+		optionalString(imsi),
+		optionalString(msisdn),
+		optionalString(apn),
+		optionalString(rat),
+		optionalString(cause),
+		teids,
+		optionalString(tai),
+		optionalString(ecgi),
+		ies,
+	}
+	return ts, row, nil
+}
+
+// optional returns the value of a pointer or nil, which the writer stores as null
+func optional[T any](v *T) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func optionalString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullableJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	return []byte(raw)
+}
+
+// first keeps the value of the first IE of a type
+func first(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
+
+// text returns a scalar JSON value as text, numbers keep their JSON form
+func text(raw json.RawMessage) string {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return ""
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	case nil:
+		return ""
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// causeText returns the cause value of a GTPv2 Cause structure or a scalar cause of the other protocols
+func causeText(raw json.RawMessage) string {
+	var cause struct {
+		CauseValue json.RawMessage `json:"CauseValue"`
+	}
+	if json.Unmarshal(raw, &cause) == nil && cause.CauseValue != nil {
+		return text(cause.CauseValue)
+	}
+	return text(raw)
+}
+
+// appendTEIDs appends the TEID of an F-TEID value
+func appendTEIDs(teids []interface{}, raw json.RawMessage) []interface{} {
+	var fteid map[string]json.RawMessage
+	if json.Unmarshal(raw, &fteid) != nil {
+		return teids
+	}
+	for _, key := range []string{"TEID/GRE Key", "TEID"} {
+		if value, ok := fteid[key]; ok {
+			if teid := text(value); teid != "" {
+				return append(teids, teid)
+			}
+		}
+	}
+	return teids
+}
+
+// locationText formats a TAI or ECGI of a ULI value as MCC-MNC-TAC or MCC-MNC-ECI
+func locationText(raw json.RawMessage, location, identity string) string {
+	var uli map[string]map[string]json.RawMessage
+	if json.Unmarshal(raw, &uli) != nil {
+		return ""
+	}
+	fields, ok := uli[location]
+	if !ok {
+		return ""
+	}
+	parts := []string{text(fields["MCC"]), text(fields["MNC"]), text(fields[identity])}
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,584 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// magic starts and ends every Parquet file
+const magic = "PAR1"
+
+// Type is the physical type of a column
+type Type int32
+
+const (
+	Boolean   Type = 0
+	Int32     Type = 1
+	Int64     Type = 2
+	Double    Type = 5
+	ByteArray Type = 6
+)
+
+// Repetition tells whether a field is required, optional or repeated
+type Repetition int32
+
+const (
+	Required Repetition = 0
+	Optional Repetition = 1
+	Repeated Repetition = 2
+)
+
+// Annotation describes how the physical type of a column is interpreted
+type Annotation int
+
+const (
+	NoAnnotation Annotation = iota
+	String
+	JSON
+	TimestampMicros
+)
+
+// convertedTypes maps annotations to the ConvertedType values of the Parquet format
+var convertedTypes = map[Annotation]int32{
+	String:          0,
+	TimestampMicros: 10,
+	JSON:            19,
+}
+
+// Codec is the compression codec of the column chunks
+type Codec int32
+
+const (
+	Uncompressed Codec = 0
+	Snappy       Codec = 1
+	Gzip         Codec = 2
+	Zstd         Codec = 6
+)
+
+// ParseCodec returns the codec of a name (none, snappy, gzip, zstd)
+func ParseCodec(name string) (Codec, error) {
+	switch strings.ToLower(name) {
+	case "none", "uncompressed":
+		return Uncompressed, nil
+	case "snappy":
+		return Snappy, nil
+	case "gzip":
+		return Gzip, nil
+	case "zstd":
+		return Zstd, nil
+	}
+	return 0, fmt.Errorf("unknown compression codec %q", name)
+}
+
+// Node is a field of the schema. Nodes with fields are groups, the others are columns.
+type Node struct {
+	Name       string
+	Type       Type
+	Repetition Repetition
+	Annotation Annotation
+	Fields     []Node
+}
+
+// Encodings of the column data
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+// column buffers the levels and values of a leaf node until the row group is written
+type column struct {
+	node   *Node
+	path   []string
+	maxDef int
+	maxRep int
+
+	defs   []uint8
+	reps   []uint8
+	values []byte
+}
+
+// Writer writes rows to a Parquet file. Columns are written with the PLAIN encoding in a single
+// data page per row group, definition and repetition levels with the RLE encoding.
+type Writer struct {
+	w       io.Writer
+	offset  int64
+	schema  []Node
+	columns []*column
+	codec   Codec
+
+	rows      int64
+	totalRows int64
+	rowGroups []rowGroup
+	createdBy string
+}
+
+// rowGroup holds the metadata of a written row group
+type rowGroup struct {
+	chunks  []columnChunk
+	size    int64
+	numRows int64
+}
+
+type columnChunk struct {
+	numValues        int64
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// NewWriter writes the file header and returns a writer for rows of the given schema.
+// createdBy is stored in the file metadata.
+func NewWriter(w io.Writer, schema []Node, codec Codec, createdBy string) (*Writer, error) {
+	pw := &Writer{w: w, schema: schema, codec: codec, createdBy: createdBy}
+	for i := range schema {
+		pw.addColumns(&schema[i], nil, 0, 0)
+	}
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *Writer) addColumns(n *Node, path []string, def, rep int) {
+	path = append(append([]string{}, path...), n.Name)
+	switch n.Repetition {
+	case Optional:
+		def++
+	case Repeated:
+		def++
+		rep++
+	}
+	if len(n.Fields) == 0 {
+		pw.columns = append(pw.columns, &column{node: n, path: path, maxDef: def, maxRep: rep})
+		return
+	}
+	for i := range n.Fields {
+		pw.addColumns(&n.Fields[i], path, def, rep)
+	}
+}
+
+func (pw *Writer) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+// Size returns the number of bytes written to the file so far
+func (pw *Writer) Size() int64 {
+	return pw.offset
+}
+
+// BufferedBytes returns the size of the row group data not written yet, before compression
+func (pw *Writer) BufferedBytes() int {
+	size := 0
+	for _, c := range pw.columns {
+		size += len(c.values) + len(c.defs) + len(c.reps)
+	}
+	return size
+}
+
+// BufferedRows returns the number of rows of the current row group
+func (pw *Writer) BufferedRows() int64 {
+	return pw.rows
+}
+
+// WriteRow adds a row to the current row group. The row holds one value per top level node:
+// nil for a missing optional value, a []interface{} of elements for repeated nodes and
+// a []interface{} of field values for groups.
+func (pw *Writer) WriteRow(row []interface{}) error {
+	if len(row) != len(pw.schema) {
+		return fmt.Errorf("row has %d values, schema has %d fields", len(row), len(pw.schema))
+	}
+	// A row that fails half way is removed again so that the columns stay aligned
+	type mark struct{ levels, values int }
+	marks := make([]mark, len(pw.columns))
+	for i, c := range pw.columns {
+		marks[i] = mark{len(c.defs), len(c.values)}
+	}
+
+	columns := pw.columns
+	for i := range pw.schema {
+		var err error
+		if columns, err = pw.shred(&pw.schema[i], row[i], columns, 0, 0, 0); err != nil {
+			for j, c := range pw.columns {
+				c.defs, c.reps, c.values = c.defs[:marks[j].levels], c.reps[:marks[j].levels], c.values[:marks[j].values]
+			}
+			return err
+		}
+	}
+	pw.rows++
+	return nil
+}
+
+// shred appends the levels and values of a node to its columns and returns the columns of the
+// following nodes. rep is the repetition level of the first value, depth the repetition
+// level of the node's parent and def its definition level.
+func (pw *Writer) shred(n *Node, v interface{}, columns []*column, rep, depth, def int) ([]*column, error) {
+	switch n.Repetition {
+	case Repeated:
+		elems, ok := v.([]interface{})
+		if v != nil && !ok {
+			return nil, fmt.Errorf("%s: repeated value of type %T", n.Name, v)
+		}
+		if len(elems) == 0 {
+			return pw.null(n, columns, rep, def), nil
+		}
+		var rest []*column
+		for i, elem := range elems {
+			r := rep
+			if i > 0 {
+				r = depth + 1
+			}
+			var err error
+			if rest, err = pw.shredValue(n, elem, columns, r, depth+1, def+1); err != nil {
+				return nil, err
+			}
+		}
+		return rest, nil
+	case Optional:
+		if v == nil {
+			return pw.null(n, columns, rep, def), nil
+		}
+		return pw.shredValue(n, v, columns, rep, depth, def+1)
+	}
+	if v == nil && len(n.Fields) == 0 {
+		return nil, fmt.Errorf("%s: missing required value", n.Name)
+	}
+	return pw.shredValue(n, v, columns, rep, depth, def)
+}
+
+// shredValue writes a present value of a node
+func (pw *Writer) shredValue(n *Node, v interface{}, columns []*column, rep, depth, def int) ([]*column, error) {
+	if len(n.Fields) == 0 {
+		if err := columns[0].add(rep, def, v); err != nil {
+			return nil, err
+		}
+		return columns[1:], nil
+	}
+
+	fields, ok := v.([]interface{})
+	if !ok || len(fields) != len(n.Fields) {
+		return nil, fmt.Errorf("%s: group value must hold %d fields", n.Name, len(n.Fields))
+	}
+	for i := range n.Fields {
+		var err error
+		if columns, err = pw.shred(&n.Fields[i], fields[i], columns, rep, depth, def); err != nil {
+			return nil, err
+		}
+	}
+	return columns, nil
+}
+
+// null writes a missing value for every column of a node
+func (pw *Writer) null(n *Node, columns []*column, rep, def int) []*column {
+	count := leaves(n)
+	for _, c := range columns[:count] {
+		c.reps = append(c.reps, uint8(rep))
+		c.defs = append(c.defs, uint8(def))
+	}
+	return columns[count:]
+}
+
+func leaves(n *Node) int {
+	if len(n.Fields) == 0 {
+		return 1
+	}
+	count := 0
+	for i := range n.Fields {
+		count += leaves(&n.Fields[i])
+	}
+	return count
+}
+
+// add appends a value with the PLAIN encoding
+func (c *column) add(rep, def int, v interface{}) error {
+	c.reps = append(c.reps, uint8(rep))
+	c.defs = append(c.defs, uint8(def))
+
+	switch c.node.Type {
+	case Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("%s: boolean value of type %T", c.node.Name, v)
+		}
+		// Booleans are bit packed, the packing is done when the page is written
+		if b {
+			c.values = append(c.values, 1)
+		} else {
+			c.values = append(c.values, 0)
+		}
+	case Int32:
+		i, ok := integer(v)
+		if !ok {
+			return fmt.Errorf("%s: int32 value of type %T", c.node.Name, v)
+		}
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(i))
+	case Int64:
+		if t, ok := v.(time.Time); ok {
+			c.values = binary.LittleEndian.AppendUint64(c.values, uint64(t.UnixMicro()))
+			break
+		}
+		i, ok := integer(v)
+		if !ok {
+			return fmt.Errorf("%s: int64 value of type %T", c.node.Name, v)
+		}
+		c.values = binary.LittleEndian.AppendUint64(c.values, uint64(i))
+	case ByteArray:
+		var b []byte
+		switch s := v.(type) {
+		case string:
+			b = []byte(s)
+		case []byte:
+			b = s
+		default:
+			return fmt.Errorf("%s: byte array value of type %T", c.node.Name, v)
+		}
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(b)))
+		c.values = append(c.values, b...)
+	default:
+		return fmt.Errorf("%s: unsupported type %d", c.node.Name, c.node.Type)
+	}
+	return nil
+}
+
+func integer(v interface{}) (int64, bool) {
+	switch i := v.(type) {
+	case int:
+		return int64(i), true
+	case int8:
+		return int64(i), true
+	case int16:
+		return int64(i), true
+	case int32:
+		return int64(i), true
+	case int64:
+		return i, true
+	case uint8:
+		return int64(i), true
+	case uint16:
+		return int64(i), true
+	case uint32:
+		return int64(i), true
+	case uint64:
+		return int64(i), true
+	}
+	return 0, false
+}
+
+// appendLevels appends levels in the RLE/bit-packed hybrid encoding using RLE runs only,
+// prefixed with their length as the data page format requires
+func appendLevels(b []byte, levels []uint8, maxLevel int) []byte {
+	start := len(b)
+	b = append(b, 0, 0, 0, 0)
+	width := (bits.Len(uint(maxLevel)) + 7) / 8
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		b = binary.AppendUvarint(b, uint64(j-i)<<1)
+		for k := 0; k < width; k++ {
+			b = append(b, levels[i])
+		}
+		i = j
+	}
+	binary.LittleEndian.PutUint32(b[start:], uint32(len(b)-start-4))
+	return b
+}
+
+// packBooleans converts one byte per value to the bit packed PLAIN encoding of booleans
+func packBooleans(values []byte) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v != 0 {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+)
+
+func compress(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case Uncompressed:
+		return data, nil
+	case Snappy:
+		return snappy.Encode(nil, data), nil
+	case Gzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case Zstd:
+		zstdOnce.Do(func() {
+			zstdEncoder, _ = zstd.NewWriter(nil)
+		})
+		return zstdEncoder.EncodeAll(data, nil), nil
+	}
+	return nil, fmt.Errorf("unsupported codec %d", codec)
+}
+
+// Flush writes the buffered rows as a row group
+func (pw *Writer) Flush() error {
+	if pw.rows == 0 {
+		return nil
+	}
+
+	group := rowGroup{numRows: pw.rows}
+	for _, c := range pw.columns {
+		var page []byte
+		if c.maxRep > 0 {
+			page = appendLevels(page, c.reps, c.maxRep)
+		}
+		if c.maxDef > 0 {
+			page = appendLevels(page, c.defs, c.maxDef)
+		}
+		if c.node.Type == Boolean {
+			page = append(page, packBooleans(c.values)...)
+		} else {
+			page = append(page, c.values...)
+		}
+
+		compressed, err := compress(pw.codec, page)
+		if err != nil {
+			return err
+		}
+
+		t := newThriftWriter()
+		t.beginStruct(0)
+		t.i32(1, 0) // DATA_PAGE
+		t.i32(2, int32(len(page)))
+		t.i32(3, int32(len(compressed)))
+		t.beginStruct(5)
+		t.i32(1, int32(len(c.defs)))
+		t.i32(2, encodingPlain)
+		t.i32(3, encodingRLE)
+		t.i32(4, encodingRLE)
+		t.endStruct()
+		t.endStruct()
+
+		chunk := columnChunk{
+			numValues:        int64(len(c.defs)),
+			offset:           pw.offset,
+			uncompressedSize: int64(len(t.buf) + len(page)),
+			compressedSize:   int64(len(t.buf) + len(compressed)),
+		}
+		if err := pw.write(t.buf); err != nil {
+			return err
+		}
+		if err := pw.write(compressed); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.uncompressedSize
+
+		c.defs, c.reps, c.values = c.defs[:0], c.reps[:0], c.values[:0]
+	}
+
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.totalRows += pw.rows
+	pw.rows = 0
+	return nil
+}
+
+// Close writes the remaining rows and the file footer. The underlying writer is not closed.
+func (pw *Writer) Close() error {
+	if err := pw.Flush(); err != nil {
+		return err
+	}
+
+	t := newThriftWriter()
+	t.beginStruct(0)
+	t.i32(1, 1)
+	t.list(2, thriftStruct, 1+countNodes(pw.schema))
+	t.beginStruct(0)
+	t.string(4, "schema")
+	t.i32(5, int32(len(pw.schema)))
+	t.endStruct()
+	for i := range pw.schema {
+		writeSchema(t, &pw.schema[i])
+	}
+	t.i64(3, pw.totalRows)
+
+	t.list(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		t.beginStruct(0)
+		t.list(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			c := pw.columns[i]
+			t.beginStruct(0)
+			t.i64(2, chunk.offset)
+			t.beginStruct(3)
+			t.i32(1, int32(c.node.Type))
+			t.listI32(2, encodingPlain, encodingRLE)
+			t.listString(3, c.path...)
+			t.i32(4, int32(pw.codec))
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.uncompressedSize)
+			t.i64(7, chunk.compressedSize)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, group.size)
+		t.i64(3, group.numRows)
+		t.endStruct()
+	}
+	if pw.createdBy != "" {
+		t.string(6, pw.createdBy)
+	}
+	t.endStruct()
+
+	footer := binary.LittleEndian.AppendUint32(t.buf, uint32(len(t.buf)))
+	if err := pw.write(footer); err != nil {
+		return err
+	}
+	return pw.write([]byte(magic))
+}
+
+func countNodes(nodes []Node) int {
+	count := len(nodes)
+	for i := range nodes {
+		count += countNodes(nodes[i].Fields)
+	}
+	return count
+}
+
+// writeSchema writes the SchemaElement of a node and its fields in depth-first order
+func writeSchema(t *thriftWriter, n *Node) {
+	t.beginStruct(0)
+	if len(n.Fields) == 0 {
+		t.i32(1, int32(n.Type))
+	}
+	t.i32(3, int32(n.Repetition))
+	t.string(4, n.Name)
+	if len(n.Fields) > 0 {
+		t.i32(5, int32(len(n.Fields)))
+	}
+	if converted, ok := convertedTypes[n.Annotation]; ok {
+		t.i32(6, converted)
+	}
+	t.endStruct()
+	for i := range n.Fields {
+		writeSchema(t, &n.Fields[i])
+	}
+}
@@ -0,0 +1,443 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// thriftReader decodes Thrift compact structs into maps of field ID to value
+type thriftReader struct {
+	t *testing.T
+	b []byte
+}
+
+func (r *thriftReader) varint() int64 {
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.t.Fatalf("invalid varint at %x", r.b)
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.t.Fatalf("invalid uvarint at %x", r.b)
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := r.uvarint()
+		s := string(r.b[:n])
+		r.b = r.b[n:]
+		return s
+	case thriftList:
+		header := r.b[0]
+		r.b = r.b[1:]
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		elems := make([]interface{}, size)
+		for i := range elems {
+			elems[i] = r.value(header & 0x0f)
+		}
+		return elems
+	case thriftStruct:
+		return r.structure()
+	}
+	r.t.Fatalf("unexpected thrift type %d", typ)
+	return nil
+}
+
+func (r *thriftReader) structure() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var id int16
+	for {
+		header := r.b[0]
+		r.b = r.b[1:]
+		if header == 0 {
+			return fields
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(header & 0x0f)
+	}
+}
+
+type parsedFile struct {
+	data     []byte
+	metadata map[int16]interface{}
+}
+
+func parseFile(t *testing.T, data []byte) parsedFile {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte(magic)) || !bytes.HasSuffix(data, []byte(magic)) {
+		t.Fatal("file does not start and end with PAR1")
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{t: t, b: data[len(data)-8-size : len(data)-8]}
+	metadata := r.structure()
+	if len(r.b) != 0 {
+		t.Fatalf("%d trailing footer bytes", len(r.b))
+	}
+	return parsedFile{data: data, metadata: metadata}
+}
+
+func (f parsedFile) schemaNames() []string {
+	var names []string
+	for _, element := range f.metadata[2].([]interface{}) {
+		names = append(names, element.(map[int16]interface{})[4].(string))
+	}
+	return names
+}
+
+// columnPage returns the levels and the decompressed values of a column chunk
+func (f parsedFile) columnPage(t *testing.T, group, column int, maxRep, maxDef int) (reps, defs []int, values []byte) {
+	t.Helper()
+	groups := f.metadata[4].([]interface{})
+	chunk := groups[group].(map[int16]interface{})[1].([]interface{})[column].(map[int16]interface{})
+	meta := chunk[3].(map[int16]interface{})
+
+	r := &thriftReader{t: t, b: f.data[meta[9].(int64):]}
+	header := r.structure()
+	page := r.b[:header[3].(int64)]
+	var err error
+	switch Codec(meta[4].(int64)) {
+	case Snappy:
+		page, err = snappy.Decode(nil, page)
+	case Gzip:
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(bytes.NewReader(page)); err == nil {
+			page, err = io.ReadAll(zr)
+		}
+	case Zstd:
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(nil); err == nil {
+			page, err = zr.DecodeAll(page, nil)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(page)) != header[2].(int64) {
+		t.Fatalf("page has %d bytes, header says %d", len(page), header[2])
+	}
+	numValues := int(header[5].(map[int16]interface{})[1].(int64))
+
+	levels := func(maxLevel int) []int {
+		if maxLevel == 0 {
+			return nil
+		}
+		size := binary.LittleEndian.Uint32(page)
+		lr := &thriftReader{t: t, b: page[4 : 4+size]}
+		page = page[4+size:]
+		var out []int
+		for len(lr.b) > 0 {
+			run := int(lr.uvarint() >> 1)
+			for i := 0; i < run; i++ {
+				out = append(out, int(lr.b[0]))
+			}
+			lr.b = lr.b[1:]
+		}
+		if len(out) != numValues {
+			t.Fatalf("%d levels for %d values", len(out), numValues)
+		}
+		return out
+	}
+	reps = levels(maxRep)
+	defs = levels(maxDef)
+	return reps, defs, page
+}
+
+// byteArrays splits PLAIN encoded byte arrays
+func byteArrays(values []byte) []string {
+	var out []string
+	for len(values) > 0 {
+		n := binary.LittleEndian.Uint32(values)
+		out = append(out, string(values[4:4+n]))
+		values = values[4+n:]
+	}
+	return out
+}
+
+var testSchema = []Node{
+	{Name: "id", Type: Int64, Repetition: Required},
+	{Name: "name", Type: ByteArray, Repetition: Optional, Annotation: String},
+	{Name: "tags", Type: ByteArray, Repetition: Repeated, Annotation: String},
+	{Name: "items", Repetition: Repeated, Fields: []Node{
+		{Name: "key", Type: ByteArray, Repetition: Required, Annotation: String},
+		{Name: "value", Type: Int32, Repetition: Optional},
+	}},
+	{Name: "valid", Type: Boolean, Repetition: Required},
+}
+
+func TestWriter(t *testing.T) {
+	for _, codec := range []Codec{Uncompressed, Snappy, Gzip, Zstd} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, testSchema, codec, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows := [][]interface{}{
+			{int64(1), "a", []interface{}{"x", "y"}, []interface{}{[]interface{}{"k1", 1}, []interface{}{"k2", nil}}, true},
+			{int64(2), nil, nil, nil, false},
+			{int64(3), "c", []interface{}{"z"}, []interface{}{[]interface{}{"k3", int32(3)}}, true},
+		}
+		for _, row := range rows {
+			if err := w.WriteRow(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// A failing row leaves the buffered columns untouched
+		if err := w.WriteRow([]interface{}{int64(4), "d", []interface{}{"w"}, []interface{}{[]interface{}{nil, 1}}, true}); err == nil {
+			t.Error("WriteRow() with a missing required value did not fail")
+		}
+		if w.BufferedRows() != 3 {
+			t.Errorf("BufferedRows() = %d, want 3", w.BufferedRows())
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if w.Size() != int64(buf.Len()) {
+			t.Errorf("Size() = %d, file has %d bytes", w.Size(), buf.Len())
+		}
+
+		f := parseFile(t, buf.Bytes())
+		if f.metadata[3] != int64(3) || f.metadata[6] != "test" {
+			t.Errorf("codec %d: num_rows %v, created_by %v", codec, f.metadata[3], f.metadata[6])
+		}
+		wantNames := []string{"schema", "id", "name", "tags", "items", "key", "value", "valid"}
+		if names := f.schemaNames(); !reflect.DeepEqual(names, wantNames) {
+			t.Errorf("schema = %v, want %v", names, wantNames)
+		}
+
+		if _, defs, values := f.columnPage(t, 0, 1, 0, 1); !reflect.DeepEqual(defs, []int{1, 0, 1}) ||
+			!reflect.DeepEqual(byteArrays(values), []string{"a", "c"}) {
+			t.Errorf("codec %d: name defs %v values %q", codec, defs, byteArrays(values))
+		}
+		if reps, defs, values := f.columnPage(t, 0, 2, 1, 1); !reflect.DeepEqual(reps, []int{0, 1, 0, 0}) ||
+			!reflect.DeepEqual(defs, []int{1, 1, 0, 1}) || !reflect.DeepEqual(byteArrays(values), []string{"x", "y", "z"}) {
+			t.Errorf("codec %d: tags reps %v defs %v values %q", codec, reps, defs, byteArrays(values))
+		}
+		if reps, defs, values := f.columnPage(t, 0, 4, 1, 2); !reflect.DeepEqual(reps, []int{0, 1, 0, 0}) ||
+			!reflect.DeepEqual(defs, []int{2, 1, 0, 2}) || !bytes.Equal(values, []byte{1, 0, 0, 0, 3, 0, 0, 0}) {
+			t.Errorf("codec %d: items.value reps %v defs %v values %x", codec, reps, defs, values)
+		}
+		if _, _, values := f.columnPage(t, 0, 5, 0, 0); !bytes.Equal(values, []byte{0b101}) {
+			t.Errorf("codec %d: valid values %b", codec, values)
+		}
+	}
+}
+
+func TestRecordRow(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		record   string
+		wantTime time.Time
+		want     map[string]interface{}
+	}{
+		{
+			name:     "GTPv2 Create Session Request",
+			protocol: "GTPv2",
+			record: `{"timestamp":"2024-05-01T10:00:00.5Z","srcIP":"10.0.0.1","dstIP":"10.0.0.2","srcPort":2123,"dstPort":2123,
+				"messageType":32,"teid":0,"sequenceNumber":7,"ies":[
+				{"type":"IMSI","value":"001010123456789"},
+				{"type":"MSISDN","value":"491711234567"},
+				{"type":"ULI","value":{"TAI":{"MCC":"001","MNC":"01","TAC":"0x0001"},"ECGI":{"MCC":"001","MNC":"01","ECI":"0x0000101"}}},
+				{"type":"RATType","value":"EUTRAN"},
+				{"type":"F-TEID","value":{"InterfaceType":"S11 MME GTP-C","TEID/GRE Key":"0x00000001","F-TEID IPv4":"10.0.0.1"}},
+				{"type":"APN","value":"internet"},
+				{"type":"BearerContext","value":{"EBI":5,"FTEIDs":[{"InterfaceType":"S1-U eNodeB GTP-U","TEID/GRE Key":"0x00000002"}]}}]}`,
+			wantTime: time.Date(2024, 5, 1, 10, 0, 0, 5e8, time.UTC),
+			want: map[string]interface{}{
+				"protocol": "GTPv2", "message_type": int32(32), "src_ip": "10.0.0.1", "dst_port": int32(2123),
+				"teid": int64(0), "sequence_number": int64(7), "imsi": "001010123456789", "msisdn": "491711234567",
+				"apn": "internet", "rat": "EUTRAN", "cause": nil, "teids": []interface{}{"0x00000001", "0x00000002"},
+				"tai": "001-01-0x0001", "ecgi": "001-01-0x0000101", "src_node": nil,
+			},
+		},
+		{
+			name:     "Numeric Cause",
+			protocol: "GTPv2",
+			record:   `{"timestamp":"2024-05-01T10:00:00Z","messageType":33,"ies":[{"type":"Cause","value":{"CauseValue":16,"CS":0}},{"type":"Recovery","value":null}]}`,
+			wantTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			want:     map[string]interface{}{"cause": "16", "teid": nil, "imsi": nil, "teids": []interface{}(nil)},
+		},
+		{
+			name:     "GTPv1 Cause",
+			protocol: "GTPv1-C",
+			record:   `{"timestamp":"2024-05-01T10:00:00Z","ies":[{"type":"Cause","value":"Request accepted"}]}`,
+			wantTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			want:     map[string]interface{}{"protocol": "GTPv1-C", "cause": "Request accepted"},
+		},
+		{
+			name:     "G-PDU Flow",
+			protocol: "GTP-U",
+			record:   `{"recordType":"flow","teid":1234,"srcIP":"192.168.0.1","protocol":6,"firstSeen":"2024-05-01T09:59:00Z","lastSeen":"2024-05-01T10:00:00Z"}`,
+			wantTime: time.Date(2024, 5, 1, 9, 59, 0, 0, time.UTC),
+			want:     map[string]interface{}{"protocol": "GTP-U", "teid": int64(1234), "message_type": nil, "ies": []interface{}(nil)},
+		},
+	}
+
+	columns := map[string]int{}
+	for i, n := range RecordSchema {
+		columns[n.Name] = i
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, row, err := RecordRow(tt.protocol, []byte(tt.record))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ts.Equal(tt.wantTime) {
+				t.Errorf("timestamp = %v, want %v", ts, tt.wantTime)
+			}
+			for name, want := range tt.want {
+				if got := row[columns[name]]; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", name, got, want)
+				}
+			}
+			// Every row must fit the schema
+			w, _ := NewWriter(&bytes.Buffer{}, RecordSchema, Uncompressed, "")
+			if err := w.WriteRow(row); err != nil {
+				t.Errorf("WriteRow() error = %v", err)
+			}
+		})
+	}
+
+	if _, _, err := RecordRow("GTPv2", []byte("{")); err == nil {
+		t.Error("RecordRow() of invalid JSON did not fail")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		maxBytes  int64
+		interval  time.Duration
+		rows      int
+		step      time.Duration
+		wantFiles []string
+		wantRows  []int64
+	}{
+		{
+			name:      "Time Windows",
+			interval:  time.Hour,
+			rows:      6,
+			step:      20 * time.Minute,
+			wantFiles: []string{"gtp-20240501T100000Z-0001.parquet", "gtp-20240501T110000Z-0002.parquet"},
+			wantRows:  []int64{3, 3},
+		},
+		{
+			name:      "Size Limit",
+			maxBytes:  1,
+			rows:      2,
+			step:      time.Second,
+			wantFiles: []string{"gtp-20240501T100000Z-0001.parquet", "gtp-20240501T100001Z-0002.parquet"},
+			wantRows:  []int64{1, 1},
+		},
+		{
+			name:      "No Rollover",
+			rows:      5,
+			step:      time.Hour,
+			wantFiles: []string{"gtp-20240501T100000Z-0001.parquet"},
+			wantRows:  []int64{5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "out")
+			sink, err := NewFileSink(FileSinkConfig{
+				Dir:          dir,
+				Prefix:       "gtp",
+				Schema:       testSchema,
+				Codec:        Snappy,
+				MaxBytes:     tt.maxBytes,
+				Interval:     tt.interval,
+				RowGroupRows: 2,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.rows; i++ {
+				row := []interface{}{int64(i), nil, nil, nil, true}
+				if err := sink.Write(start.Add(time.Duration(i)*tt.step), row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := sink.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if sink.FilesWritten() != len(tt.wantFiles) {
+				t.Errorf("FilesWritten() = %d, want %d", sink.FilesWritten(), len(tt.wantFiles))
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.wantFiles) {
+				t.Fatalf("files = %v, want %v", names, tt.wantFiles)
+			}
+			for i, name := range names {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rows := parseFile(t, data).metadata[3]; rows != tt.wantRows[i] {
+					t.Errorf("%s has %v rows, want %d", name, rows, tt.wantRows[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Codec
+		wantErr bool
+	}{
+		{name: "snappy", want: Snappy},
+		{name: "ZSTD", want: Zstd},
+		{name: "none", want: Uncompressed},
+		{name: "lz4", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCodec(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseCodec(%q) = %v, %v", tt.name, got, err)
+		}
+	}
+}
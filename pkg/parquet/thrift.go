@@ -0,0 +1,93 @@
+package parquet
+
+import "encoding/binary"
+
+// Types of the Thrift compact protocol used by the Parquet metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structures in the Thrift compact protocol. Only the types
+// needed for the file metadata and page headers are supported.
+type thriftWriter struct {
+	buf []byte
+	// lastID holds the ID of the previous field of each open struct, field IDs are delta encoded
+	lastID []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{lastID: []int16{0}}
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.lastID[len(t.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) string(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.appendString(v)
+}
+
+func (t *thriftWriter) appendString(v string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+// beginStruct starts a struct field, id 0 starts a list element or the top level struct
+func (t *thriftWriter) beginStruct(id int16) {
+	if id != 0 {
+		t.fieldHeader(id, thriftStruct)
+	}
+	t.lastID = append(t.lastID, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf = append(t.buf, 0)
+	t.lastID = t.lastID[:len(t.lastID)-1]
+}
+
+// list starts a list field, the elements follow without field headers
+func (t *thriftWriter) list(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elemType)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|elemType)
+	t.buf = binary.AppendUvarint(t.buf, uint64(size))
+}
+
+func (t *thriftWriter) listI32(id int16, values ...int32) {
+	t.list(id, thriftI32, len(values))
+	for _, v := range values {
+		t.buf = binary.AppendVarint(t.buf, int64(v))
+	}
+}
+
+func (t *thriftWriter) listString(id int16, values ...string) {
+	t.list(id, thriftBinary, len(values))
+	for _, v := range values {
+		t.appendString(v)
+	}
+}
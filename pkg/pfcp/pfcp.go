@@ -0,0 +1,184 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypePFCP registers PFCP layer type for use with GoPacket
+var LayerTypePFCP = gopacket.RegisterLayerType(1013,
+	gopacket.LayerTypeMetadata{Name: "PFCP", Decoder: gopacket.DecodeFunc(decodePFCP)})
+
+const pfcpMinimumSizeInBytes int = 8
+
+// IE represents an Information Element in PFCP, vendor-specific IEs carry an enterprise ID
+type IE struct {
+	Type         uint16
+	EnterpriseID uint16
+	Content      []byte
+}
+
+// IsVendorSpecific reports whether the IE type is in the enterprise-specific range
+func (ie IE) IsVendorSpecific() bool {
+	return ie.Type&0x8000 != 0
+}
+
+// PFCP is the protocol between the control plane and user plane functions on the Sx and N4 interfaces.
+// Defined in the 3GPP TS 29.244 specification
+type PFCP struct {
+	Version             uint8
+	FollowOnFlag        bool
+	MessagePriorityFlag bool
+	SEIDFlag            bool
+	MessageType         uint8
+	MessageLength       uint16
+	SEID                uint64
+	SequenceNumber      uint32
+	MessagePriority     uint8
+	IEs                 []IE
+
+	Contents []byte
+	Payload  []byte
+}
+
+func init() {
+	// Registers PFCP to be identified and processed over its standard UDP port, 8805
+	udpPort := layers.UDPPort(8805)
+	layers.RegisterUDPPortLayerType(udpPort, LayerTypePFCP)
+}
+
+// DecodeFromBytes analyses a byte slice and attempts to decode it as a PFCP packet
+func (p *PFCP) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	hLen := pfcpMinimumSizeInBytes
+	dLen := len(data)
+	if dLen < hLen {
+		return fmt.Errorf("PFCP packet too small: %d bytes", dLen)
+	}
+
+	p.Version = (data[0] >> 5) & 0x07
+	p.FollowOnFlag = ((data[0] >> 2) & 0x01) == 1
+	p.MessagePriorityFlag = ((data[0] >> 1) & 0x01) == 1
+	p.SEIDFlag = (data[0] & 0x01) == 1
+	p.MessageType = data[1]
+	p.MessageLength = binary.BigEndian.Uint16(data[2:4])
+
+	// Message length excludes the first 4 octets of the header
+	pLen := 4 + int(p.MessageLength)
+	if dLen < pLen {
+		return fmt.Errorf("PFCP packet too small: %d bytes", dLen)
+	}
+
+	if p.SEIDFlag {
+		hLen += 8
+	}
+	if pLen < hLen {
+		return fmt.Errorf("PFCP message length %d is shorter than the header", p.MessageLength)
+	}
+
+	cIndex := 4
+	if p.SEIDFlag {
+		p.SEID = binary.BigEndian.Uint64(data[cIndex : cIndex+8])
+		cIndex += 8
+	}
+	p.SequenceNumber = uint32(data[cIndex])<<16 | uint32(data[cIndex+1])<<8 | uint32(data[cIndex+2])
+	if p.MessagePriorityFlag {
+		p.MessagePriority = data[cIndex+3] >> 4
+	}
+	cIndex += 4
+
+	ies, err := DecodeIEs(data[cIndex:pLen])
+	if err != nil {
+		return err
+	}
+	p.IEs = ies
+
+	p.Contents = data[:pLen]
+	p.Payload = data[pLen:]
+	return nil
+}
+
+// DecodeIEs splits a byte slice into PFCP Information Elements, it is also used for grouped IEs
+func DecodeIEs(data []byte) ([]IE, error) {
+	var ies []IE
+	index := 0
+
+	for index < len(data) {
+		if index+4 > len(data) {
+			return nil, fmt.Errorf("IE header exceeds packet length at index %d", index)
+		}
+		ie := IE{Type: binary.BigEndian.Uint16(data[index : index+2])}
+		ieLength := int(binary.BigEndian.Uint16(data[index+2 : index+4]))
+		index += 4
+
+		if index+ieLength > len(data) {
+			return nil, fmt.Errorf("IE %d exceeds packet length", ie.Type)
+		}
+		content := data[index : index+ieLength]
+		index += ieLength
+
+		// The length of a vendor-specific IE includes its enterprise ID
+		if ie.IsVendorSpecific() {
+			if len(content) < 2 {
+				return nil, fmt.Errorf("vendor-specific IE %d is missing the enterprise ID", ie.Type)
+			}
+			ie.EnterpriseID = binary.BigEndian.Uint16(content[:2])
+			content = content[2:]
+		}
+		ie.Content = content
+
+		ies = append(ies, ie)
+	}
+
+	return ies, nil
+}
+
+// decodePFCP is a utility function to facilitate the decoding of PFCP packets within GoPacket's framework
+func decodePFCP(data []byte, p gopacket.PacketBuilder) error {
+	pfcp := &PFCP{}
+
+	if err := pfcp.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+
+	p.AddLayer(pfcp)
+	if len(pfcp.Payload) == 0 {
+		return nil
+	}
+	// Refers to the decoder directly as NextLayerType would make LayerTypePFCP depend on itself
+	if pfcp.FollowOnFlag {
+		return p.NextDecoder(gopacket.DecodeFunc(decodePFCP))
+	}
+	return p.NextDecoder(gopacket.LayerTypePayload)
+}
+
+// LayerType returns LayerTypePFCP
+func (p *PFCP) LayerType() gopacket.LayerType {
+	return LayerTypePFCP
+}
+
+// LayerContents returns the contents of the PFCP layer.
+func (p *PFCP) LayerContents() []byte {
+	return p.Contents
+}
+
+// LayerPayload returns the payload of the PFCP layer.
+func (p *PFCP) LayerPayload() []byte {
+	return p.Payload
+}
+
+// CanDecode returns a set of layers that PFCP objects can decode
+func (p *PFCP) CanDecode() gopacket.LayerClass {
+	return LayerTypePFCP
+}
+
+// NextLayerType specifies the next layer that GoPacket should attempt to decode, a message with the
+// follow-on flag is followed by another PFCP message in the same datagram (TS 29.244 7.2.2.1)
+func (p *PFCP) NextLayerType() gopacket.LayerType {
+	if p.FollowOnFlag && len(p.Payload) > 0 {
+		return LayerTypePFCP
+	}
+	return gopacket.LayerTypePayload
+}
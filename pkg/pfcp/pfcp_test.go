@@ -0,0 +1,134 @@
+package pfcp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// testPFCPPacket is a Session Establishment Request carrying Node ID, F-SEID and a Create PDR
+var testPFCPPacket = []byte{
+	0x84, 0xb5, 0xd1, 0x58, 0x1f, 0xa3, 0x84, 0xb5,
+	0x9c, 0x67, 0x9d, 0x29, 0x08, 0x00, 0x45, 0x00,
+	0x00, 0x7f, 0x00, 0x01, 0x00, 0x00, 0x40, 0x11,
+	0x00, 0x00, 0x0a, 0x00, 0x00, 0x02, 0x0a, 0x00,
+	0x00, 0x01, 0x22, 0x65, 0x22, 0x65, 0x00, 0x6b,
+	0x00, 0x00, 0x21, 0x32, 0x00, 0x5f, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x00, 0x3c, 0x00, 0x05, 0x00, 0x0a,
+	0x00, 0x00, 0x01, 0x00, 0x39, 0x00, 0x0d, 0x02,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+	0x0a, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x35,
+	0x00, 0x38, 0x00, 0x02, 0x00, 0x01, 0x00, 0x1d,
+	0x00, 0x04, 0x00, 0x00, 0x00, 0xff, 0x00, 0x02,
+	0x00, 0x1b, 0x00, 0x14, 0x00, 0x01, 0x00, 0x00,
+	0x15, 0x00, 0x09, 0x01, 0x00, 0x00, 0x00, 0x01,
+	0x0a, 0x00, 0x00, 0x02, 0x00, 0x5d, 0x00, 0x05,
+	0x06, 0xc0, 0xa8, 0x01, 0x01, 0x00, 0x6c, 0x00,
+	0x04, 0x00, 0x00, 0x00, 0x01,
+}
+
+func TestPFCPPacket(t *testing.T) {
+	p := gopacket.NewPacket(testPFCPPacket, layers.LayerTypeEthernet, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Error("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+
+	if got, ok := p.Layer(LayerTypePFCP).(*PFCP); ok {
+		want := &PFCP{
+			Version:        1,
+			SEIDFlag:       true,
+			MessageType:    50,
+			MessageLength:  95,
+			SEID:           0,
+			SequenceNumber: 1,
+			IEs: []IE{
+				{Type: 60, Content: testPFCPPacket[62:67]},
+				{Type: 57, Content: testPFCPPacket[71:84]},
+				{Type: 1, Content: testPFCPPacket[88:141]},
+			},
+
+			Contents: testPFCPPacket[42:141],
+			Payload:  []uint8{},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("PFCP packet mismatch:\ngot  :\n%#v\n\nwant :\n%#v\n\n", got, want)
+		}
+	} else {
+		t.Error("Incorrect pfcp packet")
+	}
+}
+
+func TestFollowOnMessages(t *testing.T) {
+	// The Session Establishment Request with the follow-on flag set, followed by a Heartbeat Request
+	data := append([]byte{}, testPFCPPacket[42:141]...)
+	data[0] |= 0x04
+	data = append(data, 0x20, 0x01, 0x00, 0x0c, 0x00, 0x00, 0x02, 0x00, 0x00, 0x60, 0x00, 0x04, 0xe5, 0x00, 0x00, 0x00)
+
+	p := gopacket.NewPacket(data, LayerTypePFCP, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Fatal("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+
+	var got []uint8
+	for _, layer := range p.Layers() {
+		if msg, ok := layer.(*PFCP); ok {
+			got = append(got, msg.MessageType)
+		}
+	}
+	if want := []uint8{50, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("message types = %v, want %v", got, want)
+	}
+
+	// Without the follow-on flag the second message is left as payload
+	data[0] &^= 0x04
+	p = gopacket.NewPacket(data, LayerTypePFCP, gopacket.Default)
+	if n := len(p.Layers()); n != 2 || p.Layer(gopacket.LayerTypePayload) == nil {
+		t.Errorf("got %d layers without the follow-on flag, want PFCP and payload", n)
+	}
+}
+
+func TestDecodeIEs(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []IE
+		wantErr bool
+	}{
+		{
+			name: "Standard IE",
+			data: []byte{0x00, 0x13, 0x00, 0x01, 0x01},
+			want: []IE{{Type: 19, Content: []byte{0x01}}},
+		},
+		{
+			name: "Vendor-specific IE",
+			data: []byte{0x80, 0x01, 0x00, 0x03, 0x07, 0xdb, 0xaa},
+			want: []IE{{Type: 0x8001, EnterpriseID: 2011, Content: []byte{0xaa}}},
+		},
+		{
+			name:    "Truncated header",
+			data:    []byte{0x00, 0x13, 0x00},
+			wantErr: true,
+		},
+		{
+			name:    "Length exceeds data",
+			data:    []byte{0x00, 0x13, 0x00, 0x05, 0x01},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeIEs(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeIEs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeIEs() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package pfcpie
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+// GateStatusNames maps gate status values to their descriptions (3GPP TS 29.244 8.2.7)
+var GateStatusNames = map[uint8]string{
+	0: "OPEN",
+	1: "CLOSED",
+}
+
+// ApplyAction represents Apply Action IE (3GPP TS 29.244 8.2.26)
+type ApplyAction struct {
+	DROP bool // Drop
+	FORW bool // Forward
+	BUFF bool // Buffer
+	NOCP bool // Notify the CP function
+	DUPL bool // Duplicate
+	IPMA bool // IP Multicast Accept
+	IPMD bool // IP Multicast Deny
+	DFRT bool // Duplicate for Redundant Transmission
+	EDRT bool // Eliminate Duplicate Packets for Redundant Transmission
+	BDPN bool // Buffered Downlink Packet Notification
+	DDPN bool // Discarded Downlink Packet Notification
+}
+
+// GateStatus represents Gate Status IE (3GPP TS 29.244 8.2.7)
+type GateStatus struct {
+	UL interface{} `json:"UL"`
+	DL interface{} `json:"DL"`
+}
+
+// BitRate represents MBR and GBR IEs in kbps (3GPP TS 29.244 8.2.8, 8.2.9)
+type BitRate struct {
+	UL uint64 `json:"UL"`
+	DL uint64 `json:"DL"`
+}
+
+// SDFFilter represents SDF Filter IE (3GPP TS 29.244 8.2.5)
+type SDFFilter struct {
+	FlowDescription        string  `json:"FlowDescription,omitempty"`
+	ToSTrafficClass        string  `json:"ToSTrafficClass,omitempty"`
+	SecurityParameterIndex string  `json:"SecurityParameterIndex,omitempty"`
+	FlowLabel              string  `json:"FlowLabel,omitempty"`
+	SDFFilterID            *uint32 `json:"SDFFilterID,omitempty"`
+}
+
+// DecodeApplyAction decodes the Apply Action IE
+func DecodeApplyAction(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Apply Action")
+	}
+
+	action := ApplyAction{
+		DROP: data[0]&0x01 != 0,
+		FORW: data[0]&0x02 != 0,
+		BUFF: data[0]&0x04 != 0,
+		NOCP: data[0]&0x08 != 0,
+		DUPL: data[0]&0x10 != 0,
+		IPMA: data[0]&0x20 != 0,
+		IPMD: data[0]&0x40 != 0,
+		DFRT: data[0]&0x80 != 0,
+	}
+	// The second octet was added in Release 16
+	if len(data) > 1 {
+		action.EDRT = data[1]&0x01 != 0
+		action.BDPN = data[1]&0x02 != 0
+		action.DDPN = data[1]&0x04 != 0
+	}
+
+	return action, nil
+}
+
+// DecodeGateStatus decodes the Gate Status IE
+func DecodeGateStatus(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Gate Status")
+	}
+
+	ul := (data[0] >> 2) & 0x03
+	dl := data[0] & 0x03
+
+	return GateStatus{
+		UL: formatValue(GateStatusNames[ul], ul),
+		DL: formatValue(GateStatusNames[dl], dl),
+	}, nil
+}
+
+// DecodeBitRate decodes the MBR and GBR IEs, each direction is a 5-byte value in kbps
+func DecodeBitRate(data []byte) (interface{}, error) {
+	if len(data) < 10 {
+		return nil, fmt.Errorf("insufficient data for bit rate: expected 10 bytes, got %d", len(data))
+	}
+
+	return BitRate{
+		UL: uint64(data[0])<<32 | uint64(binary.BigEndian.Uint32(data[1:5])),
+		DL: uint64(data[5])<<32 | uint64(binary.BigEndian.Uint32(data[6:10])),
+	}, nil
+}
+
+// DecodeSDFFilter decodes the SDF Filter IE, fields are present according to the flags
+func DecodeSDFFilter(data []byte) (interface{}, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("insufficient data for SDF Filter")
+	}
+
+	flags := data[0]
+	filter := SDFFilter{}
+	// The flags octet is followed by a spare octet
+	index := 2
+
+	if flags&0x01 != 0 {
+		if len(data) < index+2 {
+			return nil, fmt.Errorf("insufficient data for flow description length")
+		}
+		length := int(binary.BigEndian.Uint16(data[index : index+2]))
+		index += 2
+		if len(data) < index+length {
+			return nil, fmt.Errorf("flow description length %d exceeds remaining data %d", length, len(data)-index)
+		}
+		filter.FlowDescription = string(data[index : index+length])
+		index += length
+	}
+
+	optional := []struct {
+		flag  uint8
+		size  int
+		field *string
+	}{
+		{0x02, 2, &filter.ToSTrafficClass},
+		{0x04, 4, &filter.SecurityParameterIndex},
+		{0x08, 3, &filter.FlowLabel},
+	}
+	for _, o := range optional {
+		if flags&o.flag == 0 {
+			continue
+		}
+		if len(data) < index+o.size {
+			return nil, fmt.Errorf("insufficient data for SDF Filter field")
+		}
+		*o.field = hex.EncodeToString(data[index : index+o.size])
+		index += o.size
+	}
+
+	if flags&0x10 != 0 {
+		if len(data) < index+4 {
+			return nil, fmt.Errorf("insufficient data for SDF Filter ID")
+		}
+		id := binary.BigEndian.Uint32(data[index : index+4])
+		filter.SDFFilterID = &id
+	}
+
+	return filter, nil
+}
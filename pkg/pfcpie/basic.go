@@ -0,0 +1,99 @@
+package pfcpie
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/vagabundor/gtp2json/config"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+)
+
+// CauseDescriptions maps a byte to a PFCP cause description (3GPP TS 29.244 8.2.1)
+var CauseDescriptions = map[byte]string{
+	1:  "Request accepted (success)",
+	2:  "More Usage Report to send",
+	64: "Request rejected (reason not specified)",
+	65: "Session context not found",
+	66: "Mandatory IE missing",
+	67: "Conditional IE missing",
+	68: "Invalid length",
+	69: "Mandatory IE incorrect",
+	70: "Invalid Forwarding Policy",
+	71: "Invalid F-TEID allocation option",
+	72: "No established PFCP Association",
+	73: "Rule creation/modification Failure",
+	74: "PFCP entity in congestion",
+	75: "No resources available",
+	76: "Service not supported",
+	77: "System failure",
+	78: "Redirection Requested",
+	79: "All dynamic addresses are occupied",
+	80: "Unknown Pre-defined Rule",
+	81: "Unknown Application ID",
+}
+
+// formatValue returns the formatted value based on the selected format
+func formatValue(description string, value uint8) interface{} {
+	switch config.GetOutputFormat() {
+	case "numeric":
+		return value
+	case "text":
+		return description
+	case "mixed":
+		return fmt.Sprintf("%s (%d)", description, value)
+	default:
+		return value
+	}
+}
+
+// DecodeCause decodes the PFCP Cause IE
+func DecodeCause(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Cause")
+	}
+
+	description, exists := CauseDescriptions[data[0]]
+	if !exists {
+		description = fmt.Sprintf("Unknown Cause (%d)", data[0])
+	}
+
+	return formatValue(description, data[0]), nil
+}
+
+// DecodeUint16 decodes 2-byte identifiers such as the PDR ID
+func DecodeUint16(data []byte) (interface{}, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("insufficient data: expected 2 bytes, got %d", len(data))
+	}
+	return binary.BigEndian.Uint16(data[:2]), nil
+}
+
+// DecodeUint32 decodes 4-byte values such as rule identifiers, precedence and durations
+func DecodeUint32(data []byte) (interface{}, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("insufficient data: expected 4 bytes, got %d", len(data))
+	}
+	return binary.BigEndian.Uint32(data[:4]), nil
+}
+
+// DecodeString decodes IEs carrying an octet string such as the Application ID
+func DecodeString(data []byte) (interface{}, error) {
+	return string(data), nil
+}
+
+// DecodeNetworkInstance decodes the Network Instance, encoded as a DNN/APN or as a plain string
+func DecodeNetworkInstance(data []byte) (interface{}, error) {
+	if len(data) > 0 && int(data[0]) < len(data) {
+		if apn, err := gtp2ie.DecodeAPN(data); err == nil {
+			return apn, nil
+		}
+	}
+	return string(data), nil
+}
+
+// DecodeQFI decodes the QoS Flow Identifier IE
+func DecodeQFI(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for QFI")
+	}
+	return data[0] & 0x3F, nil
+}
@@ -0,0 +1,260 @@
+package pfcpie
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+)
+
+// InterfaceValueNames maps Source and Destination Interface values to their descriptions (3GPP TS 29.244 8.2.2, 8.2.24)
+var InterfaceValueNames = map[uint8]string{
+	0: "Access",
+	1: "Core",
+	2: "SGi-LAN/N6-LAN",
+	3: "CP-function",
+	4: "5G VN Internal",
+}
+
+// OuterHeaderRemovalNames maps Outer Header Removal descriptions (3GPP TS 29.244 8.2.64)
+var OuterHeaderRemovalNames = map[uint8]string{
+	0: "GTP-U/UDP/IPv4",
+	1: "GTP-U/UDP/IPv6",
+	2: "UDP/IPv4",
+	3: "UDP/IPv6",
+	4: "IPv4",
+	5: "IPv6",
+	6: "GTP-U/UDP/IP",
+	7: "VLAN S-TAG",
+	8: "S-TAG and C-TAG",
+}
+
+// outerHeaderCreationNames lists Outer Header Creation description bits in specification order (3GPP TS 29.244 8.2.56)
+var outerHeaderCreationNames = []string{
+	"GTP-U/UDP/IPv4",
+	"GTP-U/UDP/IPv6",
+	"UDP/IPv4",
+	"UDP/IPv6",
+	"IPv4",
+	"IPv6",
+	"C-TAG",
+	"S-TAG",
+	"N19 Indication",
+	"N6 Indication",
+}
+
+// FTEID represents PFCP F-TEID IE (3GPP TS 29.244 8.2.3)
+type FTEID struct {
+	TEID     string `json:"TEID,omitempty"`
+	IPv4     string `json:"IPv4,omitempty"`
+	IPv6     string `json:"IPv6,omitempty"`
+	CH       bool   `json:"CH"` // UP function shall assign the F-TEID
+	ChooseID *uint8 `json:"ChooseID,omitempty"`
+}
+
+// FSEID represents F-SEID IE (3GPP TS 29.244 8.2.37)
+type FSEID struct {
+	SEID string `json:"SEID"`
+	IPv4 string `json:"IPv4,omitempty"`
+	IPv6 string `json:"IPv6,omitempty"`
+}
+
+// UEIPAddress represents UE IP Address IE (3GPP TS 29.244 8.2.62)
+type UEIPAddress struct {
+	IPv4                     string `json:"IPv4,omitempty"`
+	IPv6                     string `json:"IPv6,omitempty"`
+	SD                       bool   `json:"SD"` // The address is a destination address
+	IPv6PrefixDelegationBits *uint8 `json:"IPv6PrefixDelegationBits,omitempty"`
+	CHV4                     bool   `json:"CHV4"` // UP function shall assign an IPv4 address
+	CHV6                     bool   `json:"CHV6"` // UP function shall assign an IPv6 address
+}
+
+// OuterHeaderCreation represents Outer Header Creation IE (3GPP TS 29.244 8.2.56)
+type OuterHeaderCreation struct {
+	Description []string `json:"Description"`
+	TEID        string   `json:"TEID,omitempty"`
+	IPv4        string   `json:"IPv4,omitempty"`
+	IPv6        string   `json:"IPv6,omitempty"`
+	Port        *uint16  `json:"Port,omitempty"`
+}
+
+// DecodeInterface decodes the Source Interface and Destination Interface IEs
+func DecodeInterface(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for interface value")
+	}
+
+	value := data[0] & 0x0F
+	description, exists := InterfaceValueNames[value]
+	if !exists {
+		description = fmt.Sprintf("Unknown Interface (%d)", value)
+	}
+
+	return formatValue(description, value), nil
+}
+
+// DecodeFTEID decodes the PFCP F-TEID IE, which either carries an address or asks the UP function to choose one
+func DecodeFTEID(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for F-TEID")
+	}
+
+	flags := data[0]
+	fteid := FTEID{CH: flags&0x04 != 0}
+	index := 1
+
+	if fteid.CH {
+		if flags&0x08 != 0 {
+			if len(data) < 2 {
+				return nil, fmt.Errorf("insufficient data for F-TEID CHOOSE ID")
+			}
+			chooseID := data[1]
+			fteid.ChooseID = &chooseID
+		}
+		return fteid, nil
+	}
+
+	if len(data) < index+4 {
+		return nil, fmt.Errorf("insufficient data for F-TEID TEID")
+	}
+	fteid.TEID = hex.EncodeToString(data[index : index+4])
+	index += 4
+
+	ipv4, ipv6, _, err := decodeAddresses(data, index, flags&0x01 != 0, flags&0x02 != 0)
+	if err != nil {
+		return nil, fmt.Errorf("F-TEID: %w", err)
+	}
+	fteid.IPv4, fteid.IPv6 = ipv4, ipv6
+
+	return fteid, nil
+}
+
+// DecodeFSEID decodes the F-SEID IE
+func DecodeFSEID(data []byte) (interface{}, error) {
+	if len(data) < 9 {
+		return nil, fmt.Errorf("insufficient data for F-SEID: expected at least 9 bytes, got %d", len(data))
+	}
+
+	fseid := FSEID{SEID: hex.EncodeToString(data[1:9])}
+	ipv4, ipv6, _, err := decodeAddresses(data, 9, data[0]&0x02 != 0, data[0]&0x01 != 0)
+	if err != nil {
+		return nil, fmt.Errorf("F-SEID: %w", err)
+	}
+	fseid.IPv4, fseid.IPv6 = ipv4, ipv6
+
+	return fseid, nil
+}
+
+// DecodeUEIPAddress decodes the UE IP Address IE
+func DecodeUEIPAddress(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for UE IP Address")
+	}
+
+	flags := data[0]
+	ueip := UEIPAddress{
+		SD:   flags&0x04 != 0,
+		CHV4: flags&0x10 != 0,
+		CHV6: flags&0x20 != 0,
+	}
+
+	ipv4, ipv6, index, err := decodeAddresses(data, 1, flags&0x02 != 0, flags&0x01 != 0)
+	if err != nil {
+		return nil, fmt.Errorf("UE IP Address: %w", err)
+	}
+	ueip.IPv4, ueip.IPv6 = ipv4, ipv6
+
+	if flags&0x08 != 0 {
+		if len(data) < index+1 {
+			return nil, fmt.Errorf("insufficient data for IPv6 prefix delegation bits")
+		}
+		bits := data[index]
+		ueip.IPv6PrefixDelegationBits = &bits
+	}
+
+	return ueip, nil
+}
+
+// DecodeOuterHeaderCreation decodes the Outer Header Creation IE
+func DecodeOuterHeaderCreation(data []byte) (interface{}, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("insufficient data for Outer Header Creation")
+	}
+
+	description := binary.BigEndian.Uint16(data[:2])
+	ohc := OuterHeaderCreation{Description: make([]string, 0)}
+	for i, name := range outerHeaderCreationNames {
+		// Bits are numbered from the least significant bit of the first octet
+		bit := uint16(0x0100) << i
+		if i >= 8 {
+			bit = 1 << (i - 8)
+		}
+		if description&bit != 0 {
+			ohc.Description = append(ohc.Description, name)
+		}
+	}
+
+	gtpu := description&0x0300 != 0
+	udp := description&0x0C00 != 0
+	v4 := description&0x1500 != 0
+	v6 := description&0x2A00 != 0
+	index := 2
+
+	if gtpu {
+		if len(data) < index+4 {
+			return nil, fmt.Errorf("insufficient data for Outer Header Creation TEID")
+		}
+		ohc.TEID = hex.EncodeToString(data[index : index+4])
+		index += 4
+	}
+
+	ipv4, ipv6, index, err := decodeAddresses(data, index, v4, v6)
+	if err != nil {
+		return nil, fmt.Errorf("Outer Header Creation: %w", err)
+	}
+	ohc.IPv4, ohc.IPv6 = ipv4, ipv6
+
+	if udp {
+		if len(data) < index+2 {
+			return nil, fmt.Errorf("insufficient data for Outer Header Creation port")
+		}
+		port := binary.BigEndian.Uint16(data[index : index+2])
+		ohc.Port = &port
+	}
+
+	return ohc, nil
+}
+
+// DecodeOuterHeaderRemoval decodes the Outer Header Removal IE
+func DecodeOuterHeaderRemoval(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Outer Header Removal")
+	}
+
+	description, exists := OuterHeaderRemovalNames[data[0]]
+	if !exists {
+		description = fmt.Sprintf("Unknown Outer Header Removal (%d)", data[0])
+	}
+
+	return formatValue(description, data[0]), nil
+}
+
+// decodeAddresses decodes the optional IPv4 and IPv6 addresses following the given index and returns the next index
+func decodeAddresses(data []byte, index int, v4, v6 bool) (string, string, int, error) {
+	var ipv4, ipv6 string
+	if v4 {
+		if len(data) < index+4 {
+			return "", "", 0, fmt.Errorf("insufficient data for IPv4 address")
+		}
+		ipv4 = net.IP(data[index : index+4]).String()
+		index += 4
+	}
+	if v6 {
+		if len(data) < index+16 {
+			return "", "", 0, fmt.Errorf("insufficient data for IPv6 address")
+		}
+		ipv6 = net.IP(data[index : index+16]).String()
+		index += 16
+	}
+	return ipv4, ipv6, index, nil
+}
@@ -0,0 +1,59 @@
+package pfcpie
+
+import (
+	"fmt"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+	"net"
+)
+
+// NodeIDTypeNames maps Node ID types to their descriptions (3GPP TS 29.244 8.2.38)
+var NodeIDTypeNames = map[uint8]string{
+	0: "IPv4 address",
+	1: "IPv6 address",
+	2: "FQDN",
+}
+
+// NodeID represents Node ID IE (3GPP TS 29.244 8.2.38)
+type NodeID struct {
+	NodeIDType interface{} `json:"NodeIDType"`
+	IPv4       string      `json:"IPv4,omitempty"`
+	IPv6       string      `json:"IPv6,omitempty"`
+	FQDN       string      `json:"FQDN,omitempty"`
+}
+
+// DecodeNodeID decodes the Node ID IE
+func DecodeNodeID(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Node ID")
+	}
+
+	nodeIDType := data[0] & 0x0F
+	description, exists := NodeIDTypeNames[nodeIDType]
+	if !exists {
+		return nil, fmt.Errorf("unknown Node ID type: %d", nodeIDType)
+	}
+
+	nodeID := NodeID{NodeIDType: formatValue(description, nodeIDType)}
+	value := data[1:]
+
+	switch nodeIDType {
+	case 0:
+		if len(value) != 4 {
+			return nil, fmt.Errorf("invalid length for IPv4 Node ID: %d", len(value))
+		}
+		nodeID.IPv4 = net.IP(value).String()
+	case 1:
+		if len(value) != 16 {
+			return nil, fmt.Errorf("invalid length for IPv6 Node ID: %d", len(value))
+		}
+		nodeID.IPv6 = net.IP(value).String()
+	case 2:
+		fqdn, err := gtp2ie.DecodeFQDN(value)
+		if err != nil {
+			return nil, err
+		}
+		nodeID.FQDN = fqdn.(string)
+	}
+
+	return nodeID, nil
+}
@@ -0,0 +1,270 @@
+package pfcpie
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+	"github.com/vagabundor/gtp2json/pkg/pfcp"
+)
+
+const (
+	IETypeCreatePDR                  = 1
+	IETypePDI                        = 2
+	IETypeCreateFAR                  = 3
+	IETypeForwardingParameters       = 4
+	IETypeDuplicatingParameters      = 5
+	IETypeCreateURR                  = 6
+	IETypeCreateQER                  = 7
+	IETypeCreatedPDR                 = 8
+	IETypeUpdatePDR                  = 9
+	IETypeUpdateFAR                  = 10
+	IETypeUpdateForwardingParameters = 11
+	IETypeUpdateURR                  = 13
+	IETypeUpdateQER                  = 14
+	IETypeRemovePDR                  = 15
+	IETypeRemoveFAR                  = 16
+	IETypeRemoveURR                  = 17
+	IETypeRemoveQER                  = 18
+	IETypeCause                      = 19
+	IETypeSourceInterface            = 20
+	IETypeFTEID                      = 21
+	IETypeNetworkInstance            = 22
+	IETypeSDFFilter                  = 23
+	IETypeApplicationID              = 24
+	IETypeGateStatus                 = 25
+	IETypeMBR                        = 26
+	IETypeGBR                        = 27
+	IETypePrecedence                 = 29
+	IETypeVolumeThreshold            = 31
+	IETypeTimeThreshold              = 32
+	IETypeReportType                 = 39
+	IETypeDestinationInterface       = 42
+	IETypeApplyAction                = 44
+	IETypeSequenceNumber             = 52
+	IETypePDRID                      = 56
+	IETypeFSEID                      = 57
+	IETypeNodeID                     = 60
+	IETypeMeasurementMethod          = 62
+	IETypeUsageReportTrigger         = 63
+	IETypeVolumeMeasurement          = 66
+	IETypeDurationMeasurement        = 67
+	IETypeTimeOfFirstPacket          = 69
+	IETypeTimeOfLastPacket           = 70
+	IETypeStartTime                  = 75
+	IETypeEndTime                    = 76
+	IETypeUsageReportSMR             = 78
+	IETypeUsageReportSDR             = 79
+	IETypeUsageReportSRR             = 80
+	IETypeURRID                      = 81
+	IETypeLinkedURRID                = 82
+	IETypeOuterHeaderCreation        = 84
+	IETypeUEIPAddress                = 93
+	IETypeOuterHeaderRemoval         = 95
+	IETypeRecoveryTimeStamp          = 96
+	IETypeURSEQN                     = 104
+	IETypeFARID                      = 108
+	IETypeQERID                      = 109
+	IETypePDNType                    = 113
+	IETypeQFI                        = 124
+)
+
+// IE represents a decoded IE inside a grouped IE, it has the same JSON layout as the top-level IEs
+type IE struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// ieTypeNames maps IE types to their string representations
+var ieTypeNames = map[uint16]string{
+	IETypeCreatePDR:                  "CreatePDR",
+	IETypePDI:                        "PDI",
+	IETypeCreateFAR:                  "CreateFAR",
+	IETypeForwardingParameters:       "ForwardingParameters",
+	IETypeDuplicatingParameters:      "DuplicatingParameters",
+	IETypeCreateURR:                  "CreateURR",
+	IETypeCreateQER:                  "CreateQER",
+	IETypeCreatedPDR:                 "CreatedPDR",
+	IETypeUpdatePDR:                  "UpdatePDR",
+	IETypeUpdateFAR:                  "UpdateFAR",
+	IETypeUpdateForwardingParameters: "UpdateForwardingParameters",
+	IETypeUpdateURR:                  "UpdateURR",
+	IETypeUpdateQER:                  "UpdateQER",
+	IETypeRemovePDR:                  "RemovePDR",
+	IETypeRemoveFAR:                  "RemoveFAR",
+	IETypeRemoveURR:                  "RemoveURR",
+	IETypeRemoveQER:                  "RemoveQER",
+	IETypeCause:                      "Cause",
+	IETypeSourceInterface:            "SourceInterface",
+	IETypeFTEID:                      "F-TEID",
+	IETypeNetworkInstance:            "NetworkInstance",
+	IETypeSDFFilter:                  "SDFFilter",
+	IETypeApplicationID:              "ApplicationID",
+	IETypeGateStatus:                 "GateStatus",
+	IETypeMBR:                        "MBR",
+	IETypeGBR:                        "GBR",
+	IETypePrecedence:                 "Precedence",
+	IETypeVolumeThreshold:            "VolumeThreshold",
+	IETypeTimeThreshold:              "TimeThreshold",
+	IETypeReportType:                 "ReportType",
+	IETypeDestinationInterface:       "DestinationInterface",
+	IETypeApplyAction:                "ApplyAction",
+	IETypeSequenceNumber:             "SequenceNumber",
+	IETypePDRID:                      "PDRID",
+	IETypeFSEID:                      "F-SEID",
+	IETypeNodeID:                     "NodeID",
+	IETypeMeasurementMethod:          "MeasurementMethod",
+	IETypeUsageReportTrigger:         "UsageReportTrigger",
+	IETypeVolumeMeasurement:          "VolumeMeasurement",
+	IETypeDurationMeasurement:        "DurationMeasurement",
+	IETypeTimeOfFirstPacket:          "TimeOfFirstPacket",
+	IETypeTimeOfLastPacket:           "TimeOfLastPacket",
+	IETypeStartTime:                  "StartTime",
+	IETypeEndTime:                    "EndTime",
+	IETypeUsageReportSMR:             "UsageReport",
+	IETypeUsageReportSDR:             "UsageReport",
+	IETypeUsageReportSRR:             "UsageReport",
+	IETypeURRID:                      "URRID",
+	IETypeLinkedURRID:                "LinkedURRID",
+	IETypeOuterHeaderCreation:        "OuterHeaderCreation",
+	IETypeUEIPAddress:                "UEIPAddress",
+	IETypeOuterHeaderRemoval:         "OuterHeaderRemoval",
+	IETypeRecoveryTimeStamp:          "RecoveryTimeStamp",
+	IETypeURSEQN:                     "UR-SEQN",
+	IETypeFARID:                      "FARID",
+	IETypeQERID:                      "QERID",
+	IETypePDNType:                    "PDNType",
+	IETypeQFI:                        "QFI",
+}
+
+// groupedIETypes lists the IEs whose content is a list of IEs
+var groupedIETypes = map[uint16]bool{
+	IETypeCreatePDR:                  true,
+	IETypePDI:                        true,
+	IETypeCreateFAR:                  true,
+	IETypeForwardingParameters:       true,
+	IETypeDuplicatingParameters:      true,
+	IETypeCreateURR:                  true,
+	IETypeCreateQER:                  true,
+	IETypeCreatedPDR:                 true,
+	IETypeUpdatePDR:                  true,
+	IETypeUpdateFAR:                  true,
+	IETypeUpdateForwardingParameters: true,
+	IETypeUpdateURR:                  true,
+	IETypeUpdateQER:                  true,
+	IETypeRemovePDR:                  true,
+	IETypeRemoveFAR:                  true,
+	IETypeRemoveURR:                  true,
+	IETypeRemoveQER:                  true,
+	IETypeUsageReportSMR:             true,
+	IETypeUsageReportSDR:             true,
+	IETypeUsageReportSRR:             true,
+}
+
+// ProcessIE decodes the content of a given IE based on its type
+func ProcessIE(ie pfcp.IE) (string, interface{}, error) {
+	if ie.IsVendorSpecific() {
+		// Vendor-specific IEs are decoded like GTPv2 Private Extensions, so registered layouts apply
+		name := fmt.Sprintf("vendor_type_%d", ie.Type)
+		value, err := gtp2ie.DecodePrivateExtension(append([]byte{byte(ie.EnterpriseID >> 8), byte(ie.EnterpriseID)}, ie.Content...))
+		if err != nil {
+			return name, nil, fmt.Errorf("failed to decode %s: %w", name, err)
+		}
+		return name, value, nil
+	}
+
+	ieName, ok := ieTypeNames[ie.Type]
+	if !ok {
+		// Unknown type encode to hex
+		return fmt.Sprintf("unknown_type_%d", ie.Type), hex.EncodeToString(ie.Content), nil
+	}
+
+	if groupedIETypes[ie.Type] {
+		decodedContent, err := DecodeGroupedIE(ie.Content)
+		if err != nil {
+			return ieName, nil, fmt.Errorf("failed to decode %s: %w", ieName, err)
+		}
+		return ieName, decodedContent, nil
+	}
+
+	var decodeFunc func([]byte) (interface{}, error)
+	switch ie.Type {
+	case IETypeCause:
+		decodeFunc = DecodeCause
+	case IETypeSourceInterface, IETypeDestinationInterface:
+		decodeFunc = DecodeInterface
+	case IETypeFTEID:
+		decodeFunc = DecodeFTEID
+	case IETypeNetworkInstance:
+		decodeFunc = DecodeNetworkInstance
+	case IETypeSDFFilter:
+		decodeFunc = DecodeSDFFilter
+	case IETypeApplicationID:
+		decodeFunc = DecodeString
+	case IETypeGateStatus:
+		decodeFunc = DecodeGateStatus
+	case IETypeMBR, IETypeGBR:
+		decodeFunc = DecodeBitRate
+	case IETypePrecedence, IETypeSequenceNumber, IETypeURRID, IETypeLinkedURRID, IETypeFARID, IETypeQERID,
+		IETypeDurationMeasurement, IETypeTimeThreshold, IETypeURSEQN:
+		decodeFunc = DecodeUint32
+	case IETypeVolumeThreshold, IETypeVolumeMeasurement:
+		decodeFunc = DecodeVolume
+	case IETypeReportType:
+		decodeFunc = DecodeReportType
+	case IETypeApplyAction:
+		decodeFunc = DecodeApplyAction
+	case IETypePDRID:
+		decodeFunc = DecodeUint16
+	case IETypeFSEID:
+		decodeFunc = DecodeFSEID
+	case IETypeNodeID:
+		decodeFunc = DecodeNodeID
+	case IETypeMeasurementMethod:
+		decodeFunc = DecodeMeasurementMethod
+	case IETypeUsageReportTrigger:
+		decodeFunc = DecodeUsageReportTrigger
+	case IETypeTimeOfFirstPacket, IETypeTimeOfLastPacket, IETypeStartTime, IETypeEndTime, IETypeRecoveryTimeStamp:
+		decodeFunc = gtp2ie.DecodeNTPTimestamp
+	case IETypeOuterHeaderCreation:
+		decodeFunc = DecodeOuterHeaderCreation
+	case IETypeUEIPAddress:
+		decodeFunc = DecodeUEIPAddress
+	case IETypeOuterHeaderRemoval:
+		decodeFunc = DecodeOuterHeaderRemoval
+	case IETypePDNType:
+		decodeFunc = gtp2ie.DecodePDNType
+	case IETypeQFI:
+		decodeFunc = DecodeQFI
+	default:
+		return ieName, hex.EncodeToString(ie.Content), nil
+	}
+
+	decodedContent, err := decodeFunc(ie.Content)
+	if err != nil {
+		return ieName, nil, fmt.Errorf("failed to decode %s: %w", ieName, err)
+	}
+
+	return ieName, decodedContent, nil
+}
+
+// DecodeGroupedIE decodes the IEs embedded in a grouped IE
+func DecodeGroupedIE(data []byte) (interface{}, error) {
+	ies, err := pfcp.DecodeIEs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]IE, 0, len(ies))
+	for _, ie := range ies {
+		ieName, processedContent, err := ProcessIE(ie)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, IE{
+			Type:  ieName,
+			Value: processedContent,
+		})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,222 @@
+package pfcpie
+
+import (
+	"encoding/hex"
+	"github.com/vagabundor/gtp2json/config"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+	"github.com/vagabundor/gtp2json/pkg/pfcp"
+	"reflect"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestProcessIE(t *testing.T) {
+	u64 := func(v uint64) *uint64 { return &v }
+	u16 := func(v uint16) *uint16 { return &v }
+	u8 := func(v uint8) *uint8 { return &v }
+
+	tests := []struct {
+		name    string
+		ie      pfcp.IE
+		format  string
+		want    string
+		want1   interface{}
+		wantErr bool
+	}{
+		{
+			name:   "Cause Mixed",
+			ie:     pfcp.IE{Type: IETypeCause, Content: []byte{0x41}},
+			format: "mixed",
+			want:   "Cause",
+			want1:  "Session context not found (65)",
+		},
+		{
+			name:   "Node ID IPv4",
+			ie:     pfcp.IE{Type: IETypeNodeID, Content: []byte{0x00, 0x0a, 0x00, 0x00, 0x01}},
+			format: "text",
+			want:   "NodeID",
+			want1:  NodeID{NodeIDType: "IPv4 address", IPv4: "10.0.0.1"},
+		},
+		{
+			name:   "Node ID FQDN",
+			ie:     pfcp.IE{Type: IETypeNodeID, Content: []byte{0x02, 0x03, 0x75, 0x70, 0x66, 0x03, 0x6c, 0x61, 0x62}},
+			format: "numeric",
+			want:   "NodeID",
+			want1:  NodeID{NodeIDType: uint8(2), FQDN: "upf.lab"},
+		},
+		{
+			name:    "Node ID Unknown Type",
+			ie:      pfcp.IE{Type: IETypeNodeID, Content: []byte{0x05, 0x0a}},
+			format:  "numeric",
+			want:    "NodeID",
+			wantErr: true,
+		},
+		{
+			name:   "F-SEID IPv4",
+			ie:     pfcp.IE{Type: IETypeFSEID, Content: []byte{0x02, 0, 0, 0, 0, 0, 0, 0x12, 0x34, 0x0a, 0x00, 0x00, 0x01}},
+			format: "numeric",
+			want:   "F-SEID",
+			want1:  FSEID{SEID: "0000000000001234", IPv4: "10.0.0.1"},
+		},
+		{
+			name:   "F-TEID IPv4",
+			ie:     pfcp.IE{Type: IETypeFTEID, Content: []byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x0a, 0x00, 0x00, 0x02}},
+			format: "numeric",
+			want:   "F-TEID",
+			want1:  FTEID{TEID: "00000001", IPv4: "10.0.0.2"},
+		},
+		{
+			name:   "F-TEID Choose",
+			ie:     pfcp.IE{Type: IETypeFTEID, Content: []byte{0x0d, 0x05}},
+			format: "numeric",
+			want:   "F-TEID",
+			want1:  FTEID{CH: true, ChooseID: u8(5)},
+		},
+		{
+			name:    "F-TEID Truncated Address",
+			ie:      pfcp.IE{Type: IETypeFTEID, Content: []byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x0a}},
+			format:  "numeric",
+			want:    "F-TEID",
+			wantErr: true,
+		},
+		{
+			name:   "UE IP Address Destination IPv4",
+			ie:     pfcp.IE{Type: IETypeUEIPAddress, Content: []byte{0x06, 0xc0, 0xa8, 0x01, 0x01}},
+			format: "numeric",
+			want:   "UEIPAddress",
+			want1:  UEIPAddress{IPv4: "192.168.1.1", SD: true},
+		},
+		{
+			name:   "Outer Header Creation GTP-U/UDP/IPv4",
+			ie:     pfcp.IE{Type: IETypeOuterHeaderCreation, Content: []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x07, 0x0a, 0x00, 0x00, 0x03}},
+			format: "numeric",
+			want:   "OuterHeaderCreation",
+			want1:  OuterHeaderCreation{Description: []string{"GTP-U/UDP/IPv4"}, TEID: "00000007", IPv4: "10.0.0.3"},
+		},
+		{
+			name:   "Outer Header Creation UDP/IPv4",
+			ie:     pfcp.IE{Type: IETypeOuterHeaderCreation, Content: []byte{0x04, 0x00, 0x0a, 0x00, 0x00, 0x03, 0x08, 0x68}},
+			format: "numeric",
+			want:   "OuterHeaderCreation",
+			want1:  OuterHeaderCreation{Description: []string{"UDP/IPv4"}, IPv4: "10.0.0.3", Port: u16(2152)},
+		},
+		{
+			name:   "Apply Action",
+			ie:     pfcp.IE{Type: IETypeApplyAction, Content: []byte{0x0a}},
+			format: "numeric",
+			want:   "ApplyAction",
+			want1:  ApplyAction{FORW: true, NOCP: true},
+		},
+		{
+			name:   "Volume Measurement",
+			ie:     pfcp.IE{Type: IETypeVolumeMeasurement, Content: mustHex(t, "07"+"0000000000000300"+"0000000000000100"+"0000000000000200")},
+			format: "numeric",
+			want:   "VolumeMeasurement",
+			want1:  Volume{TotalVolume: u64(768), UplinkVolume: u64(256), DownlinkVolume: u64(512)},
+		},
+		{
+			name:   "Usage Report Trigger",
+			ie:     pfcp.IE{Type: IETypeUsageReportTrigger, Content: []byte{0x02, 0x08, 0x00}},
+			format: "numeric",
+			want:   "UsageReportTrigger",
+			want1:  []string{"VOLTH", "TERMR"},
+		},
+		{
+			name:   "Source Interface Text",
+			ie:     pfcp.IE{Type: IETypeSourceInterface, Content: []byte{0x00}},
+			format: "text",
+			want:   "SourceInterface",
+			want1:  "Access",
+		},
+		{
+			name:   "Network Instance DNN",
+			ie:     pfcp.IE{Type: IETypeNetworkInstance, Content: []byte{0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74}},
+			format: "numeric",
+			want:   "NetworkInstance",
+			want1:  "internet",
+		},
+		{
+			name:   "SDF Filter",
+			ie:     pfcp.IE{Type: IETypeSDFFilter, Content: append([]byte{0x01, 0x00, 0x00, 0x0f}, []byte("permit out ip f")...)},
+			format: "numeric",
+			want:   "SDFFilter",
+			want1:  SDFFilter{FlowDescription: "permit out ip f"},
+		},
+		{
+			name:   "MBR",
+			ie:     pfcp.IE{Type: IETypeMBR, Content: []byte{0x00, 0x00, 0x00, 0x03, 0xe8, 0x00, 0x00, 0x00, 0x07, 0xd0}},
+			format: "numeric",
+			want:   "MBR",
+			want1:  BitRate{UL: 1000, DL: 2000},
+		},
+		{
+			name:   "Create PDR",
+			ie:     pfcp.IE{Type: IETypeCreatePDR, Content: mustHex(t, "003800020001001d0004000000ff0002001b00140001000015000901000000010a000002005d000506c0a80101006c000400000001")},
+			format: "numeric",
+			want:   "CreatePDR",
+			want1: []IE{
+				{Type: "PDRID", Value: uint16(1)},
+				{Type: "Precedence", Value: uint32(255)},
+				{Type: "PDI", Value: []IE{
+					{Type: "SourceInterface", Value: uint8(0)},
+					{Type: "F-TEID", Value: FTEID{TEID: "00000001", IPv4: "10.0.0.2"}},
+					{Type: "UEIPAddress", Value: UEIPAddress{IPv4: "192.168.1.1", SD: true}},
+				}},
+				{Type: "FARID", Value: uint32(1)},
+			},
+		},
+		{
+			name:    "Create PDR Truncated Child",
+			ie:      pfcp.IE{Type: IETypeCreatePDR, Content: []byte{0x00, 0x38, 0x00, 0x02, 0x00}},
+			format:  "numeric",
+			want:    "CreatePDR",
+			wantErr: true,
+		},
+		{
+			name:   "Vendor-specific IE",
+			ie:     pfcp.IE{Type: 0x8001, EnterpriseID: 2011, Content: []byte{0xaa}},
+			format: "numeric",
+			want:   "vendor_type_32769",
+			want1:  gtp2ie.PrivateExtension{EnterpriseID: 2011, Vendor: "Huawei", Value: "aa"},
+		},
+		{
+			name:   "Recovery Time Stamp",
+			ie:     pfcp.IE{Type: IETypeRecoveryTimeStamp, Content: []byte{0xe9, 0x3c, 0x7f, 0x00}},
+			format: "numeric",
+			want:   "RecoveryTimeStamp",
+			want1:  "2024-01-01T00:00:00Z",
+		},
+		{
+			name:   "Unknown IE Type",
+			ie:     pfcp.IE{Type: 250, Content: []byte{0x01, 0x02}},
+			format: "numeric",
+			want:   "unknown_type_250",
+			want1:  "0102",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.SetOutputFormat(tt.format)
+			got, got1, err := ProcessIE(tt.ie)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ProcessIE() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ProcessIE() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(got1, tt.want1) {
+				t.Errorf("ProcessIE() got1 = %#v, want %#v", got1, tt.want1)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package pfcpie
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// usageReportTriggerNames lists Usage Report Trigger bits from the least significant bit of the first octet (3GPP TS 29.244 8.2.41)
+var usageReportTriggerNames = [][]string{
+	{"PERIO", "VOLTH", "TIMTH", "QUHTI", "START", "STOPT", "DROTH", "IMMER"},
+	{"VOLQU", "TIMQU", "LIUSA", "TERMR", "MONIT", "ENVCL", "MACAR", "EVETH"},
+	{"EVEQU", "TEBUR", "IPMJL", "QUVTI", "EMRRE", "UPINT"},
+}
+
+// Volume represents Volume Measurement and Volume Threshold IEs (3GPP TS 29.244 8.2.44, 8.2.13)
+type Volume struct {
+	TotalVolume     *uint64 `json:"TotalVolume,omitempty"`
+	UplinkVolume    *uint64 `json:"UplinkVolume,omitempty"`
+	DownlinkVolume  *uint64 `json:"DownlinkVolume,omitempty"`
+	TotalPackets    *uint64 `json:"TotalPackets,omitempty"`
+	UplinkPackets   *uint64 `json:"UplinkPackets,omitempty"`
+	DownlinkPackets *uint64 `json:"DownlinkPackets,omitempty"`
+}
+
+// ReportType represents Report Type IE (3GPP TS 29.244 8.2.21)
+type ReportType struct {
+	DLDR bool // Downlink Data Report
+	USAR bool // Usage Report
+	ERIR bool // Error Indication Report
+	UPIR bool // User Plane Inactivity Report
+	TMIR bool // TSC Management Information Report
+	SESR bool // Session Report
+	UISR bool // UP Initiated Session Request
+}
+
+// MeasurementMethod represents Measurement Method IE (3GPP TS 29.244 8.2.40)
+type MeasurementMethod struct {
+	DURAT bool // Duration
+	VOLUM bool // Volume
+	EVENT bool // Event
+}
+
+// DecodeVolume decodes the Volume Measurement and Volume Threshold IEs, fields are present according to the flags
+func DecodeVolume(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for volume")
+	}
+
+	flags := data[0]
+	volume := Volume{}
+	fields := []**uint64{
+		&volume.TotalVolume, &volume.UplinkVolume, &volume.DownlinkVolume,
+		&volume.TotalPackets, &volume.UplinkPackets, &volume.DownlinkPackets,
+	}
+
+	index := 1
+	for i, field := range fields {
+		if flags&(1<<i) == 0 {
+			continue
+		}
+		if len(data) < index+8 {
+			return nil, fmt.Errorf("insufficient data for volume field %d", i)
+		}
+		value := binary.BigEndian.Uint64(data[index : index+8])
+		*field = &value
+		index += 8
+	}
+
+	return volume, nil
+}
+
+// DecodeUsageReportTrigger decodes the Usage Report Trigger IE into the names of the set triggers
+func DecodeUsageReportTrigger(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Usage Report Trigger")
+	}
+
+	triggers := make([]string, 0)
+	for octet, names := range usageReportTriggerNames {
+		if octet >= len(data) {
+			break
+		}
+		for bit, name := range names {
+			if data[octet]&(1<<bit) != 0 {
+				triggers = append(triggers, name)
+			}
+		}
+	}
+
+	return triggers, nil
+}
+
+// DecodeReportType decodes the Report Type IE
+func DecodeReportType(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Report Type")
+	}
+
+	return ReportType{
+		DLDR: data[0]&0x01 != 0,
+		USAR: data[0]&0x02 != 0,
+		ERIR: data[0]&0x04 != 0,
+		UPIR: data[0]&0x08 != 0,
+		TMIR: data[0]&0x10 != 0,
+		SESR: data[0]&0x20 != 0,
+		UISR: data[0]&0x40 != 0,
+	}, nil
+}
+
+// DecodeMeasurementMethod decodes the Measurement Method IE
+func DecodeMeasurementMethod(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("insufficient data for Measurement Method")
+	}
+
+	return MeasurementMethod{
+		DURAT: data[0]&0x01 != 0,
+		VOLUM: data[0]&0x02 != 0,
+		EVENT: data[0]&0x04 != 0,
+	}, nil
+}
@@ -0,0 +1,337 @@
+package protoenc
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the generic cases of the Value oneof
+const (
+	valueString    protowire.Number = 1
+	valueInt       protowire.Number = 2
+	valueUint      protowire.Number = 3
+	valueDouble    protowire.Number = 4
+	valueBool      protowire.Number = 5
+	valueBytes     protowire.Number = 6
+	valueStruct    protowire.Number = 7
+	valueList      protowire.Number = 8
+	valueTimestamp protowire.Number = 9
+)
+
+// Marshal encodes a registered record as a Record message
+func Marshal(record interface{}) ([]byte, error) {
+	v := reflect.ValueOf(record)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	reg.mu.Lock()
+	index, ok := reg.recordByType[v.Type()]
+	reg.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("record type %s is not registered", v.Type())
+	}
+
+	body := appendMessage(nil, v, reg.records[index])
+	b := protowire.AppendTag(nil, protowire.Number(index+1), protowire.BytesType)
+	return protowire.AppendBytes(b, body), nil
+}
+
+// AppendDelimited appends a message prefixed with its varint length, the usual framing of protobuf streams
+func AppendDelimited(b []byte, msg []byte) []byte {
+	return protowire.AppendBytes(b, msg)
+}
+
+// appendMessage encodes the fields of a struct value
+func appendMessage(b []byte, v reflect.Value, m *message) []byte {
+	for i := range m.fields {
+		f := &m.fields[i]
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok {
+			continue
+		}
+		if f.optional {
+			if fv.IsNil() {
+				continue
+			}
+			b = appendScalar(b, protowire.Number(f.number), f.kind, fv.Elem())
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if f.repeated {
+			b = appendRepeated(b, f, fv)
+			continue
+		}
+		b = appendSingular(b, f, fv)
+	}
+	return b
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex that tolerates nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// appendSingular encodes a non-repeated field, zero values are omitted as in proto3
+func appendSingular(b []byte, f *field, v reflect.Value) []byte {
+	num := protowire.Number(f.number)
+	switch f.kind {
+	case kindMessage:
+		if v.IsZero() {
+			return b
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, appendMessage(nil, v, f.message))
+	case kindValue:
+		if v.IsNil() {
+			return b
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, appendValue(nil, v.Elem()))
+	}
+	if v.IsZero() {
+		return b
+	}
+	return appendScalar(b, num, f.kind, v)
+}
+
+// appendRepeated encodes a slice, numeric scalars are packed
+func appendRepeated(b []byte, f *field, v reflect.Value) []byte {
+	if v.Len() == 0 {
+		return b
+	}
+	num := protowire.Number(f.number)
+
+	switch f.kind {
+	case kindBool, kindUint32, kindUint64, kindInt64, kindDouble:
+		var packed []byte
+		for i := 0; i < v.Len(); i++ {
+			packed = appendPacked(packed, f.kind, indirect(v.Index(i)))
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, packed)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		switch f.kind {
+		case kindMessage:
+			elem = indirect(elem)
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			if !elem.IsValid() {
+				b = protowire.AppendBytes(b, nil)
+				continue
+			}
+			b = protowire.AppendBytes(b, appendMessage(nil, elem, f.message))
+		case kindValue:
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendBytes(b, appendValue(nil, elem.Elem()))
+		default:
+			b = appendScalar(b, num, f.kind, indirect(elem))
+		}
+	}
+	return b
+}
+
+// indirect dereferences pointers, a nil pointer yields an invalid value
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// appendPacked appends a numeric value without a tag
+func appendPacked(b []byte, kind fieldKind, v reflect.Value) []byte {
+	if !v.IsValid() {
+		return protowire.AppendVarint(b, 0)
+	}
+	switch kind {
+	case kindBool:
+		return protowire.AppendVarint(b, protowire.EncodeBool(v.Bool()))
+	case kindUint32, kindUint64:
+		return protowire.AppendVarint(b, v.Uint())
+	case kindInt64:
+		return protowire.AppendVarint(b, uint64(v.Int()))
+	case kindDouble:
+		return protowire.AppendFixed64(b, math.Float64bits(v.Float()))
+	}
+	return b
+}
+
+// appendScalar appends a tagged scalar, timestamp or message value
+func appendScalar(b []byte, num protowire.Number, kind fieldKind, v reflect.Value) []byte {
+	switch kind {
+	case kindBool, kindUint32, kindUint64, kindInt64:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return appendPacked(b, kind, v)
+	case kindDouble:
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return appendPacked(b, kind, v)
+	case kindString:
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendString(b, v.String())
+	case kindBytes:
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v.Bytes())
+	case kindTimestamp:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return b
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, appendTimestamp(nil, t))
+	}
+	return b
+}
+
+// appendTimestamp encodes a google.protobuf.Timestamp
+func appendTimestamp(b []byte, t time.Time) []byte {
+	if seconds := t.Unix(); seconds != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(seconds))
+	}
+	if nanos := t.Nanosecond(); nanos != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(nanos))
+	}
+	return b
+}
+
+// appendValue encodes a dynamic value as a Value message. Registered struct types use their typed
+// case, other structs and maps become a Struct of named fields and slices a List.
+func appendValue(b []byte, v reflect.Value) []byte {
+	if !v.IsValid() {
+		return b
+	}
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return b
+		}
+		return appendValue(b, v.Elem())
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		b = protowire.AppendTag(b, valueTimestamp, protowire.BytesType)
+		return protowire.AppendBytes(b, appendTimestamp(nil, t))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		b = protowire.AppendTag(b, valueString, protowire.BytesType)
+		return protowire.AppendString(b, v.String())
+	case reflect.Bool:
+		b = protowire.AppendTag(b, valueBool, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b = protowire.AppendTag(b, valueInt, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeZigZag(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b = protowire.AppendTag(b, valueUint, protowire.VarintType)
+		return protowire.AppendVarint(b, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		b = protowire.AppendTag(b, valueDouble, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(v.Float()))
+	case reflect.Struct:
+		reg.mu.Lock()
+		index, typed := reg.valueByType[v.Type()]
+		reg.mu.Unlock()
+		if typed {
+			b = protowire.AppendTag(b, protowire.Number(firstValueNumber+index), protowire.BytesType)
+			return protowire.AppendBytes(b, appendMessage(nil, v, reg.values[index]))
+		}
+		b = protowire.AppendTag(b, valueStruct, protowire.BytesType)
+		return protowire.AppendBytes(b, appendStruct(nil, v))
+	case reflect.Map:
+		b = protowire.AppendTag(b, valueStruct, protowire.BytesType)
+		return protowire.AppendBytes(b, appendMap(nil, v))
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			b = protowire.AppendTag(b, valueBytes, protowire.BytesType)
+			return protowire.AppendBytes(b, v.Bytes())
+		}
+		var list []byte
+		for i := 0; i < v.Len(); i++ {
+			list = protowire.AppendTag(list, 1, protowire.BytesType)
+			list = protowire.AppendBytes(list, appendValue(nil, v.Index(i)))
+		}
+		b = protowire.AppendTag(b, valueList, protowire.BytesType)
+		return protowire.AppendBytes(b, list)
+	}
+	return b
+}
+
+// appendStructField appends a Field entry of a Struct
+func appendStructField(b []byte, name string, v reflect.Value) []byte {
+	var entry []byte
+	entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, name)
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendBytes(entry, appendValue(nil, v))
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, entry)
+}
+
+// appendStruct encodes an unregistered struct with its JSON field names
+func appendStruct(b []byte, v reflect.Value) []byte {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Tag.Get("json") == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && indirect(fv).Kind() == reflect.Struct && sf.Tag.Get("json") == "" {
+			if inner := indirect(fv); inner.IsValid() {
+				b = appendStruct(b, inner)
+			}
+			continue
+		}
+		if fv.IsZero() {
+			continue
+		}
+		b = appendStructField(b, jsonName(sf), fv)
+	}
+	return b
+}
+
+// appendMap encodes a map with sorted keys so that the output is deterministic
+func appendMap(b []byte, v reflect.Value) []byte {
+	keys := v.MapKeys()
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = fmt.Sprint(k.Interface())
+	}
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return names[order[i]] < names[order[j]] })
+
+	for _, i := range order {
+		b = appendStructField(b, names[i], v.MapIndex(keys[i]))
+	}
+	return b
+}
@@ -0,0 +1,307 @@
+package protoenc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+)
+
+// The protobuf schema is derived from the Go types of the output records. Fields are numbered
+// in declaration order, embedded structs are flattened like encoding/json does, so new fields
+// have to be added at the end of a struct to keep existing field numbers stable.
+
+// fieldKind classifies how a Go field is encoded
+type fieldKind int
+
+const (
+	kindBool fieldKind = iota
+	kindUint32
+	kindUint64
+	kindInt64
+	kindDouble
+	kindString
+	kindBytes
+	kindTimestamp
+	kindMessage
+	kindValue
+)
+
+// protoTypes holds the protobuf type names of the scalar kinds
+var protoTypes = map[fieldKind]string{
+	kindBool:      "bool",
+	kindUint32:    "uint32",
+	kindUint64:    "uint64",
+	kindInt64:     "int64",
+	kindDouble:    "double",
+	kindString:    "string",
+	kindBytes:     "bytes",
+	kindTimestamp: "google.protobuf.Timestamp",
+	kindValue:     "Value",
+}
+
+// field describes a single message field
+type field struct {
+	name     string
+	number   int
+	index    []int
+	kind     fieldKind
+	repeated bool
+	optional bool
+	message  *message
+}
+
+// message describes the protobuf message of a Go struct type
+type message struct {
+	name   string
+	goType reflect.Type
+	fields []field
+}
+
+// registry holds the message descriptors and the oneof cases of Record and Value
+type registry struct {
+	mu       sync.Mutex
+	messages map[reflect.Type]*message
+	names    map[string]reflect.Type
+	order    []*message
+
+	records      []*message
+	recordNames  []string
+	recordByType map[reflect.Type]int
+
+	values      []*message
+	valueByType map[reflect.Type]int
+}
+
+var reg = &registry{
+	messages:     map[reflect.Type]*message{},
+	names:        map[string]reflect.Type{},
+	recordByType: map[reflect.Type]int{},
+	valueByType:  map[reflect.Type]int{},
+}
+
+// firstValueNumber is the field number of the first typed case of the Value oneof,
+// the numbers below it are used by the generic cases
+const firstValueNumber = 16
+
+func init() {
+	// Typed IE values, the order defines the Value oneof field numbers and must only be appended to
+	for _, v := range []interface{}{
+		gtp2ie.AMBR{}, gtp2ie.BearerContext{}, gtp2ie.BearerQoS{}, gtp2ie.Cause{}, gtp2ie.ChargingChars{},
+		gtp2ie.NodeIdentifier{}, gtp2ie.FTEID{}, gtp2ie.Indication{}, gtp2ie.MCCMNC{}, gtp2ie.PAA{},
+		gtp2ie.PCO{}, gtp2ie.PCOOption{}, gtp2ie.IPCPOption{}, gtp2ie.IPCP{}, gtp2ie.PAP{}, gtp2ie.CHAP{},
+		gtp2ie.APNRateControl{}, gtp2ie.PresenceReportingAreaAction{}, gtp2ie.PresenceReportingAreaInformation{},
+		gtp2ie.AdditionalPRA{}, gtp2ie.HomeENodebID{}, gtp2ie.PrivateExtension{}, gtp2ie.TLVOption{},
+		gtp2ie.QoSRule{}, gtp2ie.PacketFilter{}, gtp2ie.QoSFlowDescription{}, gtp2ie.QoSFlowParameter{},
+		gtp2ie.SecondaryRATUsageDataReport{}, gtp2ie.UETimeZone{}, gtp2ie.ULI{}, gtp2ie.CGI{}, gtp2ie.SAI{},
+		gtp2ie.RAI{}, gtp2ie.TAI{}, gtp2ie.ECGI{}, gtp2ie.LAI{}, gtp2ie.MacroENodebID{},
+		gtp2ie.ExtendedMacroENodebID{},
+	} {
+		if err := RegisterValue(v); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterRecord adds a record type as a case of the Record oneof under the given message name.
+// Records are numbered in registration order. It is meant to be called from init functions.
+func RegisterRecord(name string, record interface{}) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	t := reflect.TypeOf(record)
+	if _, exists := reg.recordByType[t]; exists {
+		return fmt.Errorf("record type %s already registered", t)
+	}
+	m, err := reg.messageFor(t, name)
+	if err != nil {
+		return err
+	}
+	reg.recordByType[t] = len(reg.records)
+	reg.records = append(reg.records, m)
+	reg.recordNames = append(reg.recordNames, name)
+	return nil
+}
+
+// RegisterValue adds a struct type as a typed case of the Value oneof used for interface{} fields
+// such as IE values. Values of types not registered are encoded as a generic Struct.
+func RegisterValue(value interface{}) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	t := reflect.TypeOf(value)
+	if _, exists := reg.valueByType[t]; exists {
+		return fmt.Errorf("value type %s already registered", t)
+	}
+	m, err := reg.messageFor(t, "")
+	if err != nil {
+		return err
+	}
+	reg.valueByType[t] = len(reg.values)
+	reg.values = append(reg.values, m)
+	return nil
+}
+
+// messageFor returns the message describing a struct type, building it on first use
+func (r *registry) messageFor(t reflect.Type, name string) (*message, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", t)
+	}
+	if m, ok := r.messages[t]; ok {
+		return m, nil
+	}
+
+	if name == "" {
+		name = t.Name()
+		// Types of different packages may share a name, the later one is prefixed with its package
+		if other, taken := r.names[name]; taken && other != t {
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+	}
+	if other, taken := r.names[name]; taken && other != t {
+		return nil, fmt.Errorf("message name %s is used by %s and %s", name, other, t)
+	}
+
+	// The message is stored before its fields are built so that recursive types terminate
+	m := &message{name: name, goType: t}
+	r.messages[t] = m
+	r.names[name] = t
+	r.order = append(r.order, m)
+
+	seen := map[string]bool{}
+	if err := r.addFields(m, t, nil, seen); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// addFields appends the exported fields of t to m, flattening embedded structs
+func (r *registry) addFields(m *message, t reflect.Type, index []int, seen map[string]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" || !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && tag == "" {
+			// Embedded fields are numbered where they are declared
+			if err := r.addFields(m, sf.Type, fieldIndex, seen); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := snakeCase(jsonName(sf))
+		// Like encoding/json, the first field of a name wins
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		f := field{name: name, number: len(m.fields) + 1, index: fieldIndex}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			f.optional = true
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 {
+			f.repeated, f.optional = true, false
+			ft = ft.Elem()
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+		}
+
+		kind, err := kindOf(ft)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", t.Name(), sf.Name, err)
+		}
+		f.kind = kind
+		if kind == kindMessage {
+			// Message fields have presence anyway
+			f.optional = false
+			if f.message, err = r.messageFor(ft, ""); err != nil {
+				return err
+			}
+		}
+		if kind == kindValue || kind == kindTimestamp {
+			f.optional = false
+		}
+		m.fields = append(m.fields, f)
+	}
+	return nil
+}
+
+// kindOf maps a Go type to its encoding
+func kindOf(t reflect.Type) (fieldKind, error) {
+	if t == reflect.TypeOf(time.Time{}) {
+		return kindTimestamp, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return kindBool, nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return kindUint32, nil
+	case reflect.Uint, reflect.Uint64:
+		return kindUint64, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return kindInt64, nil
+	case reflect.Float32, reflect.Float64:
+		return kindDouble, nil
+	case reflect.String:
+		return kindString, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return kindBytes, nil
+		}
+	case reflect.Struct:
+		return kindMessage, nil
+	case reflect.Interface:
+		return kindValue, nil
+	}
+	return 0, fmt.Errorf("unsupported type %s", t)
+}
+
+// jsonName returns the name of a field in the JSON output
+func jsonName(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("json"); tag != "" {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+// acronyms holds mixed-case abbreviations that are written as a single word in field names
+var acronyms = strings.NewReplacer("IPv", "Ipv", "GTPv", "Gtpv", "QoS", "Qos", "TEIDs", "Teids")
+
+// snakeCase converts a JSON or Go field name to a protobuf field name, e.g. srcIP to src_ip
+// and Macro_eNodebID to macro_e_nodeb_id
+func snakeCase(name string) string {
+	runes := []rune(acronyms.Replace(name))
+	var b strings.Builder
+	for i, c := range runes {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+			continue
+		}
+		if unicode.IsUpper(c) && i > 0 && !strings.HasSuffix(b.String(), "_") {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
@@ -0,0 +1,188 @@
+package protoenc
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type TestEndpoint struct {
+	SrcIP string `json:"srcIP,omitempty"`
+	DSCP  uint8  `json:"dscp"`
+}
+
+type TestIE struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type testRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	TestEndpoint
+	MessageType uint8    `json:"messageType"`
+	TEIDs       []uint32 `json:"teids,omitempty"`
+	GREKey      *uint32  `json:"greKey,omitempty"`
+	IEs         []TestIE `json:"ies"`
+}
+
+func init() {
+	if err := RegisterRecord("TestRecord", testRecord{}); err != nil {
+		panic(err)
+	}
+}
+
+// wireField is a decoded field of a message
+type wireField struct {
+	num   protowire.Number
+	value uint64
+	bytes []byte
+}
+
+// parse splits a message into its fields
+func parse(t *testing.T, b []byte) []wireField {
+	t.Helper()
+	var fields []wireField
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		f := wireField{num: num}
+		switch typ {
+		case protowire.VarintType:
+			f.value, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.value, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+		if n < 0 {
+			t.Fatalf("invalid field %d: %v", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func TestMarshal(t *testing.T) {
+	greKey := uint32(0)
+	record := testRecord{
+		Timestamp:    time.Unix(1700000000, 5000),
+		TestEndpoint: TestEndpoint{SrcIP: "10.0.0.1"},
+		MessageType:  32,
+		TEIDs:        []uint32{1, 300},
+		GREKey:       &greKey,
+		IEs: []TestIE{
+			{Type: "Cause", Value: gtp2ie.Cause{CauseValue: uint8(16)}},
+			{Type: "Recovery", Value: uint8(5)},
+			{Type: "Unknown", Value: map[string]interface{}{"b": "x", "a": -1}},
+		},
+	}
+
+	data, err := Marshal(&record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope := parse(t, data)
+	if len(envelope) != 1 || envelope[0].num != protowire.Number(len(reg.records)) {
+		t.Fatalf("unexpected envelope %+v", envelope)
+	}
+	fields := parse(t, envelope[0].bytes)
+
+	// The zero DSCP and empty fields are omitted, the set optional GREKey is kept
+	wantNumbers := []protowire.Number{1, 2, 4, 5, 6, 7, 7, 7}
+	if len(fields) != len(wantNumbers) {
+		t.Fatalf("got %d fields, want %d: %+v", len(fields), len(wantNumbers), fields)
+	}
+	for i, f := range fields {
+		if f.num != wantNumbers[i] {
+			t.Errorf("field %d has number %d, want %d", i, f.num, wantNumbers[i])
+		}
+	}
+
+	if ts := parse(t, fields[0].bytes); len(ts) != 2 || ts[0].value != 1700000000 || ts[1].value != 5000 {
+		t.Errorf("unexpected timestamp %+v", ts)
+	}
+	if string(fields[1].bytes) != "10.0.0.1" {
+		t.Errorf("srcIP = %q", fields[1].bytes)
+	}
+	if !bytes.Equal(fields[3].bytes, []byte{0x01, 0xac, 0x02}) {
+		t.Errorf("packed teids = %x", fields[3].bytes)
+	}
+	if fields[4].value != 0 {
+		t.Errorf("greKey = %d", fields[4].value)
+	}
+
+	// Typed IE values use their Value case, scalars and maps the generic ones
+	cause := parse(t, parse(t, fields[5].bytes)[1].bytes)
+	if cause[0].num != protowire.Number(firstValueNumber+reg.valueByType[reflect.TypeOf(gtp2ie.Cause{})]) {
+		t.Errorf("cause value case = %d", cause[0].num)
+	}
+	recovery := parse(t, parse(t, fields[6].bytes)[1].bytes)
+	if recovery[0].num != valueUint || recovery[0].value != 5 {
+		t.Errorf("recovery value = %+v", recovery)
+	}
+	unknown := parse(t, parse(t, fields[7].bytes)[1].bytes)
+	if unknown[0].num != valueStruct {
+		t.Fatalf("unknown value case = %d", unknown[0].num)
+	}
+	entries := parse(t, unknown[0].bytes)
+	if len(entries) != 2 || string(parse(t, entries[0].bytes)[0].bytes) != "a" {
+		t.Errorf("map entries are not sorted: %+v", entries)
+	}
+}
+
+func TestMarshalUnregistered(t *testing.T) {
+	if _, err := Marshal(struct{}{}); err == nil {
+		t.Error("Marshal() of an unregistered type did not fail")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	for _, want := range []string{
+		`syntax = "proto3";`,
+		"    TestRecord test_record = ",
+		"    Cause cause_value = 19;",
+		"message TestRecord {\n  google.protobuf.Timestamp timestamp = 1;\n  string src_ip = 2;\n  uint32 dscp = 3;\n" +
+			"  uint32 message_type = 4;\n  repeated uint32 teids = 5;\n  optional uint32 gre_key = 6;\n  repeated TestIE ies = 7;\n}",
+		"message TestIE {\n  string type = 1;\n  Value value = 2;\n}",
+	} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("Schema() does not contain %q", want)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"srcIP", "src_ip"},
+		{"messageType", "message_type"},
+		{"F-TEID-IPv4", "f_teid_ipv4"},
+		{"GTPv2Packet", "gtpv2_packet"},
+		{"bearerQoS", "bearer_qos"},
+		{"Macro_eNodebID", "macro_e_nodeb_id"},
+		{"FTEIDs", "f_teids"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snakeCase(tt.name); got != tt.want {
+				t.Errorf("snakeCase(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
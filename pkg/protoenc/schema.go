@@ -0,0 +1,80 @@
+package protoenc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Package is the protobuf package of the generated schema
+const Package = "gtp2json.v1"
+
+// genericMessages are the messages of the Value oneof that do not depend on registered types
+const genericMessages = `// Struct holds a value of a type without a dedicated message as named fields
+message Struct {
+  repeated Field fields = 1;
+}
+
+message Field {
+  string name = 1;
+  Value value = 2;
+}
+
+message List {
+  repeated Value values = 1;
+}
+`
+
+// Schema returns the .proto definition of all registered records and values
+func Schema() string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("// Code generated by gtp2json --print-proto. DO NOT EDIT.\n\n")
+	b.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&b, "package %s;\n\n", Package)
+	b.WriteString("import \"google/protobuf/timestamp.proto\";\n\n")
+
+	b.WriteString("// Record is the envelope of every encoded message\n")
+	b.WriteString("message Record {\n  oneof record {\n")
+	for i, m := range reg.records {
+		fmt.Fprintf(&b, "    %s %s = %d;\n", m.name, snakeCase(reg.recordNames[i]), i+1)
+	}
+	b.WriteString("  }\n}\n\n")
+
+	b.WriteString("// Value holds the decoded value of an IE or any other dynamically typed field\n")
+	b.WriteString("message Value {\n  oneof kind {\n")
+	b.WriteString("    string string_value = 1;\n")
+	b.WriteString("    sint64 int_value = 2;\n")
+	b.WriteString("    uint64 uint_value = 3;\n")
+	b.WriteString("    double double_value = 4;\n")
+	b.WriteString("    bool bool_value = 5;\n")
+	b.WriteString("    bytes bytes_value = 6;\n")
+	b.WriteString("    Struct struct_value = 7;\n")
+	b.WriteString("    List list_value = 8;\n")
+	b.WriteString("    google.protobuf.Timestamp timestamp_value = 9;\n")
+	for i, m := range reg.values {
+		fmt.Fprintf(&b, "    %s %s = %d;\n", m.name, snakeCase(m.name)+"_value", firstValueNumber+i)
+	}
+	b.WriteString("  }\n}\n\n")
+	b.WriteString(genericMessages)
+
+	for _, m := range reg.order {
+		fmt.Fprintf(&b, "\nmessage %s {\n", m.name)
+		for _, f := range m.fields {
+			b.WriteString("  ")
+			if f.repeated {
+				b.WriteString("repeated ")
+			} else if f.optional {
+				b.WriteString("optional ")
+			}
+			typeName := protoTypes[f.kind]
+			if f.kind == kindMessage {
+				typeName = f.message.name
+			}
+			fmt.Fprintf(&b, "%s %s = %d;\n", typeName, f.name, f.number)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
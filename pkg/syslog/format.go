@@ -0,0 +1,196 @@
+package syslog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formatter builds syslog and journal messages from records
+type Formatter struct {
+	Facility Facility
+	Hostname string
+	AppName  string
+	ProcID   string
+	// SDID is the ID of the structured data element, e.g. gtp@32473
+	SDID       string
+	Severities SeverityMap
+	// Summary replaces the JSON record in the message by a summary line of key=value pairs
+	Summary bool
+}
+
+// nilValue stands for an empty header field of RFC 5424
+const nilValue = "-"
+
+// header returns a header field of RFC 5424: printable ASCII without spaces of limited length
+func header(s string, max int) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c > 32 && c < 127 {
+			b.WriteRune(c)
+		}
+		if b.Len() == max {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return nilValue
+	}
+	return b.String()
+}
+
+// msgID identifies the kind of a record: protocol and message type, or the record type
+func msgID(r Record) string {
+	switch {
+	case r.MessageType != nil:
+		return fmt.Sprintf("%s-%d", r.Protocol, *r.MessageType)
+	case r.RecordType != "":
+		return r.RecordType
+	}
+	return r.Protocol
+}
+
+// params returns the structured data parameters of a record that are present
+func params(r Record) [][2]string {
+	var p [][2]string
+	add := func(name, value string) {
+		if value != "" {
+			p = append(p, [2]string{name, value})
+		}
+	}
+	add("protocol", r.Protocol)
+	if r.MessageType != nil {
+		add("messageType", strconv.Itoa(*r.MessageType))
+	}
+	add("imsi", r.IMSI)
+	add("apn", r.APN)
+	add("cause", r.Cause)
+	if r.CauseCode >= 0 {
+		add("causeCode", strconv.Itoa(r.CauseCode))
+	}
+	add("srcIP", r.SrcIP)
+	add("dstIP", r.DstIP)
+	add("teid", r.TEID)
+	return p
+}
+
+// sdEscaper escapes the characters of structured data parameter values
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// Format returns an RFC 5424 message for a record and its JSON data
+func (f *Formatter) Format(r Record, data []byte) []byte {
+	severity := f.Severities.Severity(r)
+	ts := nilValue
+	if !r.Timestamp.IsZero() {
+		ts = r.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ", int(f.Facility)*8+int(severity), ts,
+		header(f.Hostname, 255), header(f.AppName, 48), header(f.ProcID, 128), header(msgID(r), 32))
+
+	if p := params(r); len(p) > 0 && f.SDID != "" {
+		b.WriteString("[" + header(f.SDID, 32))
+		for _, param := range p {
+			fmt.Fprintf(&b, ` %s="%s"`, param[0], sdEscaper.Replace(param[1]))
+		}
+		b.WriteString("]")
+	} else {
+		b.WriteString(nilValue)
+	}
+
+	b.WriteString(" ")
+	b.WriteString(f.message(r, data))
+	return b.Bytes()
+}
+
+// message returns the summary line or the JSON record on a single line
+func (f *Formatter) message(r Record, data []byte) string {
+	if f.Summary {
+		return SummaryLine(r)
+	}
+	var b bytes.Buffer
+	if err := json.Compact(&b, data); err != nil {
+		return string(bytes.TrimSpace(data))
+	}
+	return b.String()
+}
+
+// SummaryLine returns the fields of a record as key=value pairs for SIEMs, values with
+// spaces or quotes are quoted
+func SummaryLine(r Record) string {
+	pairs := []string{"msgid=" + msgID(r)}
+	if r.Error {
+		pairs = append(pairs, "error=true")
+	}
+	if r.SrcIP != "" {
+		pairs = append(pairs, "src="+endpoint(r.SrcIP, r.SrcPort))
+	}
+	if r.DstIP != "" {
+		pairs = append(pairs, "dst="+endpoint(r.DstIP, r.DstPort))
+	}
+	for _, p := range params(r) {
+		switch p[0] {
+		case "protocol", "messageType", "srcIP", "dstIP":
+			continue
+		}
+		pairs = append(pairs, p[0]+"="+summaryValue(p[1]))
+	}
+	if r.Rejected() {
+		pairs = append(pairs, "rejected=true")
+	}
+	return strings.Join(pairs, " ")
+}
+
+// endpoint formats an address and port, IPv6 addresses in brackets
+func endpoint(ip string, port int) string {
+	if port == 0 {
+		return ip
+	}
+	if strings.Contains(ip, ":") {
+		return fmt.Sprintf("[%s]:%d", ip, port)
+	}
+	return fmt.Sprintf("%s:%d", ip, port)
+}
+
+func summaryValue(v string) string {
+	if strings.ContainsAny(v, " \"=") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// FormatJournal returns a message of the native journald protocol. The fields of the structured
+// data are added as GTP_<NAME> fields.
+func (f *Formatter) FormatJournal(r Record, data []byte) []byte {
+	var b bytes.Buffer
+	field := func(name, value string) {
+		if !strings.Contains(value, "\n") {
+			b.WriteString(name + "=" + value + "\n")
+			return
+		}
+		// Values with newlines are written with their length
+		b.WriteString(name + "\n")
+		binary.Write(&b, binary.LittleEndian, uint64(len(value)))
+		b.WriteString(value + "\n")
+	}
+
+	field("MESSAGE", f.message(r, data))
+	field("PRIORITY", strconv.Itoa(int(f.Severities.Severity(r))))
+	field("SYSLOG_FACILITY", strconv.Itoa(int(f.Facility)))
+	if f.AppName != "" {
+		field("SYSLOG_IDENTIFIER", f.AppName)
+	}
+	field("GTP_MSGID", msgID(r))
+	for _, p := range params(r) {
+		field("GTP_"+strings.ToUpper(p[0]), p[1])
+	}
+	if !r.Timestamp.IsZero() {
+		field("GTP_TIMESTAMP", r.Timestamp.UTC().Format(time.RFC3339Nano))
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,299 @@
+package syslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/gtp1ie"
+	"github.com/vagabundor/gtp2json/pkg/gtp2ie"
+	"github.com/vagabundor/gtp2json/pkg/gtpprimeie"
+	"github.com/vagabundor/gtp2json/pkg/pfcpie"
+)
+
+// Severity is the syslog severity of RFC 5424, lower values are more severe
+type Severity int
+
+const (
+	Emergency Severity = iota
+	Alert
+	Critical
+	Error
+	Warning
+	Notice
+	Informational
+	Debug
+)
+
+var severityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return strconv.Itoa(int(s))
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity returns the severity of a keyword such as warning or a number from 0 to 7
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(name, n) {
+			return Severity(i), nil
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil && n >= 0 && n < len(severityNames) {
+		return Severity(n), nil
+	}
+	return 0, fmt.Errorf("unknown severity %q", name)
+}
+
+// Facility is the syslog facility of RFC 5424
+type Facility int
+
+var facilities = map[string]Facility{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11, "ntp": 12, "security": 13, "console": 14,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// ParseFacility returns the facility of a keyword such as local0
+func ParseFacility(name string) (Facility, error) {
+	if f, ok := facilities[strings.ToLower(name)]; ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown facility %q", name)
+}
+
+// SeverityMap assigns severities to records. Message types are given as <type> or as
+// <protocol>:<type>, the latter taking precedence.
+type SeverityMap struct {
+	// Default applies to message types without an entry
+	Default Severity
+	// Rejected applies to responses with a rejection cause unless the type is more severe
+	Rejected Severity
+	// Error applies to records of the error stream
+	Error Severity
+	types map[string]Severity
+}
+
+// ParseSeverityMap parses a comma separated list of <key>=<severity>, where the key is default,
+// rejected, error, a message type or a protocol and message type, e.g.
+// "default=info,rejected=warning,error=err,GTPv2:33=notice". Keys that are not given keep their
+// defaults info, warning and err.
+func ParseSeverityMap(spec string) (SeverityMap, error) {
+	m := SeverityMap{Default: Informational, Rejected: Warning, Error: Error, types: map[string]Severity{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return m, fmt.Errorf("invalid severity mapping %q, use <key>=<severity>", entry)
+		}
+		severity, err := ParseSeverity(strings.TrimSpace(value))
+		if err != nil {
+			return m, err
+		}
+		key = strings.TrimSpace(key)
+		switch key {
+		case "default":
+			m.Default = severity
+		case "rejected":
+			m.Rejected = severity
+		case "error":
+			m.Error = severity
+		default:
+			typ := key
+			if i := strings.LastIndex(key, ":"); i >= 0 {
+				typ = key[i+1:]
+			}
+			if _, err := strconv.ParseUint(typ, 10, 8); err != nil {
+				return m, fmt.Errorf("invalid message type in severity mapping %q", entry)
+			}
+			m.types[key] = severity
+		}
+	}
+	return m, nil
+}
+
+// Severity returns the severity of a record
+func (m SeverityMap) Severity(r Record) Severity {
+	if r.Error {
+		return m.Error
+	}
+	severity := m.Default
+	if r.MessageType != nil {
+		typ := strconv.Itoa(*r.MessageType)
+		if s, ok := m.types[r.Protocol+":"+typ]; ok {
+			severity = s
+		} else if s, ok := m.types[typ]; ok {
+			severity = s
+		}
+	}
+	if r.Rejected() && m.Rejected < severity {
+		severity = m.Rejected
+	}
+	return severity
+}
+
+// Record holds the fields of a JSON record that are carried in syslog messages
+type Record struct {
+	Timestamp   time.Time
+	Protocol    string
+	RecordType  string
+	MessageType *int
+	SrcIP       string
+	DstIP       string
+	SrcPort     int
+	DstPort     int
+	TEID        string
+	IMSI        string
+	APN         string
+	// Cause is the cause as written in the record, CauseCode its value or -1 when it is unknown
+	Cause     string
+	CauseCode int
+	// Error marks records of the error stream
+	Error bool
+}
+
+// jsonRecord is the part of a JSON record that is read
+type jsonRecord struct {
+	Timestamp   *time.Time      `json:"timestamp"`
+	FirstSeen   *time.Time      `json:"firstSeen"`
+	RecordType  string          `json:"recordType"`
+	Protocol    json.RawMessage `json:"protocol"`
+	MessageType *int            `json:"messageType"`
+	SrcIP       string          `json:"srcIP"`
+	DstIP       string          `json:"dstIP"`
+	SrcPort     int             `json:"srcPort"`
+	DstPort     int             `json:"dstPort"`
+	TEID        json.Number     `json:"teid"`
+	IEs         []struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	} `json:"ies"`
+}
+
+// ParseRecord reads a JSON record of the given protocol. Records of the error stream are marked
+// with isError. Only the first IMSI, APN and Cause IE of a message are kept.
+func ParseRecord(protocol string, data []byte, isError bool) (Record, error) {
+	var j jsonRecord
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&j); err != nil {
+		return Record{}, fmt.Errorf("failed to parse record: %w", err)
+	}
+
+	r := Record{
+		Protocol:    protocol,
+		RecordType:  j.RecordType,
+		MessageType: j.MessageType,
+		SrcIP:       j.SrcIP,
+		DstIP:       j.DstIP,
+		SrcPort:     j.SrcPort,
+		DstPort:     j.DstPort,
+		TEID:        j.TEID.String(),
+		CauseCode:   -1,
+		Error:       isError,
+	}
+	switch {
+	case j.Timestamp != nil:
+		r.Timestamp = *j.Timestamp
+	case j.FirstSeen != nil:
+		r.Timestamp = *j.FirstSeen
+	}
+	// Decode failures name the protocol in the record, flow records use the field for the IP protocol
+	var name string
+	if r.Protocol == "" && json.Unmarshal(j.Protocol, &name) == nil {
+		r.Protocol = name
+	}
+
+	for _, ie := range j.IEs {
+		switch ie.Type {
+		case "IMSI":
+			if r.IMSI == "" {
+				r.IMSI = text(ie.Value)
+			}
+		case "APN":
+			if r.APN == "" {
+				r.APN = text(ie.Value)
+			}
+		case "Cause":
+			if r.Cause == "" {
+				var cause struct {
+					CauseValue json.RawMessage `json:"CauseValue"`
+				}
+				value := ie.Value
+				if json.Unmarshal(ie.Value, &cause) == nil && cause.CauseValue != nil {
+					value = cause.CauseValue
+				}
+				r.Cause = text(value)
+				r.CauseCode = causeCode(r.Protocol, r.Cause)
+			}
+		}
+	}
+	return r, nil
+}
+
+// text returns a scalar JSON value as text
+func text(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// causeDescriptions are the cause tables of the protocols, used to find the value of causes
+// written as text. GTP' uses the GTPv1 causes besides its own.
+var causeDescriptions = map[string][]map[byte]string{
+	"GTPv2":   {gtp2ie.CauseDescriptions},
+	"GTPv1-C": {gtp1ie.CauseDescriptions},
+	"PFCP":    {pfcpie.CauseDescriptions},
+	"GTP'":    {gtpprimeie.CauseDescriptions, gtp1ie.CauseDescriptions},
+}
+
+// causeCode returns the value of a cause in numeric, mixed ("Description (n)") or text format
+func causeCode(protocol, cause string) int {
+	if n, err := strconv.ParseUint(cause, 10, 8); err == nil {
+		return int(n)
+	}
+	if i := strings.LastIndex(cause, " ("); i >= 0 && strings.HasSuffix(cause, ")") {
+		if n, err := strconv.ParseUint(cause[i+2:len(cause)-1], 10, 8); err == nil {
+			return int(n)
+		}
+	}
+	for _, table := range causeDescriptions[protocol] {
+		for code, description := range table {
+			if description == cause {
+				return int(code)
+			}
+		}
+	}
+	return -1
+}
+
+// Rejected tells whether the record carries a rejection cause: 64 and above for GTPv2 and PFCP,
+// 192 and above for GTPv1-C, and for GTP' also the CDR decoding error 177
+func (r Record) Rejected() bool {
+	if r.CauseCode < 0 {
+		return false
+	}
+	switch r.Protocol {
+	case "GTPv2", "PFCP":
+		return r.CauseCode >= 64
+	case "GTPv1-C":
+		return r.CauseCode >= 192
+	case "GTP'":
+		return r.CauseCode >= 192 || r.CauseCode == 177
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/batch"
+)
+
+const createSessionResponse = `{"timestamp":"2024-05-01T12:00:00.123456+02:00","srcIP":"10.0.0.2","srcPort":2123,
+	"dstIP":"10.0.0.1","dstPort":2123,"messageType":33,"teid":1,"ies":[
+	{"type":"Cause","value":{"CauseValue":"Context Not Found (64)","PCE":false,"BCE":false,"CS":0}},
+	{"type":"IMSI","value":"001010123456789"},
+	{"type":"APN","value":"internet \"test\""}]}`
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestParseRecord(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		record   string
+		isError  bool
+		want     Record
+	}{
+		{
+			name:     "GTPv2 Mixed Cause",
+			protocol: "GTPv2",
+			record:   createSessionResponse,
+			want: Record{
+				Timestamp: time.Date(2024, 5, 1, 10, 0, 0, 123456000, time.UTC), Protocol: "GTPv2", MessageType: intPtr(33),
+				SrcIP: "10.0.0.2", DstIP: "10.0.0.1", SrcPort: 2123, DstPort: 2123, TEID: "1",
+				IMSI: "001010123456789", APN: `internet "test"`, Cause: "Context Not Found (64)", CauseCode: 64,
+			},
+		},
+		{
+			name:     "PFCP Text Cause",
+			protocol: "PFCP",
+			record:   `{"messageType":51,"ies":[{"type":"Cause","value":"Session context not found"}]}`,
+			want:     Record{Protocol: "PFCP", MessageType: intPtr(51), Cause: "Session context not found", CauseCode: 65},
+		},
+		{
+			name:     "GTPv1 Numeric Cause",
+			protocol: "GTPv1-C",
+			record:   `{"messageType":17,"ies":[{"type":"Cause","value":{"CauseValue":128}},{"type":"Cause","value":{"CauseValue":192}}]}`,
+			want:     Record{Protocol: "GTPv1-C", MessageType: intPtr(17), Cause: "128", CauseCode: 128},
+		},
+		{
+			name:    "Decode Failure",
+			record:  `{"timestamp":"2024-05-01T00:00:00Z","recordType":"decodeError","protocol":"PFCP","decodeErrors":[]}`,
+			isError: true,
+			want:    Record{Timestamp: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Protocol: "PFCP", RecordType: "decodeError", CauseCode: -1, Error: true},
+		},
+		{
+			name:     "Flow",
+			protocol: "GTP-U",
+			record:   `{"recordType":"flow","firstSeen":"2024-05-01T00:00:00Z","protocol":6,"teid":7}`,
+			want:     Record{Timestamp: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Protocol: "GTP-U", RecordType: "flow", TEID: "7", CauseCode: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRecord(tt.protocol, []byte(tt.record), tt.isError)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got.Timestamp = got.Timestamp.UTC()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRecord() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	m, err := ParseSeverityMap("default=info, rejected=warning, error=3, 32=debug, GTPv2:33=notice, PFCP:51=crit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		record Record
+		want   Severity
+	}{
+		{"Default", Record{Protocol: "GTPv2", MessageType: intPtr(1), CauseCode: -1}, Informational},
+		{"Message Type", Record{Protocol: "GTPv1-C", MessageType: intPtr(32), CauseCode: -1}, Debug},
+		{"Protocol and Message Type", Record{Protocol: "GTPv2", MessageType: intPtr(33), CauseCode: 16}, Notice},
+		{"Rejected", Record{Protocol: "GTPv2", MessageType: intPtr(33), CauseCode: 64}, Warning},
+		{"Rejected Below Type", Record{Protocol: "PFCP", MessageType: intPtr(51), CauseCode: 65}, Critical},
+		{"Accepted GTPv1", Record{Protocol: "GTPv1-C", MessageType: intPtr(17), CauseCode: 128}, Informational},
+		{"Rejected GTPv1", Record{Protocol: "GTPv1-C", MessageType: intPtr(17), CauseCode: 192}, Warning},
+		{"Error Stream", Record{Protocol: "GTPv2", MessageType: intPtr(32), CauseCode: -1, Error: true}, Error},
+	}
+	for _, tt := range tests {
+		if got := m.Severity(tt.record); got != tt.want {
+			t.Errorf("%s: Severity() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"default", "default=loud", "GTPv2:create=info", "300=info"} {
+		if _, err := ParseSeverityMap(spec); err == nil {
+			t.Errorf("ParseSeverityMap(%q) did not fail", spec)
+		}
+	}
+}
+
+func testFormatter(summary bool) *Formatter {
+	m, _ := ParseSeverityMap("")
+	return &Formatter{Facility: 16, Hostname: "probe 1", AppName: "gtp2json", ProcID: "42", SDID: "gtp@32473", Severities: m, Summary: summary}
+}
+
+func TestFormat(t *testing.T) {
+	r, err := ParseRecord("GTPv2", []byte(createSessionResponse), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd := `[gtp@32473 protocol="GTPv2" messageType="33" imsi="001010123456789" apn="internet \"test\"" ` +
+		`cause="Context Not Found (64)" causeCode="64" srcIP="10.0.0.2" dstIP="10.0.0.1" teid="1"]`
+	tests := []struct {
+		name    string
+		summary bool
+		wantMsg string
+	}{
+		{
+			name:    "JSON",
+			wantMsg: `{"timestamp":"2024-05-01T12:00:00.123456+02:00","srcIP":"10.0.0.2","srcPort":2123,`,
+		},
+		{
+			name:    "Summary",
+			summary: true,
+			wantMsg: `msgid=GTPv2-33 src=10.0.0.2:2123 dst=10.0.0.1:2123 imsi=001010123456789 apn="internet \"test\"" ` +
+				`cause="Context Not Found (64)" causeCode=64 teid=1 rejected=true`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(testFormatter(tt.summary).Format(r, []byte(createSessionResponse)))
+			// local0.warning
+			want := "<132>1 2024-05-01T10:00:00.123456Z probe1 gtp2json 42 GTPv2-33 " + sd + " " + tt.wantMsg
+			if !strings.HasPrefix(got, want) || strings.Contains(got, "\n") {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+
+	empty := string(testFormatter(true).Format(Record{Protocol: "GTP-U", RecordType: "flow", CauseCode: -1, SrcIP: "2001:db8::1", SrcPort: 2152}, nil))
+	if want := `<134>1 - probe1 gtp2json 42 flow [gtp@32473 protocol="GTP-U" srcIP="2001:db8::1"] msgid=flow src=[2001:db8::1]:2152`; empty != want {
+		t.Errorf("Format() = %s, want %s", empty, want)
+	}
+}
+
+// journalFields decodes a message of the native journald protocol
+func journalFields(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	r := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			return fields
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if name, value, ok := strings.Cut(line, "="); ok {
+			fields[name] = value
+			continue
+		}
+		var size uint64
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			t.Fatal(err)
+		}
+		value := make([]byte, size+1)
+		if _, err := io.ReadFull(r, value); err != nil {
+			t.Fatal(err)
+		}
+		fields[line] = string(value[:size])
+	}
+}
+
+func TestFormatJournal(t *testing.T) {
+	r, err := ParseRecord("GTPv2", []byte(createSessionResponse), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.APN = "multi\nline"
+	fields := journalFields(t, testFormatter(true).FormatJournal(r, nil))
+	want := map[string]string{
+		"PRIORITY":          "4",
+		"SYSLOG_FACILITY":   "16",
+		"SYSLOG_IDENTIFIER": "gtp2json",
+		"GTP_MSGID":         "GTPv2-33",
+		"GTP_IMSI":          "001010123456789",
+		"GTP_APN":           "multi\nline",
+		"GTP_CAUSECODE":     "64",
+		"GTP_TIMESTAMP":     "2024-05-01T10:00:00.123456Z",
+	}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("%s = %q, want %q", name, fields[name], value)
+		}
+	}
+	if !strings.HasPrefix(fields["MESSAGE"], "msgid=GTPv2-33 ") {
+		t.Errorf("MESSAGE = %q", fields["MESSAGE"])
+	}
+}
+
+func TestTransport(t *testing.T) {
+	messages := [][]byte{[]byte("<134>1 - - - - - - first"), []byte("<134>1 - - - - - - second\nline")}
+	backoff := batch.Backoff{Initial: time.Millisecond}
+
+	t.Run("TCP Octet Counting", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		received := make(chan []string, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			var got []string
+			for len(got) < len(messages) {
+				length, err := r.ReadString(' ')
+				if err != nil {
+					break
+				}
+				n, _ := strconv.Atoi(strings.TrimSpace(length))
+				msg := make([]byte, n)
+				if _, err := io.ReadFull(r, msg); err != nil {
+					break
+				}
+				got = append(got, string(msg))
+			}
+			received <- got
+		}()
+
+		transport, err := NewTransport("tcp", ln.Addr().String(), nil, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer transport.Close()
+		if n, err := transport.Send(messages, 3, backoff); err != nil || n != len(messages) {
+			t.Fatalf("Send() = %d, %v", n, err)
+		}
+		got := <-received
+		if len(got) != 2 || got[0] != string(messages[0]) || got[1] != string(messages[1]) {
+			t.Errorf("received %q", got)
+		}
+	})
+
+	t.Run("UDP", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		transport, err := NewTransport("udp", conn.LocalAddr().String(), nil, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer transport.Close()
+		if n, err := transport.Send(messages, 3, backoff); err != nil || n != len(messages) {
+			t.Fatalf("Send() = %d, %v", n, err)
+		}
+		buf := make([]byte, 1024)
+		for _, want := range messages {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(buf[:n]) != string(want) {
+				t.Errorf("datagram %q, want %q", buf[:n], want)
+			}
+		}
+	})
+
+	t.Run("Journald", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "journal.sock")
+		conn, err := net.ListenPacket("unixgram", path)
+		if err != nil {
+			t.Skipf("unix datagram sockets unavailable: %v", err)
+		}
+		defer conn.Close()
+		transport, err := NewTransport("journald", path, nil, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer transport.Close()
+		if _, err := transport.Send(messages[:1], 3, backoff); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil || string(buf[:n]) != string(messages[0]) {
+			t.Errorf("datagram %q, %v", buf[:n], err)
+		}
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := ln.Addr().String()
+		ln.Close()
+		transport, err := NewTransport("tcp", addr, nil, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := transport.Send(messages, 2, backoff); err == nil || n != 0 {
+			t.Errorf("Send() to a closed port = %d, %v", n, err)
+		}
+	})
+
+	if _, err := NewTransport("tcp", "", nil, time.Second); err == nil {
+		t.Error("NewTransport() without an address did not fail")
+	}
+	if _, err := NewTransport("relp", "localhost:514", nil, time.Second); err == nil {
+		t.Error("NewTransport() with an unknown network did not fail")
+	}
+}
@@ -0,0 +1,111 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/batch"
+)
+
+// JournalSocket is the socket of the native journald protocol
+const JournalSocket = "/run/systemd/journal/socket"
+
+// maxDatagram is the largest UDP payload, longer messages are truncated as RFC 5426 permits
+const maxDatagram = 65507
+
+// Transport sends messages to a syslog server over udp, tcp or tls, or to journald over the
+// journald network. Messages over TCP and TLS are framed by octet counting (RFC 6587, RFC 5425).
+type Transport struct {
+	network string
+	addr    string
+	tls     *tls.Config
+	timeout time.Duration
+	conn    net.Conn
+}
+
+// NewTransport returns a transport that connects on the first message. The address of the
+// journald network defaults to JournalSocket.
+func NewTransport(network, addr string, tlsConfig *tls.Config, timeout time.Duration) (*Transport, error) {
+	switch network {
+	case "udp", "tcp", "tls":
+		if addr == "" {
+			return nil, fmt.Errorf("no syslog address for %s", network)
+		}
+	case "journald":
+		if addr == "" {
+			addr = JournalSocket
+		}
+	default:
+		return nil, fmt.Errorf("unknown syslog network %q, use udp, tcp, tls or journald", network)
+	}
+	return &Transport{network: network, addr: addr, tls: tlsConfig, timeout: timeout}, nil
+}
+
+// Network returns the network of the transport
+func (t *Transport) Network() string {
+	return t.network
+}
+
+func (t *Transport) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: t.timeout}
+	switch t.network {
+	case "tls":
+		return tls.DialWithDialer(dialer, "tcp", t.addr, t.tls)
+	case "journald":
+		return dialer.Dial("unixgram", t.addr)
+	}
+	return dialer.Dial(t.network, t.addr)
+}
+
+// frame returns a message as it is written to the connection
+func (t *Transport) frame(msg []byte) []byte {
+	switch t.network {
+	case "tcp", "tls":
+		return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	case "udp":
+		if len(msg) > maxDatagram {
+			return msg[:maxDatagram]
+		}
+	}
+	return msg
+}
+
+// Send writes messages in order and returns the number written. After a failed write the
+// connection is opened again and the remaining messages are sent, retrying with back-off.
+func (t *Transport) Send(messages [][]byte, maxRetries int, backoff batch.Backoff) (int, error) {
+	next := 0
+	err := batch.Retry(maxRetries, backoff, func() error {
+		if t.conn == nil {
+			conn, err := t.dial()
+			if err != nil {
+				return err
+			}
+			t.conn = conn
+		}
+		for ; next < len(messages); next++ {
+			if t.timeout > 0 {
+				t.conn.SetWriteDeadline(time.Now().Add(t.timeout))
+			}
+			if _, err := t.conn.Write(t.frame(messages[next])); err != nil {
+				t.conn.Close()
+				t.conn = nil
+				return err
+			}
+		}
+		return nil
+	})
+	return next, err
+}
+
+// Close closes the connection
+func (t *Transport) Close() error {
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
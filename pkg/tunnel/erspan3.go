@@ -0,0 +1,126 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// EthernetTypeERSPANIII is the GRE protocol type of ERSPAN Type III
+const EthernetTypeERSPANIII layers.EthernetType = 0x22eb
+
+// LayerTypeERSPANIII registers ERSPAN Type III layer type for use with GoPacket
+var LayerTypeERSPANIII = gopacket.RegisterLayerType(1015,
+	gopacket.LayerTypeMetadata{Name: "ERSPAN Type III", Decoder: gopacket.DecodeFunc(decodeERSPANIII)})
+
+const erspan3MinimumSizeInBytes int = 12
+
+// ERSPANIII is the header of ERSPAN Type III mirrored frames carried in GRE.
+// Defined in draft-foschiano-erspan
+type ERSPANIII struct {
+	Version        uint8
+	VLANIdentifier uint16
+	CoS            uint8
+	BSO            uint8
+	Truncated      bool
+	SessionID      uint16
+	Timestamp      uint32
+	SGT            uint16
+	FrameType      uint8
+	HardwareID     uint8
+	Direction      bool
+	Granularity    uint8
+	// PlatformSubheader is present when the O flag is set
+	PlatformSubheader []byte
+
+	Contents []byte
+	Payload  []byte
+}
+
+func init() {
+	layers.EthernetTypeMetadata[EthernetTypeERSPANIII] = layers.EnumMetadata{
+		DecodeWith: gopacket.DecodeFunc(decodeERSPANIII),
+		Name:       "ERSPAN Type III",
+		LayerType:  LayerTypeERSPANIII,
+	}
+}
+
+// DecodeFromBytes analyses a byte slice and attempts to decode it as an ERSPAN Type III header
+func (e *ERSPANIII) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	hLen := erspan3MinimumSizeInBytes
+	if len(data) < hLen {
+		return fmt.Errorf("ERSPAN Type III header too small: %d bytes", len(data))
+	}
+	e.Version = data[0] >> 4
+	e.VLANIdentifier = binary.BigEndian.Uint16(data[0:2]) & 0x0fff
+	e.CoS = data[2] >> 5
+	e.BSO = (data[2] >> 3) & 0x03
+	e.Truncated = data[2]&0x04 != 0
+	e.SessionID = binary.BigEndian.Uint16(data[2:4]) & 0x03ff
+	e.Timestamp = binary.BigEndian.Uint32(data[4:8])
+	e.SGT = binary.BigEndian.Uint16(data[8:10])
+	e.FrameType = (data[10] >> 2) & 0x1f
+	e.HardwareID = uint8(binary.BigEndian.Uint16(data[10:12])>>4) & 0x3f
+	e.Direction = data[11]&0x08 != 0
+	e.Granularity = (data[11] >> 1) & 0x03
+
+	if data[11]&0x01 != 0 {
+		if len(data) < hLen+8 {
+			return fmt.Errorf("ERSPAN Type III platform subheader too small: %d bytes", len(data))
+		}
+		e.PlatformSubheader = data[hLen : hLen+8]
+		hLen += 8
+	}
+
+	e.Contents = data[:hLen]
+	e.Payload = data[hLen:]
+	return nil
+}
+
+// decodeERSPANIII is a utility function to facilitate the decoding of ERSPAN Type III within GoPacket's framework
+func decodeERSPANIII(data []byte, p gopacket.PacketBuilder) error {
+	e := &ERSPANIII{}
+
+	if err := e.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+
+	p.AddLayer(e)
+	return p.NextDecoder(e.NextLayerType())
+}
+
+// LayerType returns LayerTypeERSPANIII
+func (e *ERSPANIII) LayerType() gopacket.LayerType {
+	return LayerTypeERSPANIII
+}
+
+// LayerContents returns the contents of the ERSPAN Type III layer.
+func (e *ERSPANIII) LayerContents() []byte {
+	return e.Contents
+}
+
+// LayerPayload returns the payload of the ERSPAN Type III layer.
+func (e *ERSPANIII) LayerPayload() []byte {
+	return e.Payload
+}
+
+// CanDecode returns a set of layers that ERSPAN Type III objects can decode
+func (e *ERSPANIII) CanDecode() gopacket.LayerClass {
+	return LayerTypeERSPANIII
+}
+
+// NextLayerType returns Ethernet for mirrored frames and IPv4 or IPv6 for mirrored IP packets
+func (e *ERSPANIII) NextLayerType() gopacket.LayerType {
+	switch e.FrameType {
+	case 0:
+		return layers.LayerTypeEthernet
+	case 2:
+		if len(e.Payload) > 0 && e.Payload[0]>>4 == 6 {
+			return layers.LayerTypeIPv6
+		}
+		return layers.LayerTypeIPv4
+	}
+	return gopacket.LayerTypePayload
+}
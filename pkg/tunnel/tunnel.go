@@ -0,0 +1,159 @@
+package tunnel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Metadata describes the outer encapsulation a packet was received in. It tells which
+// tap point or packet broker session mirrored the packet.
+type Metadata struct {
+	VLANIDs         []uint16 `json:"vlanIds,omitempty"`
+	MPLSLabels      []uint32 `json:"mplsLabels,omitempty"`
+	GREKey          *uint32  `json:"greKey,omitempty"`
+	ERSPANSessionID *uint16  `json:"erspanSessionId,omitempty"`
+	VNI             *uint32  `json:"vni,omitempty"`
+}
+
+// Packet is a decapsulated packet carrying the metadata of the removed outer layers
+type Packet struct {
+	gopacket.Packet
+	Tunnel *Metadata
+}
+
+// WithMetadata attaches tunnel metadata to a packet, a nil metadata leaves the packet as it is
+func WithMetadata(packet gopacket.Packet, metadata *Metadata) gopacket.Packet {
+	if metadata == nil {
+		return packet
+	}
+	return &Packet{Packet: packet, Tunnel: metadata}
+}
+
+// MetadataOf returns the tunnel metadata attached to a packet or nil
+func MetadataOf(packet gopacket.Packet) *Metadata {
+	if p, ok := packet.(*Packet); ok {
+		return p.Tunnel
+	}
+	return nil
+}
+
+// Decapsulate strips GRE, ERSPAN, VXLAN and MPLS outer layers and returns the inner packet
+// together with the metadata of the outer layers. VLAN tags are only recorded, gopacket decodes
+// through them. Layers after the first transport header are not inspected, so tunnels inside
+// GTP-U user traffic are left alone. Packets without encapsulation are returned unchanged.
+func Decapsulate(packet gopacket.Packet) (gopacket.Packet, *Metadata) {
+	var metadata Metadata
+	found := false
+	cut, cutOffset, offset := -1, 0, 0
+
+	packetLayers := packet.Layers()
+loop:
+	for i, layer := range packetLayers {
+		offset += len(layer.LayerContents())
+
+		switch l := layer.(type) {
+		case *layers.Dot1Q:
+			metadata.VLANIDs = append(metadata.VLANIDs, l.VLANIdentifier)
+			found = true
+		case *layers.MPLS:
+			metadata.MPLSLabels = append(metadata.MPLSLabels, l.Label)
+			found = true
+			if l.StackBottom {
+				cut, cutOffset = i, offset
+			}
+		case *layers.GRE:
+			if l.KeyPresent {
+				key := l.Key
+				metadata.GREKey = &key
+			}
+			found = true
+			cut, cutOffset = i, offset
+		case *layers.ERSPANII:
+			sessionID := l.SessionID
+			metadata.ERSPANSessionID = &sessionID
+			found = true
+			cut, cutOffset = i, offset
+		case *ERSPANIII:
+			sessionID := l.SessionID
+			metadata.ERSPANSessionID = &sessionID
+			found = true
+			cut, cutOffset = i, offset
+		case *layers.VXLAN:
+			vni := l.VNI
+			metadata.VNI = &vni
+			found = true
+			cut, cutOffset = i, offset
+		case *layers.UDP, *layers.TCP, *layers.SCTP:
+			// Transport layers end the outer encapsulation, except for UDP carrying VXLAN
+			if i+1 < len(packetLayers) && packetLayers[i+1].LayerType() == layers.LayerTypeVXLAN {
+				continue
+			}
+			break loop
+		}
+	}
+
+	if !found {
+		return packet, nil
+	}
+	if cut < 0 || cut+1 >= len(packetLayers) {
+		return packet, &metadata
+	}
+
+	// The inner packet is decoded again from the first layer after the last tunnel header
+	next := packetLayers[cut+1].LayerType()
+	if next == gopacket.LayerTypePayload || next == gopacket.LayerTypeDecodeFailure {
+		return packet, &metadata
+	}
+	data := packet.Data()[cutOffset:]
+	inner := gopacket.NewPacket(data, next, gopacket.Default)
+	ci := packet.Metadata().CaptureInfo
+	ci.CaptureLength = len(data)
+	ci.Length = len(data)
+	inner.Metadata().CaptureInfo = ci
+
+	return inner, &metadata
+}
+
+// Encapsulations lists the encapsulation names accepted by BPFFilter
+var Encapsulations = []string{"vlan", "mpls", "gre", "erspan", "vxlan"}
+
+// BPFFilter extends a capture filter so that it also matches packets in the given encapsulations.
+// VLAN and MPLS shift the header offsets and wrap the filter, up to two tags or labels deep.
+// GRE, ERSPAN and VXLAN headers have no fixed offsets, the whole tunnel traffic is captured
+// and filtered after decapsulation.
+func BPFFilter(filter string, encapsulations []string) (string, error) {
+	enabled := make(map[string]bool)
+	for _, name := range encapsulations {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, encapsulation := range Encapsulations {
+			if name == encapsulation {
+				known = true
+			}
+		}
+		if !known {
+			return "", fmt.Errorf("unknown encapsulation '%s', use %s", name, strings.Join(Encapsulations, ", "))
+		}
+		enabled[name] = true
+	}
+
+	if enabled["gre"] || enabled["erspan"] {
+		filter += " or ip proto 47 or ip6 proto 47"
+	}
+	if enabled["vxlan"] {
+		filter += " or udp port 4789"
+	}
+	if enabled["mpls"] {
+		filter = fmt.Sprintf("%s or (mpls and (%s or (mpls and (%s))))", filter, filter, filter)
+	}
+	if enabled["vlan"] {
+		filter = fmt.Sprintf("%s or (vlan and (%s or (vlan and (%s))))", filter, filter, filter)
+	}
+	return filter, nil
+}
@@ -0,0 +1,220 @@
+package tunnel
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/vagabundor/gtp2json/pkg/gtp2"
+)
+
+// testGTPv2Message is an Echo Request carrying a Recovery IE
+var testGTPv2Message = []byte{0x40, 0x01, 0x00, 0x09, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x05}
+
+var (
+	testSrcMAC = net.HardwareAddr{0x84, 0xb5, 0x9c, 0x67, 0x9d, 0x29}
+	testDstMAC = net.HardwareAddr{0x84, 0xb5, 0xd1, 0x58, 0x1f, 0xa3}
+)
+
+func ipv4(protocol layers.IPProtocol, src, dst byte) *layers.IPv4 {
+	return &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: protocol,
+		SrcIP:    net.IP{10, 0, 0, src},
+		DstIP:    net.IP{10, 0, 0, dst},
+	}
+}
+
+// innerGTPv2 returns the IPv4, UDP and GTPv2 layers of the mirrored packet
+func innerGTPv2() []gopacket.SerializableLayer {
+	udp := &layers.UDP{SrcPort: 2123, DstPort: 2123}
+	ip := ipv4(layers.IPProtocolUDP, 1, 2)
+	udp.SetNetworkLayerForChecksum(ip)
+	return []gopacket.SerializableLayer{ip, udp, gopacket.Payload(testGTPv2Message)}
+}
+
+func serialize(t *testing.T, l ...gopacket.SerializableLayer) []byte {
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, l...); err != nil {
+		t.Fatalf("failed to serialize packet: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func eth(ethType layers.EthernetType) *layers.Ethernet {
+	return &layers.Ethernet{SrcMAC: testSrcMAC, DstMAC: testDstMAC, EthernetType: ethType}
+}
+
+func uint16Ptr(v uint16) *uint16 { return &v }
+func uint32Ptr(v uint32) *uint32 { return &v }
+
+func TestDecapsulate(t *testing.T) {
+	innerFrame := serialize(t, append([]gopacket.SerializableLayer{eth(layers.EthernetTypeIPv4)}, innerGTPv2()...)...)
+
+	outerIP := ipv4(layers.IPProtocolUDP, 9, 10)
+	outerUDP := &layers.UDP{SrcPort: 50000, DstPort: 4789}
+	outerUDP.SetNetworkLayerForChecksum(outerIP)
+
+	// ERSPAN Type III header with session 42 and frame type Ethernet
+	erspan3 := []byte{0x20, 0x64, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     *Metadata
+		stripped bool
+	}{
+		{
+			name: "No Encapsulation",
+			data: innerFrame,
+		},
+		{
+			name: "QinQ",
+			data: serialize(t, append([]gopacket.SerializableLayer{
+				eth(layers.EthernetTypeQinQ),
+				&layers.Dot1Q{VLANIdentifier: 100, Type: layers.EthernetTypeDot1Q},
+				&layers.Dot1Q{VLANIdentifier: 200, Type: layers.EthernetTypeIPv4},
+			}, innerGTPv2()...)...),
+			want: &Metadata{VLANIDs: []uint16{100, 200}},
+		},
+		{
+			name: "VXLAN",
+			data: serialize(t,
+				eth(layers.EthernetTypeIPv4),
+				outerIP,
+				outerUDP,
+				&layers.VXLAN{ValidIDFlag: true, VNI: 5001},
+				gopacket.Payload(innerFrame)),
+			want:     &Metadata{VNI: uint32Ptr(5001)},
+			stripped: true,
+		},
+		{
+			name: "ERSPAN Type II",
+			data: serialize(t,
+				eth(layers.EthernetTypeIPv4),
+				ipv4(layers.IPProtocolGRE, 9, 10),
+				&layers.GRE{SeqPresent: true, Seq: 1, Protocol: layers.EthernetTypeERSPAN},
+				&layers.ERSPANII{Version: 1, SessionID: 7},
+				gopacket.Payload(innerFrame)),
+			want:     &Metadata{ERSPANSessionID: uint16Ptr(7)},
+			stripped: true,
+		},
+		{
+			name: "ERSPAN Type III",
+			data: serialize(t,
+				eth(layers.EthernetTypeIPv4),
+				ipv4(layers.IPProtocolGRE, 9, 10),
+				&layers.GRE{SeqPresent: true, Seq: 1, Protocol: EthernetTypeERSPANIII},
+				gopacket.Payload(append(erspan3, innerFrame...))),
+			want:     &Metadata{ERSPANSessionID: uint16Ptr(42)},
+			stripped: true,
+		},
+		{
+			name: "GRE With Key",
+			data: serialize(t, append([]gopacket.SerializableLayer{
+				eth(layers.EthernetTypeIPv4),
+				ipv4(layers.IPProtocolGRE, 9, 10),
+				&layers.GRE{KeyPresent: true, Key: 77, Protocol: layers.EthernetTypeIPv4},
+			}, innerGTPv2()...)...),
+			want:     &Metadata{GREKey: uint32Ptr(77)},
+			stripped: true,
+		},
+		{
+			name: "MPLS Label Stack",
+			data: serialize(t, append([]gopacket.SerializableLayer{
+				eth(layers.EthernetTypeMPLSUnicast),
+				&layers.MPLS{Label: 1000, TTL: 64},
+				&layers.MPLS{Label: 2000, StackBottom: true, TTL: 64},
+			}, innerGTPv2()...)...),
+			want:     &Metadata{MPLSLabels: []uint32{1000, 2000}},
+			stripped: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packet := gopacket.NewPacket(tt.data, layers.LayerTypeEthernet, gopacket.Default)
+			inner, got := Decapsulate(packet)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decapsulate() metadata = %#v, want %#v", got, tt.want)
+			}
+			if (inner != packet) != tt.stripped {
+				t.Errorf("Decapsulate() stripped = %v, want %v", inner != packet, tt.stripped)
+			}
+			if inner.Layer(gtp2.LayerTypeGTPv2) == nil {
+				t.Fatal("GTPv2 layer not decoded in inner packet")
+			}
+			if ip := inner.NetworkLayer().(*layers.IPv4); !ip.SrcIP.Equal(net.IP{10, 0, 0, 1}) {
+				t.Errorf("first network layer is %v, want the inner IPv4 header", ip.SrcIP)
+			}
+			if tt.stripped && inner.Metadata().Length != len(inner.Data()) {
+				t.Errorf("capture length %d does not match packet size %d", inner.Metadata().Length, len(inner.Data()))
+			}
+		})
+	}
+}
+
+func TestMetadataOf(t *testing.T) {
+	packet := gopacket.NewPacket(testGTPv2Message, gtp2.LayerTypeGTPv2, gopacket.Default)
+	if WithMetadata(packet, nil) != packet || MetadataOf(packet) != nil {
+		t.Error("packet without metadata was wrapped")
+	}
+
+	metadata := &Metadata{VNI: uint32Ptr(1)}
+	wrapped := WithMetadata(packet, metadata)
+	if MetadataOf(wrapped) != metadata || wrapped.Layer(gtp2.LayerTypeGTPv2) == nil {
+		t.Error("metadata not attached to packet")
+	}
+}
+
+func TestBPFFilter(t *testing.T) {
+	tests := []struct {
+		name           string
+		encapsulations []string
+		want           string
+		wantErr        bool
+	}{
+		{
+			name: "None",
+			want: "udp port 2123",
+		},
+		{
+			name:           "GRE And ERSPAN",
+			encapsulations: []string{"gre", "erspan"},
+			want:           "udp port 2123 or ip proto 47 or ip6 proto 47",
+		},
+		{
+			name:           "VXLAN In VLAN",
+			encapsulations: []string{"VXLAN", " vlan"},
+			want:           "udp port 2123 or udp port 4789 or (vlan and (udp port 2123 or udp port 4789 or (vlan and (udp port 2123 or udp port 4789))))",
+		},
+		{
+			name:           "MPLS",
+			encapsulations: []string{"mpls"},
+			want:           "udp port 2123 or (mpls and (udp port 2123 or (mpls and (udp port 2123))))",
+		},
+		{
+			name:           "Unknown",
+			encapsulations: []string{"geneve"},
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BPFFilter("udp port 2123", tt.encapsulations)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BPFFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("BPFFilter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/batch"
+)
+
+// StreamHeader is set to "errors" on the requests of the error stream, so that they can be told apart
+// from records posted to the same URL
+const StreamHeader = "X-GTP2JSON-Stream"
+
+// Format is the body of the requests
+type Format int
+
+const (
+	// NDJSON sends one record per line
+	NDJSON Format = iota
+	// Array sends the records as a JSON array
+	Array
+)
+
+// ParseFormat returns the format of a name: ndjson or array
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "ndjson":
+		return NDJSON, nil
+	case "array", "json-array":
+		return Array, nil
+	}
+	return NDJSON, fmt.Errorf("unknown webhook format %q, use ndjson or array", name)
+}
+
+// Config configures the requests of a Client
+type Config struct {
+	Format Format
+	// Headers are added to every request, they may override the defaults
+	Headers http.Header
+	// Token is sent as "Authorization: Bearer"
+	Token string
+	// Gzip compresses the bodies
+	Gzip    bool
+	TLS     *tls.Config
+	Timeout time.Duration
+}
+
+// Client posts batches of JSON records to HTTP endpoints
+type Client struct {
+	config Config
+	client *http.Client
+}
+
+// NewClient returns a client sending requests with the given configuration
+func NewClient(config Config) (*Client, error) {
+	if config.Format != NDJSON && config.Format != Array {
+		return nil, fmt.Errorf("unknown webhook format %d", config.Format)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config.TLS
+	return &Client{config: config, client: &http.Client{Transport: transport, Timeout: config.Timeout}}, nil
+}
+
+// ValidURL checks that a URL can be posted to
+func ValidURL(u string) error {
+	parsed, err := url.ParseRequestURI(u)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL %q: %w", u, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("invalid webhook URL %q: scheme must be http or https", u)
+	}
+	return nil
+}
+
+// body encodes the records in the configured format
+func (c *Client) body(records [][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var zw *gzip.Writer
+	if c.config.Gzip {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+
+	switch c.config.Format {
+	case Array:
+		io.WriteString(w, "[")
+		for i, record := range records {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			w.Write(record)
+		}
+		io.WriteString(w, "]")
+	default:
+		for _, record := range records {
+			w.Write(record)
+			io.WriteString(w, "\n")
+		}
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Post sends records to a URL in one request, retrying with back-off after connection errors,
+// HTTP 429 and 5xx responses. Other responses outside 2xx are not retried.
+func (c *Client) Post(u string, records [][]byte, maxRetries int, backoff batch.Backoff) error {
+	return c.send(u, records, false, maxRetries, backoff)
+}
+
+// PostErrors sends records of the error stream like Post, with the StreamHeader set to "errors"
+func (c *Client) PostErrors(u string, records [][]byte, maxRetries int, backoff batch.Backoff) error {
+	return c.send(u, records, true, maxRetries, backoff)
+}
+
+func (c *Client) send(u string, records [][]byte, errorStream bool, maxRetries int, backoff batch.Backoff) error {
+	body, err := c.body(records)
+	if err != nil {
+		return err
+	}
+	err = batch.Retry(maxRetries, backoff, func() error {
+		return c.post(u, body, errorStream)
+	})
+	if err != nil {
+		return fmt.Errorf("post to %s failed: %w", u, err)
+	}
+	return nil
+}
+
+func (c *Client) post(u string, body []byte, errorStream bool) error {
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	if c.config.Format == Array {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+	if c.config.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	for name, values := range c.config.Headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	if errorStream {
+		req.Header.Set(StreamHeader, "errors")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return batch.Permanent(err)
+}
+
+// ParseHeader parses a header given as "Name: value"
+func ParseHeader(header string) (string, string, error) {
+	name, value, ok := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid header %q, use 'Name: value'", header)
+	}
+	return name, strings.TrimSpace(value), nil
+}
@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vagabundor/gtp2json/pkg/batch"
+)
+
+func TestPost(t *testing.T) {
+	records := [][]byte{[]byte(`{"n":1}`), []byte(`{"n":2}`)}
+	tests := []struct {
+		name       string
+		config     Config
+		errors     bool
+		statuses   []int
+		maxRetries int
+		wantBody   string
+		wantHeader http.Header
+		wantErr    bool
+		wantCalls  int
+	}{
+		{
+			name:       "NDJSON",
+			config:     Config{Format: NDJSON},
+			statuses:   []int{http.StatusOK},
+			wantBody:   "{\"n\":1}\n{\"n\":2}\n",
+			wantHeader: http.Header{"Content-Type": {"application/x-ndjson"}},
+			wantCalls:  1,
+		},
+		{
+			name: "Array with Gzip and Headers",
+			config: Config{
+				Format:  Array,
+				Gzip:    true,
+				Token:   "t0ken",
+				Headers: http.Header{"x-source": {"probe-1"}, "Content-Type": {"application/vnd.gtp+json"}},
+			},
+			statuses: []int{http.StatusAccepted},
+			wantBody: `[{"n":1},{"n":2}]`,
+			wantHeader: http.Header{
+				"Content-Type":     {"application/vnd.gtp+json"},
+				"Content-Encoding": {"gzip"},
+				"Authorization":    {"Bearer t0ken"},
+				"X-Source":         {"probe-1"},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:       "Error Stream",
+			config:     Config{Format: NDJSON, Headers: http.Header{StreamHeader: {"records"}}},
+			errors:     true,
+			statuses:   []int{http.StatusOK},
+			wantBody:   "{\"n\":1}\n{\"n\":2}\n",
+			wantHeader: http.Header{StreamHeader: {"errors"}},
+			wantCalls:  1,
+		},
+		{
+			name:      "Retried",
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent},
+			wantBody:  "{\"n\":1}\n{\"n\":2}\n",
+			wantCalls: 3,
+		},
+		{
+			name:       "Retries Exhausted",
+			statuses:   []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			maxRetries: 2,
+			wantBody:   "{\"n\":1}\n{\"n\":2}\n",
+			wantErr:    true,
+			wantCalls:  2,
+		},
+		{
+			name:      "Rejected",
+			statuses:  []int{http.StatusUnauthorized, http.StatusOK},
+			wantBody:  "{\"n\":1}\n{\"n\":2}\n",
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				if req.Method != http.MethodPost || req.URL.Path != "/ingest" {
+					t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+				}
+				if !tt.errors && req.Header.Get(StreamHeader) != "" {
+					t.Errorf("header %s set on records", StreamHeader)
+				}
+				for name, want := range tt.wantHeader {
+					if got := req.Header.Values(name); len(got) != 1 || got[0] != want[0] {
+						t.Errorf("header %s = %v, want %v", name, got, want)
+					}
+				}
+				var r io.Reader = req.Body
+				if req.Header.Get("Content-Encoding") == "gzip" {
+					zr, err := gzip.NewReader(req.Body)
+					if err != nil {
+						t.Fatal(err)
+					}
+					r = zr
+				}
+				body, _ := io.ReadAll(r)
+				if string(body) != tt.wantBody {
+					t.Errorf("body = %q, want %q", body, tt.wantBody)
+				}
+				w.WriteHeader(tt.statuses[calls-1])
+			}))
+			defer server.Close()
+
+			client, err := NewClient(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			post := client.Post
+			if tt.errors {
+				post = client.PostErrors
+			}
+			err = post(server.URL+"/ingest", records, tt.maxRetries, batch.Backoff{Initial: time.Millisecond})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Post() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("%d requests, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		header    string
+		wantName  string
+		wantValue string
+		wantErr   bool
+	}{
+		{header: "X-Source: probe-1", wantName: "X-Source", wantValue: "probe-1"},
+		{header: "X-Tags:a,b:c", wantName: "X-Tags", wantValue: "a,b:c"},
+		{header: "X-Empty:", wantName: "X-Empty"},
+		{header: "X-Source", wantErr: true},
+		{header: ": value", wantErr: true},
+	}
+	for _, tt := range tests {
+		name, value, err := ParseHeader(tt.header)
+		if (err != nil) != tt.wantErr || name != tt.wantName || value != tt.wantValue {
+			t.Errorf("ParseHeader(%q) = %q, %q, %v", tt.header, name, value, err)
+		}
+	}
+}
+
+func TestValidURL(t *testing.T) {
+	for u, valid := range map[string]bool{
+		"https://collector:8443/ingest": true,
+		"http://10.0.0.1/":              true,
+		"ftp://collector/":              false,
+		"collector:8080":                false,
+	} {
+		if err := ValidURL(u); (err == nil) != valid {
+			t.Errorf("ValidURL(%q) = %v", u, err)
+		}
+	}
+}